@@ -0,0 +1,719 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	shell "github.com/stateless-minds/go-ipfs-api"
+)
+
+// Environment variables of the native server. ActivityPub is only served
+// when ACTIVITYPUB_URL holds the public URL of the server.
+const (
+	envActivityPubURL      = "ACTIVITYPUB_URL"
+	envActivityPubChannels = "ACTIVITYPUB_CHANNELS"
+	envIPFSAPI             = "IPFS_API"
+)
+
+// Paths of the ActivityPub endpoints.
+const (
+	webFingerPath   = "/.well-known/webfinger"
+	apActorPath     = "/ap/actor"
+	apInboxPath     = "/ap/inbox"
+	apOutboxPath    = "/ap/outbox"
+	apFollowersPath = "/ap/followers"
+	apNotePath      = "/ap/news/"
+)
+
+const (
+	// apUsername is the name the news actor is followed by, as in
+	// @news@example.org.
+	apUsername = "news"
+	// apContentType is the media type of ActivityPub documents.
+	apContentType = "application/activity+json"
+	// apPublic addresses an activity to everyone.
+	apPublic = "https://www.w3.org/ns/activitystreams#Public"
+	// maxOutbox bounds the activities kept in the outbox.
+	maxOutbox = 200
+	// maxInboxSize bounds the activities the inbox accepts.
+	maxInboxSize = 1 << 20
+	// defaultDeliveryAttempts is how many times an activity is sent to an
+	// inbox before it is given up.
+	defaultDeliveryAttempts = 3
+)
+
+var apContext = []string{"https://www.w3.org/ns/activitystreams", "https://w3id.org/security/v1"}
+
+var (
+	errNotFollowed = errors.New("activity is not addressed to the news actor")
+	errRemoteURL   = errors.New("not a public HTTPS address")
+)
+
+// apObject is the subset of ActivityStreams objects the server publishes.
+type apObject struct {
+	Context      any      `json:"@context,omitempty"`
+	ID           string   `json:"id"`
+	Type         string   `json:"type"`
+	AttributedTo string   `json:"attributedTo,omitempty"`
+	Name         string   `json:"name,omitempty"`
+	Content      string   `json:"content,omitempty"`
+	URL          string   `json:"url,omitempty"`
+	Published    string   `json:"published,omitempty"`
+	To           []string `json:"to,omitempty"`
+	Cc           []string `json:"cc,omitempty"`
+}
+
+// apActivity is an activity sent or received by the server.
+type apActivity struct {
+	Context   any             `json:"@context,omitempty"`
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	Actor     string          `json:"actor"`
+	Published string          `json:"published,omitempty"`
+	To        []string        `json:"to,omitempty"`
+	Cc        []string        `json:"cc,omitempty"`
+	Object    json.RawMessage `json:"object"`
+}
+
+// apRemoteActor is what the server needs to know about another actor.
+type apRemoteActor struct {
+	ID        string `json:"id"`
+	Inbox     string `json:"inbox"`
+	Endpoints struct {
+		SharedInbox string `json:"sharedInbox"`
+	} `json:"endpoints"`
+	PublicKey struct {
+		ID           string `json:"id"`
+		Owner        string `json:"owner"`
+		PublicKeyPem string `json:"publicKeyPem"`
+	} `json:"publicKey"`
+}
+
+// federation publishes the news of some channels as an ActivityPub actor
+// and delivers them to its followers.
+type federation struct {
+	base     string
+	key      *rsa.PrivateKey
+	client   *http.Client
+	watcher  *changeWatcher
+	attempts int
+	backoff  time.Duration
+	// followersFile keeps followers across restarts, they are only kept
+	// in memory when it is empty
+	followersFile string
+
+	mu sync.Mutex
+	// outbox holds the Create activities of the latest news, newest first
+	outbox []apActivity
+	notes  map[string]apObject
+	// followers maps actor IDs to the inbox they receive activities in
+	followers map[string]string
+
+	wg sync.WaitGroup
+}
+
+// newFederation returns the federation of the news of channels, served at
+// the public URL base.
+func newFederation(base string, key *rsa.PrivateKey, channels []channel) *federation {
+	f := &federation{
+		base:      strings.TrimSuffix(base, "/"),
+		key:       key,
+		client:    newRemoteClient(),
+		attempts:  defaultDeliveryAttempts,
+		backoff:   hookBackoff,
+		notes:     make(map[string]apObject),
+		followers: make(map[string]string),
+	}
+	f.watcher = newChangeWatcher(channels, f.onChange)
+	return f
+}
+
+// startFederation serves ActivityPub on mux when the server is configured
+// to, following the news through the local node.
+func startFederation(mux *http.ServeMux) error {
+	base := os.Getenv(envActivityPubURL)
+	if base == "" {
+		return nil
+	}
+
+	channels := []channel{{}}
+	if ids := os.Getenv(envActivityPubChannels); ids != "" {
+		channels = nil
+		for _, id := range strings.Split(ids, ",") {
+			channels = append(channels, parseChannel(strings.TrimSpace(id)))
+		}
+	}
+	api := os.Getenv(envIPFSAPI)
+	if api == "" {
+		api = defaultAPI
+	}
+
+	key, err := loadFederationKey()
+	if err != nil {
+		return err
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return err
+	}
+
+	f := newFederation(base, key, channels)
+	f.followersFile = filepath.Join(dir, "cyber-witness", "followers.json")
+	err = f.loadFollowers()
+	if err != nil {
+		return err
+	}
+
+	sh := shell.NewShell(api)
+	events, err := loadAllEvents(sh, channels)
+	if err != nil {
+		return err
+	}
+	f.seed(events)
+	f.watcher.subscribe(newSubscriber(sh, nil))
+	f.register(mux)
+	return nil
+}
+
+func (f *federation) actorID() string {
+	return f.base + apActorPath
+}
+
+func (f *federation) keyID() string {
+	return f.actorID() + "#main-key"
+}
+
+// register adds the ActivityPub endpoints to mux.
+func (f *federation) register(mux *http.ServeMux) {
+	mux.HandleFunc(webFingerPath, f.handleWebFinger)
+	mux.HandleFunc(apActorPath, f.handleActor)
+	mux.HandleFunc(apInboxPath, f.handleInbox)
+	mux.HandleFunc(apOutboxPath, f.handleOutbox)
+	mux.HandleFunc(apFollowersPath, f.handleFollowers)
+	mux.HandleFunc(apNotePath, f.handleNote)
+}
+
+// seed fills the outbox with the news known at startup, which are not
+// delivered again.
+func (f *federation) seed(events []Event) {
+	f.watcher.seed(events)
+
+	sortEvents(events)
+	for _, e := range events {
+		if e.Type == eventType && e.state() == stateNews {
+			f.addToOutbox(e)
+		}
+	}
+}
+
+func (f *federation) onChange(kind string, e Event) {
+	if kind != changeNews {
+		return
+	}
+
+	a, ok := f.addToOutbox(e)
+	if ok {
+		f.deliverToFollowers(a)
+	}
+}
+
+// note returns the Note announcing e as news.
+func (f *federation) note(e Event) apObject {
+	c := e.corrected()
+	link := f.base + eventPath(e)
+
+	var content strings.Builder
+	content.WriteString("<p><strong>" + html.EscapeString(c.Title) + "</strong></p>")
+	if c.Location != "" {
+		content.WriteString("<p>Location: " + html.EscapeString(c.Location) + "</p>")
+	}
+	content.WriteString("<p>Confirmed by " + strconv.Itoa(e.ConfirmedBy) + " witnesses</p>")
+	if len(e.Details) > 0 {
+		content.WriteString("<ul>")
+		for _, d := range e.Details {
+			content.WriteString("<li>" + html.EscapeString(d.Text) + "</li>")
+		}
+		content.WriteString("</ul>")
+	}
+	content.WriteString(`<p><a href="` + html.EscapeString(link) + `">` + html.EscapeString(link) + "</a></p>")
+
+	at := e.NewsAt
+	if at == 0 {
+		at = e.CreatedAt
+	}
+	return apObject{
+		ID:           f.base + apNotePath + url.PathEscape(e.ID),
+		Type:         "Note",
+		AttributedTo: f.actorID(),
+		Name:         c.Title,
+		Content:      content.String(),
+		URL:          link,
+		Published:    time.Unix(at, 0).UTC().Format(time.RFC3339),
+		To:           []string{apPublic},
+		Cc:           []string{f.base + apFollowersPath},
+	}
+}
+
+// addToOutbox publishes the news e in the outbox. It returns false if it
+// already was.
+func (f *federation) addToOutbox(e Event) (apActivity, bool) {
+	n := f.note(e)
+	object, err := json.Marshal(n)
+	if err != nil {
+		log.Println(err)
+		return apActivity{}, false
+	}
+	a := apActivity{
+		Context:   apContext,
+		ID:        n.ID + "/activity",
+		Type:      "Create",
+		Actor:     f.actorID(),
+		Published: n.Published,
+		To:        n.To,
+		Cc:        n.Cc,
+		Object:    object,
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.notes[n.ID]; ok {
+		return a, false
+	}
+	f.notes[n.ID] = n
+	f.outbox = append([]apActivity{a}, f.outbox...)
+	if len(f.outbox) > maxOutbox {
+		for _, old := range f.outbox[maxOutbox:] {
+			delete(f.notes, strings.TrimSuffix(old.ID, "/activity"))
+		}
+		f.outbox = f.outbox[:maxOutbox]
+	}
+	return a, true
+}
+
+// deliverToFollowers sends a to the inbox of every follower in the
+// background, once per shared inbox.
+func (f *federation) deliverToFollowers(a apActivity) {
+	f.mu.Lock()
+	inboxes := map[string]bool{}
+	for _, inbox := range f.followers {
+		inboxes[inbox] = true
+	}
+	f.mu.Unlock()
+
+	for inbox := range inboxes {
+		f.deliver(inbox, a)
+	}
+}
+
+// deliver sends a to inbox in the background, retrying with exponential
+// backoff.
+func (f *federation) deliver(inbox string, a apActivity) {
+	body, err := json.Marshal(a)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+
+		delay := f.backoff
+		for attempt := 1; ; attempt++ {
+			err := f.post(inbox, body)
+			if err == nil {
+				return
+			}
+			if attempt == f.attempts {
+				log.Println("Could not deliver " + a.ID + " to " + inbox + ": " + err.Error())
+				return
+			}
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}()
+}
+
+func (f *federation) post(inbox string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, inbox, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", apContentType)
+	err = signRequest(req, f.keyID(), f.key, body)
+	if err != nil {
+		return err
+	}
+
+	res, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("inbox answered %s", res.Status)
+	}
+	return nil
+}
+
+// wait returns once every pending delivery is done.
+func (f *federation) wait() {
+	f.wg.Wait()
+}
+
+// newRemoteClient returns the client actors are fetched and activities
+// delivered with. Their addresses come from other servers, so it only
+// connects to public addresses over HTTPS, whatever the names resolve to
+// and wherever the redirects lead.
+func newRemoteClient() *http.Client {
+	dialer := &net.Dialer{Timeout: hookTimeout, Control: dialPublic}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   hookTimeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			_, err := remoteURL(req.URL.String())
+			return err
+		},
+	}
+}
+
+// dialPublic refuses connections to loopback, private, link-local and other
+// addresses that aren't public.
+func dialPublic(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return fmt.Errorf("%w: %s", errRemoteURL, address)
+	}
+	return nil
+}
+
+// remoteURL parses the URL of a remote actor or inbox, which must be an
+// HTTPS URL.
+func remoteURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "https" || u.Host == "" || u.User != nil {
+		return nil, fmt.Errorf("%w: %s", errRemoteURL, raw)
+	}
+	return u, nil
+}
+
+// fetchActor dereferences the actor with the given ID, or owning the key
+// with the given ID.
+func (f *federation) fetchActor(id string) (apRemoteActor, error) {
+	var a apRemoteActor
+	id, _, _ = strings.Cut(id, "#")
+	u, err := remoteURL(id)
+	if err != nil {
+		return a, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, id, nil)
+	if err != nil {
+		return a, err
+	}
+	req.Header.Set("Accept", apContentType)
+	res, err := f.client.Do(req)
+	if err != nil {
+		return a, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return a, fmt.Errorf("actor %s: %s", id, res.Status)
+	}
+
+	err = json.NewDecoder(io.LimitReader(res.Body, maxInboxSize)).Decode(&a)
+	if err != nil {
+		return a, err
+	}
+	if a.ID != id || a.Inbox == "" {
+		return a, fmt.Errorf("actor %s: invalid actor document", id)
+	}
+	// the inboxes activities are delivered to are the actor's own, not
+	// any address its server names
+	for _, inbox := range []string{a.Inbox, a.Endpoints.SharedInbox} {
+		if inbox == "" {
+			continue
+		}
+		i, err := remoteURL(inbox)
+		if err != nil {
+			return a, err
+		}
+		if i.Host != u.Host {
+			return a, fmt.Errorf("actor %s: inbox %s on another host", id, inbox)
+		}
+	}
+	return a, nil
+}
+
+func (f *federation) handleWebFinger(w http.ResponseWriter, r *http.Request) {
+	u, err := url.Parse(f.base)
+	if err != nil || r.URL.Query().Get("resource") != "acct:"+apUsername+"@"+u.Host {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/jrd+json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"subject": "acct:" + apUsername + "@" + u.Host,
+		"aliases": []string{f.actorID()},
+		"links": []map[string]string{
+			{"rel": "self", "type": apContentType, "href": f.actorID()},
+			{"rel": "http://webfinger.net/rel/profile-page", "type": "text/html", "href": f.base},
+		},
+	})
+}
+
+func (f *federation) handleActor(w http.ResponseWriter, r *http.Request) {
+	pem, err := encodePublicKey(&f.key.PublicKey)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeActivityJSON(w, map[string]any{
+		"@context":          apContext,
+		"id":                f.actorID(),
+		"type":              "Service",
+		"preferredUsername": apUsername,
+		"name":              "Cyber Witness",
+		"summary":           "News confirmed by at least 2 witnesses on Cyber Witness.",
+		"url":               f.base,
+		"inbox":             f.base + apInboxPath,
+		"outbox":            f.base + apOutboxPath,
+		"followers":         f.base + apFollowersPath,
+		"publicKey": map[string]string{
+			"id":           f.keyID(),
+			"owner":        f.actorID(),
+			"publicKeyPem": pem,
+		},
+	})
+}
+
+func (f *federation) handleOutbox(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	items := append([]apActivity(nil), f.outbox...)
+	f.mu.Unlock()
+	for n := range items {
+		items[n].Context = nil
+	}
+
+	writeActivityJSON(w, map[string]any{
+		"@context":     apContext,
+		"id":           f.base + apOutboxPath,
+		"type":         "OrderedCollection",
+		"totalItems":   len(items),
+		"orderedItems": items,
+	})
+}
+
+// handleFollowers only tells how many followers there are, who follows
+// the news is nobody else's business.
+func (f *federation) handleFollowers(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	n := len(f.followers)
+	f.mu.Unlock()
+
+	writeActivityJSON(w, map[string]any{
+		"@context":   apContext,
+		"id":         f.base + apFollowersPath,
+		"type":       "OrderedCollection",
+		"totalItems": n,
+	})
+}
+
+func (f *federation) handleNote(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	n, ok := f.notes[f.base+r.URL.Path]
+	f.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	n.Context = apContext
+	writeActivityJSON(w, n)
+}
+
+// handleInbox accepts follows and their undoing from actors that sign their
+// requests. Other activities are acknowledged and ignored.
+func (f *federation) handleInbox(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxInboxSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var sender apRemoteActor
+	_, err = verifyRequest(r, body, func(keyID string) (*rsa.PublicKey, error) {
+		a, err := f.fetchActor(keyID)
+		if err != nil {
+			return nil, err
+		}
+		sender = a
+		if sender.PublicKey.ID != keyID || sender.PublicKey.Owner != sender.ID {
+			return nil, fmt.Errorf("%w: key %s is not the key of %s", errBadHTTPSignature, keyID, sender.ID)
+		}
+		return decodePublicKey(sender.PublicKey.PublicKeyPem)
+	})
+	if err != nil {
+		log.Println("Rejected inbox request: " + err.Error())
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var a apActivity
+	err = json.Unmarshal(body, &a)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if a.Actor != sender.ID {
+		http.Error(w, "activity not sent by its actor", http.StatusForbidden)
+		return
+	}
+
+	switch a.Type {
+	case "Follow":
+		err = f.follow(a, sender)
+	case "Undo":
+		err = f.undo(a)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// follow adds the sender of a Follow activity to the followers and accepts
+// it. The sender was fetched by fetchActor, which only accepts inboxes on
+// the host of the actor.
+func (f *federation) follow(a apActivity, sender apRemoteActor) error {
+	var object string
+	if json.Unmarshal(a.Object, &object) != nil || object != f.actorID() {
+		return errNotFollowed
+	}
+
+	inbox := sender.Inbox
+	if sender.Endpoints.SharedInbox != "" {
+		inbox = sender.Endpoints.SharedInbox
+	}
+	f.mu.Lock()
+	f.followers[sender.ID] = inbox
+	f.mu.Unlock()
+	f.saveFollowers()
+
+	follow, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	f.deliver(sender.Inbox, apActivity{
+		Context: apContext,
+		ID:      f.actorID() + "#accepts/" + url.PathEscape(a.ID),
+		Type:    "Accept",
+		Actor:   f.actorID(),
+		To:      []string{sender.ID},
+		Object:  follow,
+	})
+	return nil
+}
+
+// undo removes the sender of an undone Follow from the followers.
+func (f *federation) undo(a apActivity) error {
+	var follow apActivity
+	if json.Unmarshal(a.Object, &follow) != nil || follow.Type != "Follow" {
+		return nil
+	}
+	if follow.Actor != a.Actor {
+		return fmt.Errorf("follow of %s undone by %s", follow.Actor, a.Actor)
+	}
+
+	f.mu.Lock()
+	delete(f.followers, a.Actor)
+	f.mu.Unlock()
+	f.saveFollowers()
+	return nil
+}
+
+// isFollowed reports whether the actor with the given ID follows the news.
+func (f *federation) isFollowed(id string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.followers[id]
+	return ok
+}
+
+func (f *federation) loadFollowers() error {
+	if f.followersFile == "" {
+		return nil
+	}
+	b, err := os.ReadFile(f.followersFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return json.Unmarshal(b, &f.followers)
+}
+
+func (f *federation) saveFollowers() {
+	if f.followersFile == "" {
+		return
+	}
+
+	f.mu.Lock()
+	b, err := json.Marshal(f.followers)
+	f.mu.Unlock()
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(f.followersFile), 0o700)
+	}
+	if err == nil {
+		err = os.WriteFile(f.followersFile, b, 0o600)
+	}
+	if err != nil {
+		log.Println("Could not save followers: " + err.Error())
+	}
+}
+
+func writeActivityJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", apContentType)
+	json.NewEncoder(w).Encode(v)
+}
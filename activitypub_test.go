@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// remoteActor is a local stand-in for an account on another ActivityPub
+// server, serving its actor document and recording what reaches its inbox.
+type remoteActor struct {
+	t   *testing.T
+	key *rsa.PrivateKey
+	url string
+	// server is the federation whose signatures the inbox checks
+	server *federation
+
+	mu       sync.Mutex
+	received []apActivity
+}
+
+func newRemoteActor(t *testing.T, server *federation) *remoteActor {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &remoteActor{t: t, key: key, server: server}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/alice", a.handleActor)
+	mux.HandleFunc("/users/alice/inbox", a.handleInbox)
+	srv := httptest.NewTLSServer(mux)
+	t.Cleanup(srv.Close)
+	a.url = srv.URL
+	// the server only reaches public addresses, the actor is on loopback
+	// with a test certificate
+	server.client = srv.Client()
+	return a
+}
+
+func (a *remoteActor) id() string {
+	return a.url + "/users/alice"
+}
+
+func (a *remoteActor) handleActor(w http.ResponseWriter, r *http.Request) {
+	pem, _ := encodePublicKey(&a.key.PublicKey)
+	writeActivityJSON(w, map[string]any{
+		"id":    a.id(),
+		"type":  "Person",
+		"inbox": a.id() + "/inbox",
+		"publicKey": map[string]string{
+			"id":           a.id() + "#main-key",
+			"owner":        a.id(),
+			"publicKeyPem": pem,
+		},
+	})
+}
+
+func (a *remoteActor) handleInbox(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	_, err := verifyRequest(r, body, func(keyID string) (*rsa.PublicKey, error) {
+		if keyID != a.server.keyID() {
+			a.t.Errorf("signed with key %s", keyID)
+		}
+		return &a.server.key.PublicKey, nil
+	})
+	if err != nil {
+		a.t.Errorf("delivery to the inbox: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var activity apActivity
+	if err := json.Unmarshal(body, &activity); err != nil {
+		a.t.Error(err)
+	}
+	a.mu.Lock()
+	a.received = append(a.received, activity)
+	a.mu.Unlock()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (a *remoteActor) inbox() []apActivity {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]apActivity(nil), a.received...)
+}
+
+// send posts an activity of the remote actor to the server's inbox, signed
+// unless signed is false.
+func (a *remoteActor) send(activity map[string]any, signed bool) int {
+	a.t.Helper()
+	body, _ := json.Marshal(activity)
+	req, _ := http.NewRequest(http.MethodPost, a.server.base+apInboxPath, bytes.NewReader(body))
+	req.Header.Set("Content-Type", apContentType)
+	if signed {
+		if err := signRequest(req, a.id()+"#main-key", a.key, body); err != nil {
+			a.t.Fatal(err)
+		}
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	res.Body.Close()
+	return res.StatusCode
+}
+
+func newTestFederation(t *testing.T) *federation {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	f := newFederation(srv.URL, key, []channel{{}})
+	f.backoff = time.Millisecond
+	f.register(mux)
+	return f
+}
+
+func getActivityJSON(t *testing.T, u string, v any) {
+	t.Helper()
+	res, err := http.Get(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s: %s", u, res.Status)
+	}
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWebFingerFindsTheNewsActor(t *testing.T) {
+	f := newTestFederation(t)
+	u, _ := url.Parse(f.base)
+
+	var jrd struct {
+		Links []struct{ Rel, Type, Href string }
+	}
+	getActivityJSON(t, f.base+webFingerPath+"?resource=acct:news@"+u.Host, &jrd)
+	if len(jrd.Links) == 0 || jrd.Links[0].Href != f.actorID() || jrd.Links[0].Type != apContentType {
+		t.Fatalf("WebFinger links = %+v, want the actor", jrd.Links)
+	}
+
+	var actor apRemoteActor
+	getActivityJSON(t, f.actorID(), &actor)
+	if actor.Inbox != f.base+apInboxPath || actor.PublicKey.ID != f.keyID() {
+		t.Errorf("actor = %+v", actor)
+	}
+	if pub, err := decodePublicKey(actor.PublicKey.PublicKeyPem); err != nil || !pub.Equal(&f.key.PublicKey) {
+		t.Errorf("published key: %v", err)
+	}
+}
+
+func TestFollowersReceivePromotedNews(t *testing.T) {
+	f := newTestFederation(t)
+	alice := newRemoteActor(t, f)
+
+	follow := map[string]any{"id": alice.id() + "/follows/1", "type": "Follow", "actor": alice.id(), "object": f.actorID()}
+	if status := alice.send(follow, false); status != http.StatusUnauthorized {
+		t.Errorf("unsigned follow answered %d", status)
+	}
+	if status := alice.send(follow, true); status != http.StatusAccepted {
+		t.Fatalf("follow answered %d", status)
+	}
+	f.wait()
+	if got := alice.inbox(); len(got) != 1 || got[0].Type != "Accept" || !f.isFollowed(alice.id()) {
+		t.Fatalf("inbox after following = %+v", got)
+	}
+
+	rumor := Event{ID: "1", Type: eventType, Title: "Fire <downtown>", Location: "Main St", Witnesses: []string{"11"}, ConfirmedBy: 1, CreatedAt: 100}
+	f.seed([]Event{rumor})
+	news := rumor
+	news.Witnesses = []string{"11", "12"}
+	news.ConfirmedBy = 2
+	news.NewsAt = 200
+	f.watcher.receive(news, false)
+	f.wait()
+
+	got := alice.inbox()
+	if len(got) != 2 || got[1].Type != "Create" {
+		t.Fatalf("inbox after promotion = %+v, want a Create", got)
+	}
+	var note apObject
+	if err := json.Unmarshal(got[1].Object, &note); err != nil {
+		t.Fatal(err)
+	}
+	if note.Type != "Note" || !strings.Contains(note.Content, "Confirmed by 2 witnesses") || !strings.Contains(note.Content, "Fire &lt;downtown&gt;") {
+		t.Errorf("note = %+v", note)
+	}
+
+	var outbox struct {
+		TotalItems   int
+		OrderedItems []apActivity
+	}
+	getActivityJSON(t, f.base+apOutboxPath, &outbox)
+	if outbox.TotalItems != 1 || outbox.OrderedItems[0].ID != got[1].ID {
+		t.Errorf("outbox = %+v", outbox)
+	}
+	var fetched apObject
+	getActivityJSON(t, note.ID, &fetched)
+	if fetched.Content != note.Content {
+		t.Errorf("dereferenced note = %+v", fetched)
+	}
+
+	// further confirmations don't announce the news again
+	news.Witnesses = append(news.Witnesses, "13")
+	news.ConfirmedBy = 3
+	f.watcher.receive(news, false)
+	f.wait()
+	if n := len(alice.inbox()); n != 2 {
+		t.Errorf("%d activities delivered, want no more", n)
+	}
+
+	undo := map[string]any{"id": alice.id() + "/undo/1", "type": "Undo", "actor": alice.id(), "object": follow}
+	if status := alice.send(undo, true); status != http.StatusAccepted || f.isFollowed(alice.id()) {
+		t.Errorf("undo answered %d, still followed: %v", status, f.isFollowed(alice.id()))
+	}
+}
+
+func TestRemoteActorsArePublic(t *testing.T) {
+	f := newTestFederation(t)
+	inbox := "https://169.254.169.254/inbox"
+	mux := http.NewServeMux()
+	srv := httptest.NewTLSServer(mux)
+	t.Cleanup(srv.Close)
+	mux.HandleFunc("/users/mallory", func(w http.ResponseWriter, r *http.Request) {
+		writeActivityJSON(w, map[string]any{"id": srv.URL + "/users/mallory", "type": "Person", "inbox": inbox})
+	})
+
+	// the default client refuses the loopback test server
+	if _, err := f.fetchActor(srv.URL + "/users/mallory"); !errors.Is(err, errRemoteURL) {
+		t.Errorf("fetched an actor on loopback: %v", err)
+	}
+
+	f.client = srv.Client()
+	if _, err := f.fetchActor(strings.Replace(srv.URL, "https:", "http:", 1) + "/users/mallory"); !errors.Is(err, errRemoteURL) {
+		t.Errorf("fetched an actor over HTTP: %v", err)
+	}
+	if _, err := f.fetchActor(srv.URL + "/users/mallory"); err == nil {
+		t.Errorf("accepted an actor with its inbox at %s", inbox)
+	}
+	inbox = srv.URL + "/users/mallory/inbox"
+	if _, err := f.fetchActor(srv.URL + "/users/mallory"); err != nil {
+		t.Errorf("actor with its own inbox: %v", err)
+	}
+
+	for _, address := range []string{"127.0.0.1:443", "[::1]:443", "10.0.0.1:443", "192.168.1.1:443", "169.254.169.254:80", "[fe80::1]:443", "[::ffff:127.0.0.1]:443", "0.0.0.0:443"} {
+		if err := dialPublic("tcp", address, nil); !errors.Is(err, errRemoteURL) {
+			t.Errorf("dialing %s allowed: %v", address, err)
+		}
+	}
+	if err := dialPublic("tcp", "93.184.215.14:443", nil); err != nil {
+		t.Errorf("dialing a public address: %v", err)
+	}
+}
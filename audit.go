@@ -0,0 +1,266 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+// Operations recorded in the audit log of an event.
+const (
+	opReport        = "report"
+	opConfirm       = "confirm"
+	opDetail        = "detail"
+	opDispute       = "dispute"
+	opCorrection    = "correction"
+	opRetraction    = "retraction"
+	opMergeProposal = "merge-proposal"
+	opMergeVote     = "merge-vote"
+	// opMerge is logged on the duplicate merged into the report of Ref,
+	// opMergedFrom on the surviving report.
+	opMerge      = "merge"
+	opMergedFrom = "merged-from"
+)
+
+// auditStatement is the kind of statement audit entries are signed as.
+const auditStatement = "audit"
+
+var errBadAuditEntry = errors.New("audit entry signature is invalid")
+
+// AuditEntry records one operation on an event. Entries are signed by
+// whoever performed the operation, so the log can be checked offline
+// without trusting the peers that relayed the event.
+type AuditEntry struct {
+	Op    string `mapstructure:"op" json:"op" validate:"uuid_rfc4122"`
+	Actor string `mapstructure:"actor" json:"actor" validate:"uuid_rfc4122"`
+	At    int64  `mapstructure:"at" json:"at" validate:"uuid_rfc4122"`
+	// Ref is what the operation was about: the text of a detail, the
+	// reason of a correction or the other report of a merge.
+	Ref       string `mapstructure:"ref" json:"ref,omitempty" validate:"uuid_rfc4122"`
+	Signer    string `mapstructure:"signer" json:"signer" validate:"uuid_rfc4122"`
+	Signature string `mapstructure:"signature" json:"signature" validate:"uuid_rfc4122"`
+}
+
+// auditExport is the file the audit log of an event is exported as.
+type auditExport struct {
+	Event       string       `json:"event"`
+	Title       string       `json:"title"`
+	ReporterKey string       `json:"reporterKey,omitempty"`
+	Entries     []AuditEntry `json:"entries"`
+}
+
+// auditStep is an entry of the audit log replayed: whether its signature
+// holds and the state the event was in afterwards.
+type auditStep struct {
+	Entry AuditEntry
+	Err   error
+	State eventState
+}
+
+func signAuditEntry(key ed25519.PrivateKey, id string, a AuditEntry) (AuditEntry, error) {
+	a.Signer = signerID(key)
+	a.Signature = ""
+	sig, err := signStatement(key, id, auditStatement, a)
+	a.Signature = sig
+	return a, err
+}
+
+// verifyAuditEntry checks the signature of an entry of the event with the
+// given ID.
+func verifyAuditEntry(id string, a AuditEntry) error {
+	sig := a.Signature
+	a.Signature = ""
+	if verifyStatement(a.Signer, sig, id, auditStatement, a) != nil {
+		return errBadAuditEntry
+	}
+	return nil
+}
+
+// auditLog returns the audit log of e in the order the operations were
+// performed.
+func (e Event) auditLog() []AuditEntry {
+	entries := append([]AuditEntry(nil), e.Audit...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].At != entries[j].At {
+			return entries[i].At < entries[j].At
+		}
+		return entries[i].Signature < entries[j].Signature
+	})
+	return entries
+}
+
+// hasAuditEntry reports whether entries holds a, entry signatures being
+// unique.
+func hasAuditEntry(entries []AuditEntry, a AuditEntry) bool {
+	for _, v := range entries {
+		if v.Signature == a.Signature {
+			return true
+		}
+	}
+	return false
+}
+
+// unionAudit returns a followed by the entries of b it doesn't hold. Logs
+// only ever grow, an update can't drop an entry.
+func unionAudit(a, b []AuditEntry) []AuditEntry {
+	out := a
+	for _, e := range b {
+		if !hasAuditEntry(out, e) {
+			out = append(out[:len(out):len(out)], e)
+		}
+	}
+	return out
+}
+
+// validAudit checks the entries incoming adds to the log of local.
+func validAudit(local, incoming Event) error {
+	for _, a := range incoming.Audit {
+		if hasAuditEntry(local.Audit, a) {
+			continue
+		}
+		if err := verifyAuditEntry(incoming.ID, a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replayAudit replays the log of the event with the given ID from the
+// report on, so readers can see which operation moved the event to which
+// state, for example the confirmation that made it news. Witnesses a
+// surviving report gained from a duplicate are logged on the duplicate.
+func replayAudit(id string, entries []AuditEntry) []auditStep {
+	var e Event
+	steps := make([]auditStep, 0, len(entries))
+	for _, a := range entries {
+		switch a.Op {
+		case opReport:
+			e.Type = eventType
+			e.Reporter = a.Actor
+		case opConfirm:
+			if !contains(e.Witnesses, a.Actor) {
+				e.Witnesses = append(e.Witnesses, a.Actor)
+				e.ConfirmedBy++
+			}
+		case opDispute:
+			e.Disputes = union(e.Disputes, []string{a.Actor})
+		case opRetraction:
+			e.State = string(stateRetracted)
+		case opMerge:
+			e.Type = mergedType
+		}
+		steps = append(steps, auditStep{Entry: a, Err: verifyAuditEntry(id, a), State: e.state()})
+	}
+	return steps
+}
+
+// logged returns e with op performed by the citizen appended to its audit
+// log.
+func (w *witness) logged(e Event, op, ref string, now time.Time) Event {
+	if len(w.key) != ed25519.PrivateKeySize {
+		return e
+	}
+
+	a, err := signAuditEntry(w.key, e.ID, AuditEntry{Op: op, Actor: w.citizenID, At: now.Unix(), Ref: ref})
+	if err != nil {
+		log.Println(err)
+		return e
+	}
+	e.Audit = append(e.Audit[:len(e.Audit):len(e.Audit)], a)
+	return e
+}
+
+// exportAudit returns the audit log of e as the file it is exported as.
+func exportAudit(e Event) ([]byte, error) {
+	return json.MarshalIndent(auditExport{
+		Event:       e.ID,
+		Title:       e.Title,
+		ReporterKey: e.ReporterKey,
+		Entries:     e.auditLog(),
+	}, "", "  ")
+}
+
+// auditedEvent returns the event with the given ID whose audit log is
+// shown: a loaded or retired one, or the one opened from a link.
+func (w *witness) auditedEvent(id string) (Event, bool) {
+	if e, ok := w.knownEvent(id); ok {
+		return e, true
+	}
+	if w.linkedEvent != nil && w.linkedEvent.ID == id {
+		return *w.linkedEvent, true
+	}
+	return Event{}, false
+}
+
+func (w *witness) onExportAudit(ctx app.Context, e app.Event) {
+	event, ok := w.auditedEvent(ctx.JSSrc().Get("value").String())
+	if !ok {
+		w.createNotification(ctx, NotificationWarning, w.t("event.audit"), w.t("audit.notFound"))
+		return
+	}
+
+	b, err := exportAudit(event)
+	if err != nil {
+		w.createNotification(ctx, NotificationDanger, w.t(ErrorHeader), w.t("audit.exportFailed"))
+		log.Println(err)
+		return
+	}
+	downloadFile("cyber-witness-audit-"+event.ID+".json", "application/json", b)
+}
+
+// renderAuditLog renders the audit log of e, collapsed until opened.
+func (w *witness) renderAuditLog(e Event) app.UI {
+	steps := replayAudit(e.ID, e.auditLog())
+
+	return app.If(len(steps) > 0, func() app.UI {
+		return app.Details().Body(
+			app.Summary().Text(w.t("event.audit")),
+			app.Table().Class("p-table--mobile-card").Body(
+				app.THead().Body(
+					app.Tr().Body(
+						app.Th().Text(w.t("audit.time")),
+						app.Th().Text(w.t("audit.operation")),
+						app.Th().Text(w.t("audit.actor")),
+						app.Th().Text(w.t("audit.state")),
+						app.Th().Text(w.t("audit.signature")),
+					),
+				),
+				app.TBody().Body(
+					app.Range(steps).Slice(func(n int) app.UI {
+						s := steps[n]
+						became := n == 0 || steps[n-1].State != s.State
+
+						return app.Tr().Body(
+							app.Td().Text(time.Unix(s.Entry.At, 0).Format(time.RFC822)),
+							app.Td().Body(
+								app.Text(w.t("audit.op."+s.Entry.Op)),
+								app.If(s.Entry.Ref != "", func() app.UI {
+									return app.Span().Class("p-text--small u-text--muted").Text(" " + s.Entry.Ref)
+								}),
+							),
+							app.Td().Text(s.Entry.Actor),
+							app.Td().Body(
+								app.If(became, func() app.UI {
+									return app.Span().Text(w.t("state." + string(s.State)))
+								}),
+							),
+							app.Td().Body(
+								app.If(s.Err == nil, func() app.UI {
+									return app.Span().Class("p-status-label--positive").Text(w.t("audit.valid"))
+								}).Else(func() app.UI {
+									return app.Span().Class("p-status-label--negative").Text(w.t("audit.invalid"))
+								}),
+							),
+						)
+					}),
+				),
+			),
+			app.Button().Class("is-dense p-button--base").Value(e.ID).Text(w.t("audit.export")).OnClick(w.onExportAudit),
+		)
+	})
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// auditWitness returns a witness of citizen with its own signing key.
+func auditWitness(t *testing.T, citizen string, events ...Event) *witness {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &witness{citizenID: citizen, key: key, events: events}
+}
+
+func TestAuditLogExplainsNews(t *testing.T) {
+	reporter := auditWitness(t, "10")
+	reporter.eventTitle = "Fire"
+	e, _ := reporter.newReport(time.Unix(100, 0))
+
+	for n, citizen := range []string{"11", "12"} {
+		w := auditWitness(t, citizen, e)
+		var ok bool
+		e, ok = w.confirmation(e.ID, time.Unix(int64(200+n), 0))
+		if !ok {
+			t.Fatalf("citizen %s could not confirm", citizen)
+		}
+	}
+
+	steps := replayAudit(e.ID, e.auditLog())
+	want := []struct {
+		op    string
+		actor string
+		state eventState
+	}{
+		{opReport, "10", stateRumor},
+		{opConfirm, "11", stateRumor},
+		{opConfirm, "12", stateNews},
+	}
+	if len(steps) != len(want) {
+		t.Fatalf("audit log = %+v, want %d entries", steps, len(want))
+	}
+	for n, s := range steps {
+		if s.Entry.Op != want[n].op || s.Entry.Actor != want[n].actor || s.State != want[n].state || s.Err != nil {
+			t.Errorf("entry %d = %s by %s, %s, %v, want %s by %s, %s", n, s.Entry.Op, s.Entry.Actor, s.State, s.Err, want[n].op, want[n].actor, want[n].state)
+		}
+	}
+}
+
+func TestAuditLogIsAppendOnly(t *testing.T) {
+	w := auditWitness(t, "10")
+	w.eventTitle = "Fire"
+	e, _ := w.newReport(time.Unix(100, 0))
+	w.receiveUpdate(e)
+
+	other := auditWitness(t, "20", e)
+	disputed, _ := other.dispute(e.ID, time.Unix(200, 0))
+
+	// an update missing the dispute entry doesn't drop it
+	merged, _ := mergeEvents(disputed, e)
+	if len(merged.Audit) != 2 {
+		t.Errorf("merged audit log = %+v, want 2 entries", merged.Audit)
+	}
+
+	tampered := disputed
+	tampered.Audit = append([]AuditEntry(nil), disputed.Audit...)
+	tampered.Audit[1].Actor = "30"
+	if err := validAudit(e, tampered); !errors.Is(err, errBadAuditEntry) {
+		t.Errorf("tampered entry: %v, want %v", err, errBadAuditEntry)
+	}
+	w.receiveUpdate(tampered)
+	if n := len(w.events[w.eventIndex(e.ID)].Audit); n != 1 {
+		t.Errorf("tampered update applied, %d entries", n)
+	}
+
+	w.receiveUpdate(disputed)
+	if n := len(w.events[w.eventIndex(e.ID)].Audit); n != 2 {
+		t.Errorf("valid update dropped, %d entries", n)
+	}
+}
+
+func TestVerifyExportedAudit(t *testing.T) {
+	w := auditWitness(t, "10")
+	w.eventTitle = "Fire"
+	e, _ := w.newReport(time.Unix(100, 0))
+	w.events = []Event{e}
+	e, _ = w.withDetails(e.ID, Detail{Text: "smoke", Author: "10"}, time.Unix(150, 0))
+
+	b, err := exportAudit(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var a auditExport
+	if err := json.Unmarshal(b, &a); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := printAudit(&out, a); err != nil {
+		t.Errorf("exported log failed verification: %v\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "\tdetail\t10\t\"smoke\"") {
+		t.Errorf("detail entry missing from:\n%s", out.String())
+	}
+
+	a.Entries[1].Ref = "fire"
+	out.Reset()
+	if err := printAudit(&out, a); err == nil {
+		t.Errorf("edited log verified:\n%s", out.String())
+	}
+}
+
+func TestAuditedEvent(t *testing.T) {
+	fire, flood, old := Event{ID: "1"}, Event{ID: "2"}, Event{ID: "3", Type: archivedType}
+	w := &witness{events: []Event{fire}, retired: map[string]Event{"3": old}, linkedEvent: &flood}
+
+	for _, want := range []Event{fire, flood, old} {
+		if e, ok := w.auditedEvent(want.ID); !ok || e.ID != want.ID {
+			t.Errorf("auditedEvent(%q) = %+v, %v", want.ID, e, ok)
+		}
+	}
+	if e, ok := w.auditedEvent("4"); ok {
+		t.Errorf("auditedEvent of an unknown event = %+v", e)
+	}
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+	shell "github.com/stateless-minds/go-ipfs-api"
+)
+
+const (
+	formatJSONL = "jsonl"
+	formatCAR   = "car"
+)
+
+// snapshot is the root node of a CAR backup.
+type snapshot struct {
+	Events    []link `json:"events"`
+	CreatedAt int64  `json:"createdAt"`
+}
+
+// snapshotEvent wraps an event in a CAR backup. Its revision and evidence
+// are IPLD links so that exporting the snapshot also packs their blocks.
+type snapshotEvent struct {
+	Event    Event  `json:"event"`
+	Revision *link  `json:"revision,omitempty"`
+	Evidence []link `json:"evidence,omitempty"`
+}
+
+// importReport summarises what an import did to the event store.
+type importReport struct {
+	Added     int
+	Merged    int
+	Unchanged int
+	Unsigned  int
+	// Rejected lists the events that failed the checks of live updates,
+	// with the reason.
+	Rejected  []string
+	Conflicts []string
+}
+
+func (r importReport) String() string {
+	return fmt.Sprintf("%d added, %d merged, %d unchanged, %d unsigned, %d rejected, %d conflicts",
+		r.Added, r.Merged, r.Unchanged, r.Unsigned, len(r.Rejected), len(r.Conflicts))
+}
+
+// backupFormat guesses the backup format from a file name.
+func backupFormat(name string) string {
+	if strings.HasSuffix(strings.ToLower(name), ".car") {
+		return formatCAR
+	}
+	return formatJSONL
+}
+
+// writeJSONL writes one event per line.
+func writeJSONL(w io.Writer, events []Event) error {
+	enc := json.NewEncoder(w)
+	for _, e := range events {
+		err := enc.Encode(e)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readJSONL reads events written by writeJSONL, skipping blank lines.
+func readJSONL(r io.Reader) ([]Event, error) {
+	var events []Event
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		b := bytes.TrimSpace(scanner.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+
+		e := Event{}
+		err := json.Unmarshal(b, &e)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		events = append(events, e)
+	}
+	return events, scanner.Err()
+}
+
+// writeCAR stores events as a snapshot DAG and writes it as a CAR archive.
+func writeCAR(sh *shell.Shell, w io.Writer, events []Event) error {
+	root := snapshot{CreatedAt: time.Now().Unix()}
+	for _, e := range events {
+		node := snapshotEvent{Event: e}
+		if e.Revision != "" {
+			node.Revision = &link{CID: e.Revision}
+		}
+		for _, cid := range e.Evidence {
+			node.Evidence = append(node.Evidence, link{CID: cid})
+		}
+
+		b, err := json.Marshal(node)
+		if err != nil {
+			return err
+		}
+		cid, err := sh.DagPut(b, "dag-json", "dag-cbor")
+		if err != nil {
+			return err
+		}
+		root.Events = append(root.Events, link{CID: cid})
+	}
+
+	b, err := json.Marshal(root)
+	if err != nil {
+		return err
+	}
+	cid, err := sh.DagPut(b, "dag-json", "dag-cbor")
+	if err != nil {
+		return err
+	}
+
+	resp, err := sh.Request("dag/export", cid).Send(context.Background())
+	if err != nil {
+		return err
+	}
+	defer resp.Close()
+	if resp.Error != nil {
+		return resp.Error
+	}
+
+	_, err = io.Copy(w, resp.Output)
+	return err
+}
+
+// readCAR imports a CAR archive written by writeCAR into the node and
+// returns the events of its snapshots.
+func readCAR(sh *shell.Shell, r io.Reader) ([]Event, error) {
+	out, err := sh.DagImport(r, false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	for _, root := range out.Roots {
+		s := snapshot{}
+		err = sh.DagGet(root.Root.Cid.Value, &s)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, l := range s.Events {
+			node := snapshotEvent{}
+			err = sh.DagGet(l.CID, &node)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, node.Event)
+		}
+	}
+	return events, nil
+}
+
+// exportEvents writes every stored event of the given channels, active or
+// archived, in format.
+func exportEvents(sh *shell.Shell, w io.Writer, format string, channels []channel) error {
+	events, err := loadAllEvents(sh, channels)
+	if err != nil {
+		return err
+	}
+	return writeEvents(sh, w, format, events)
+}
+
+// writeEvents writes events in format.
+func writeEvents(sh *shell.Shell, w io.Writer, format string, events []Event) error {
+	if format == formatCAR {
+		return writeCAR(sh, w, events)
+	}
+	return writeJSONL(w, events)
+}
+
+// importEvents validates incoming events and merges them into the store
+// with the rules used for live updates, see witness.validUpdate: the store
+// stands in for the loaded events and l for the lifecycle archives are
+// checked against. Unsigned events are only accepted if they predate
+// signing. Versions that match one side are stored as their signer published
+// them, only ones combining both are signed by key as a new revision. Every
+// event goes to the store of its own channel.
+func importEvents(sh *shell.Shell, key ed25519.PrivateKey, author string, l lifecycle, incoming []Event) (importReport, error) {
+	report := importReport{}
+
+	var channels []channel
+	seen := make(map[channel]bool)
+	for _, e := range incoming {
+		c := parseChannel(e.Channel)
+		if !seen[c] {
+			seen[c] = true
+			channels = append(channels, c)
+		}
+	}
+
+	existing, err := loadAllEvents(sh, channels)
+	if err != nil {
+		return report, err
+	}
+	store := &witness{events: existing, lifecycle: l}
+
+	for _, e := range incoming {
+		n := store.eventIndex(e.ID)
+		local := Event{}
+		if n >= 0 {
+			local = store.events[n]
+		}
+		if err := store.validUpdate(local, n >= 0, e); err != nil {
+			report.Rejected = append(report.Rejected, e.ID+": "+err.Error())
+			continue
+		}
+		if errors.Is(verifyEvent(e), errUnsigned) {
+			report.Unsigned++
+		}
+
+		if n < 0 {
+			err := publishEvent(sh, e, topicUpdateEvent)
+			if err != nil {
+				return report, err
+			}
+			store.events = append(store.events, e)
+			report.Added++
+			continue
+		}
+
+		merged, conflicts := mergeEvents(local, e)
+		for _, c := range conflicts {
+			report.Conflicts = append(report.Conflicts, e.ID+": "+c)
+		}
+		switch {
+		case samePayload(merged, local):
+			report.Unchanged++
+			continue
+		case samePayload(merged, e):
+			merged, err = e, publishEvent(sh, e, topicUpdateEvent)
+		default:
+			merged, err = storeEvent(sh, key, author, merged, topicUpdateEvent)
+		}
+		if err != nil {
+			return report, err
+		}
+		store.events[n] = merged
+		report.Merged++
+	}
+
+	return report, nil
+}
+
+// readBackup decodes the events of a backup in format.
+func readBackup(sh *shell.Shell, r io.Reader, format string) ([]Event, error) {
+	if format == formatCAR {
+		return readCAR(sh, r)
+	}
+	return readJSONL(r)
+}
+
+func (w *witness) renderBackupModal() app.UI {
+	return app.Div().Class("p-modal").ID("backup-modal").Style("display", "none").Body(
+		app.Section().Class("p-modal__dialog").Role("dialog").Aria("modal", true).Aria("labelledby", "modal-title").Aria("describedby", "modal-description").Body(
+			app.Header().Class("p-modal__header").Body(
+				app.H2().Class("p-modal__title").ID("modal-title").Text(w.t("hero.backup")),
+				app.Button().Class("p-modal__close").Aria("label", w.t("modal.close")).Aria("controls", "modal").OnClick(w.closeBackupModal),
+			),
+			app.P().ID("modal-description").Text(w.t("backup.description")),
+			app.If(w.importReport != nil, func() app.UI {
+				r := w.importReport
+				return app.Div().Class("p-card").Body(
+					app.H4().Text(w.t("backup.lastImport")),
+					app.P().Text(w.importSummary(*r)),
+					app.Ul().Class("p-list").Body(
+						app.Range(r.Rejected).Slice(func(n int) app.UI {
+							return app.Li().Class("p-list__item").Text(w.t("backup.rejected") + " " + r.Rejected[n])
+						}),
+						app.Range(r.Conflicts).Slice(func(n int) app.UI {
+							return app.Li().Class("p-list__item").Text(w.t("backup.conflict") + " " + r.Conflicts[n])
+						}),
+					),
+				)
+			}),
+			app.Footer().Class("p-modal__footer").Body(
+				app.Label().Class("p-button").For("backup-import").Text(w.t("backup.import")),
+				app.Input().ID("backup-import").Type("file").Accept(".jsonl,.car").Style("display", "none").OnChange(w.onImportBackup),
+				app.Button().Value(formatCAR).Text(w.t("backup.exportCAR")).OnClick(w.onExportBackup),
+				app.Button().Class("p-button--positive").Value(formatJSONL).Text(w.t("backup.exportJSONL")).OnClick(w.onExportBackup),
+			),
+		),
+	)
+}
+
+// importSummary is importReport.String in the language of the interface.
+func (w *witness) importSummary(r importReport) string {
+	counts := []struct {
+		n  int
+		id string
+	}{
+		{r.Added, "backup.added"},
+		{r.Merged, "backup.merged"},
+		{r.Unchanged, "backup.unchanged"},
+		{r.Unsigned, "backup.unsigned"},
+		{len(r.Rejected), "backup.rejectedCount"},
+		{len(r.Conflicts), "backup.conflicts"},
+	}
+	parts := make([]string, 0, len(counts))
+	for _, c := range counts {
+		parts = append(parts, strconv.Itoa(c.n)+" "+w.t(c.id))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (w *witness) openBackupDialog(ctx app.Context, e app.Event) {
+	app.Window().GetElementByID("backup-modal").Set("style", "display:flex")
+}
+
+func (w *witness) closeBackupModal(ctx app.Context, e app.Event) {
+	app.Window().GetElementByID("backup-modal").Set("style", "display:none")
+}
+
+func (w *witness) onExportBackup(ctx app.Context, e app.Event) {
+	format := ctx.JSSrc().Get("value").String()
+	channels := w.following()
+
+	ctx.Async(func() {
+		var buf bytes.Buffer
+		err := exportEvents(w.sh, &buf, format, channels)
+
+		ctx.Dispatch(func(ctx app.Context) {
+			if err != nil {
+				w.createNotification(ctx, NotificationDanger, w.t(ErrorHeader), w.t("backup.exportFailed"))
+				log.Println(err)
+				return
+			}
+
+			name := "cyber-witness-" + time.Now().Format("2006-01-02") + "." + format
+			mimeType := "application/jsonl"
+			if format == formatCAR {
+				mimeType = "application/vnd.ipld.car"
+			}
+			downloadFile(name, mimeType, buf.Bytes())
+		})
+	})
+}
+
+func (w *witness) onImportBackup(ctx app.Context, e app.Event) {
+	format := backupFormat(ctx.JSSrc().Get("value").String())
+
+	readFile(ctx, ctx.JSSrc(), func(b []byte) {
+		ctx.Async(func() {
+			report := importReport{}
+			events, err := readBackup(w.sh, bytes.NewReader(b), format)
+			if err == nil {
+				report, err = importEvents(w.sh, w.key, w.citizenID, w.lifecycle, events)
+			}
+
+			ctx.Dispatch(func(ctx app.Context) {
+				w.importReport = &report
+				if err != nil {
+					w.createNotification(ctx, NotificationDanger, w.t(ErrorHeader), w.t("backup.importFailed")+" "+err.Error())
+					log.Println(err)
+					return
+				}
+				w.createNotification(ctx, NotificationSuccess, w.t(SuccessHeader), w.t("backup.importFinished")+" "+w.importSummary(report)+".")
+			})
+		})
+	})
+}
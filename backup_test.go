@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	shell "github.com/stateless-minds/go-ipfs-api"
+)
+
+// signedBy returns e signed with key.
+func signedBy(t *testing.T, key ed25519.PrivateKey, e Event) Event {
+	t.Helper()
+	e, err := signEvent(e, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func backupEvents(t *testing.T) []Event {
+	t.Helper()
+	_, key, _ := ed25519.GenerateKey(nil)
+	return []Event{
+		signedBy(t, key, Event{ID: "1", Title: "Fire", Location: "Main St", Type: eventType, CreatedAt: 100, Witnesses: []string{"11"}, ConfirmedBy: 1}),
+		signedBy(t, key, Event{ID: "2", Title: "Flood", Location: "River Rd", Type: eventType, CreatedAt: 300}),
+	}
+}
+
+func TestBackupRoundTrip(t *testing.T) {
+	events := backupEvents(t)
+
+	var jsonl bytes.Buffer
+	if err := writeJSONL(&jsonl, events); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readJSONL(strings.NewReader("\n" + jsonl.String() + "\n"))
+	if err != nil || !reflect.DeepEqual(got, events) {
+		t.Errorf("JSONL round trip = %+v, %v, want %+v", got, err, events)
+	}
+
+	var car bytes.Buffer
+	if err := writeCAR(shell.NewShell(newFakeNetwork(t).addNode()), &car, events); err != nil {
+		t.Fatal(err)
+	}
+	// restoring on another node must not depend on the blocks of the first
+	got, err = readCAR(shell.NewShell(newFakeNetwork(t).addNode()), &car)
+	if err != nil || !reflect.DeepEqual(got, events) {
+		t.Errorf("CAR round trip = %+v, %v, want %+v", got, err, events)
+	}
+	for _, e := range got {
+		if verifyEvent(e) != nil {
+			t.Errorf("restored event %s lost its signature", e.ID)
+		}
+	}
+}
+
+func TestImportEventsChecksUpdates(t *testing.T) {
+	sh := shell.NewShell(newFakeNetwork(t).addNode())
+	var key, reporter, witness, mallory ed25519.PrivateKey
+	for _, k := range []*ed25519.PrivateKey{&key, &reporter, &witness, &mallory} {
+		_, *k, _ = ed25519.GenerateKey(nil)
+	}
+
+	now := time.Now().Unix()
+	fire := signedBy(t, reporter, withState(Event{ID: "1", Title: "Fire", Type: eventType, Reporter: "10", ReporterKey: signerID(reporter), CreatedAt: now}))
+	legacy := Event{ID: "legacy", Title: "Old", Type: eventType, CreatedAt: now}
+	for _, e := range []Event{fire, legacy} {
+		b, _ := json.Marshal(e)
+		if err := sh.OrbitDocsPut(channel{}.dbName(), b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	confirmed := fire
+	confirmed.Witnesses, confirmed.ConfirmedBy = []string{"11"}, 1
+	retitled := fire
+	retitled.Title = "Small fire"
+	stripped := fire
+	stripped.Witnesses, stripped.ConfirmedBy, stripped.Signature = []string{"x1"}, 1, ""
+	tampered := fire
+	tampered.Tags = []string{"hoax"}
+	miscounted := fire
+	miscounted.ConfirmedBy = 5
+	archived := fire
+	archived.Type = archivedType
+
+	report, err := importEvents(sh, key, "10", defaultLifecycle, []Event{
+		signedBy(t, reporter, withState(Event{ID: "2", Title: "Flood", Type: eventType, Reporter: "10", ReporterKey: signerID(reporter), CreatedAt: now})),
+		signedBy(t, witness, confirmed),
+		signedBy(t, mallory, retitled),
+		withState(stripped),
+		tampered,
+		signedBy(t, mallory, miscounted),
+		signedBy(t, mallory, withState(archived)),
+		legacy,
+		{ID: "legacy2", Title: "Older", Type: eventType, CreatedAt: now},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Added != 2 || report.Merged != 1 || report.Unchanged != 2 || report.Unsigned != 2 {
+		t.Errorf("import = %v, want 2 added, 1 merged, 2 unchanged, 2 unsigned", report)
+	}
+	if len(report.Rejected) != 4 {
+		t.Errorf("rejected %q, want the stripped, tampered, miscounted and unexpired versions", report.Rejected)
+	}
+	if len(report.Conflicts) != 1 || !strings.Contains(report.Conflicts[0], `title: "Fire" kept, "Small fire" ignored`) {
+		t.Errorf("conflicts = %q", report.Conflicts)
+	}
+
+	events, err := loadAllEvents(sh, []channel{{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := map[string]Event{}
+	for _, e := range events {
+		stored[e.ID] = e
+	}
+	if e := stored[fire.ID]; e.Title != "Fire" || e.Type != eventType || !reflect.DeepEqual(e.Witnesses, []string{"11"}) || verifyEvent(e) != nil {
+		t.Errorf("stored fire = %+v", e)
+	}
+	if len(stored) != 4 {
+		t.Errorf("stored %d events, want 4: %+v", len(stored), events)
+	}
+}
+
+func TestMergeEvents(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(nil)
+	signed := func(e Event) Event { return signedBy(t, key, e) }
+	base := Event{ID: "1", Title: "Fire", Location: "Main St", Type: eventType, CreatedAt: 100, Details: []Detail{{Text: "smoke", Author: "10"}}}
+	with := func(change func(e *Event)) Event {
+		e := base
+		change(&e)
+		return e
+	}
+
+	tests := []struct {
+		name      string
+		local     Event
+		incoming  Event
+		want      func(e Event) bool
+		conflicts int
+		signature string
+	}{
+		{
+			name:     "union of witnesses",
+			local:    signed(with(func(e *Event) { e.Witnesses, e.ConfirmedBy = []string{"11"}, 1 })),
+			incoming: signed(with(func(e *Event) { e.Witnesses, e.ConfirmedBy = []string{"12"}, 1 })),
+			want: func(e Event) bool {
+				return reflect.DeepEqual(e.Witnesses, []string{"11", "12"}) && e.ConfirmedBy == 2 && e.state() == stateNews
+			},
+			signature: "none",
+		},
+		{
+			name:     "union of details",
+			local:    base,
+			incoming: with(func(e *Event) { e.Details = append(e.Details, Detail{Text: "flames", Author: "11"}) }),
+			want:     func(e Event) bool { return len(e.Details) == 2 },
+		},
+		{
+			name:      "conflicts keep the local value",
+			local:     base,
+			incoming:  with(func(e *Event) { e.Title, e.Location = "Flood", "River Rd" }),
+			want:      func(e Event) bool { return e.Title == "Fire" && e.Location == "Main St" },
+			conflicts: 2,
+		},
+		{
+			name:     "earliest creation",
+			local:    base,
+			incoming: with(func(e *Event) { e.CreatedAt = 50 }),
+			want:     func(e Event) bool { return e.CreatedAt == 50 },
+		},
+		{
+			name:     "archived wins over active",
+			local:    base,
+			incoming: with(func(e *Event) { e.Type, e.ArchivedAt = archivedType, 300 }),
+			want:     func(e Event) bool { return e.Type == archivedType && e.ArchivedAt == 300 },
+		},
+		{
+			name:     "merged wins over archived",
+			local:    with(func(e *Event) { e.Type = mergedType; e.MergeInto = "2" }),
+			incoming: with(func(e *Event) { e.Type = archivedType }),
+			want:     func(e Event) bool { return e.Type == mergedType && e.MergeInto == "2" },
+		},
+		{
+			name:      "signature of the matching side",
+			local:     base,
+			incoming:  signed(base),
+			want:      func(e Event) bool { return verifyEvent(e) == nil },
+			signature: "incoming",
+		},
+	}
+	for _, tt := range tests {
+		merged, conflicts := mergeEvents(tt.local, tt.incoming)
+		if !tt.want(merged) {
+			t.Errorf("%s: merged %+v", tt.name, merged)
+		}
+		if len(conflicts) != tt.conflicts {
+			t.Errorf("%s: conflicts %q, want %d", tt.name, conflicts, tt.conflicts)
+		}
+		switch tt.signature {
+		case "none":
+			if merged.Signature != "" {
+				t.Errorf("%s: combined version signed by %s", tt.name, merged.Signer)
+			}
+		case "incoming":
+			if merged.Signature != tt.incoming.Signature {
+				t.Errorf("%s: signature of the incoming version dropped", tt.name)
+			}
+		}
+	}
+}
+
+func TestImportEventsKeepsSignatures(t *testing.T) {
+	sh := shell.NewShell(newFakeNetwork(t).addNode())
+	var reporter, witness, author, importer ed25519.PrivateKey
+	for _, k := range []*ed25519.PrivateKey{&reporter, &witness, &author, &importer} {
+		_, *k, _ = ed25519.GenerateKey(nil)
+	}
+
+	fire := signedBy(t, reporter, Event{ID: "1", Title: "Fire", Location: "Main St", Type: eventType, CreatedAt: 100, Details: []Detail{{Text: "flames", Author: "10"}}})
+	b, _ := json.Marshal(fire)
+	if err := sh.OrbitDocsPut(channel{}.dbName(), b); err != nil {
+		t.Fatal(err)
+	}
+	stored := func() Event {
+		events, err := loadAllEvents(sh, []channel{{}})
+		if err != nil || len(events) != 1 {
+			t.Fatalf("stored %+v, %v", events, err)
+		}
+		return events[0]
+	}
+
+	confirmed := fire
+	confirmed.Witnesses, confirmed.ConfirmedBy = []string{"11"}, 1
+	confirmed = signedBy(t, witness, confirmed)
+	if _, err := importEvents(sh, importer, "13", defaultLifecycle, []Event{confirmed}); err != nil {
+		t.Fatal(err)
+	}
+	if e := stored(); e.Signer != confirmed.Signer || e.Signature != confirmed.Signature {
+		t.Errorf("newer version stored as %+v, want it as its signer published it", e)
+	}
+
+	detailed := fire
+	detailed.Details = append(detailed.Details, Detail{Text: "smoke", Author: "12"})
+	report, err := importEvents(sh, importer, "13", defaultLifecycle, []Event{signedBy(t, author, detailed)})
+	if err != nil || report.Merged != 1 {
+		t.Fatalf("import = %v, %v", report, err)
+	}
+	if e := stored(); e.Signer != signerID(importer) || verifyEvent(e) != nil || len(e.Witnesses) != 1 || len(e.Details) != 2 {
+		t.Errorf("combined version = %+v, want it signed by the importing citizen", e)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// BIP-340 Schnorr signatures over secp256k1, which Nostr signs events
+// with. The secp256k1 module only ships Decred's Schnorr variant, so the
+// scheme is built here on its curve arithmetic.
+
+var errBadNostrKey = errors.New("not a valid secp256k1 secret key")
+
+// nostrKey is a secp256k1 key pair with the x-only public key BIP-340 uses.
+type nostrKey struct {
+	secret secp256k1.ModNScalar
+	pub    [32]byte
+}
+
+// newNostrKey returns the key pair of a 32 byte secret key.
+func newNostrKey(secret []byte) (*nostrKey, error) {
+	k := &nostrKey{}
+	if len(secret) != 32 || k.secret.SetByteSlice(secret) || k.secret.IsZero() {
+		return nil, errBadNostrKey
+	}
+
+	var p secp256k1.JacobianPoint
+	secp256k1.ScalarBaseMultNonConst(&k.secret, &p)
+	p.ToAffine()
+	p.X.PutBytes(&k.pub)
+	return k, nil
+}
+
+// generateNostrKey returns a new random key pair.
+func generateNostrKey() (*nostrKey, error) {
+	for {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		k, err := newNostrKey(secret)
+		if err == nil {
+			return k, nil
+		}
+	}
+}
+
+// publicKey returns the hex encoded x-only public key Nostr events name
+// their author with.
+func (k *nostrKey) publicKey() string {
+	return hex.EncodeToString(k.pub[:])
+}
+
+// taggedHash is the domain separated hash of BIP-340.
+func taggedHash(tag string, parts ...[]byte) [32]byte {
+	t := sha256.Sum256([]byte(tag))
+	h := sha256.New()
+	h.Write(t[:])
+	h.Write(t[:])
+	for _, p := range parts {
+		h.Write(p)
+	}
+	var sum [32]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// sign returns the signature of msg using aux as auxiliary randomness.
+func (k *nostrKey) sign(msg []byte, aux [32]byte) [64]byte {
+	var p secp256k1.JacobianPoint
+	secp256k1.ScalarBaseMultNonConst(&k.secret, &p)
+	p.ToAffine()
+	d := k.secret
+	if p.Y.IsOdd() {
+		d.Negate()
+	}
+
+	// the nonce hashes the secret key masked with the auxiliary randomness
+	dBytes := d.Bytes()
+	mask := taggedHash("BIP0340/aux", aux[:])
+	for i := range dBytes {
+		dBytes[i] ^= mask[i]
+	}
+	h := taggedHash("BIP0340/nonce", dBytes[:], k.pub[:], msg)
+	var nonce secp256k1.ModNScalar
+	nonce.SetBytes(&h)
+	if nonce.IsZero() {
+		// happens with negligible probability, retry with other randomness
+		aux[0]++
+		return k.sign(msg, aux)
+	}
+
+	var r secp256k1.JacobianPoint
+	secp256k1.ScalarBaseMultNonConst(&nonce, &r)
+	r.ToAffine()
+	if r.Y.IsOdd() {
+		nonce.Negate()
+	}
+	var rx [32]byte
+	r.X.PutBytes(&rx)
+
+	e := challenge(rx[:], k.pub[:], msg)
+	s := new(secp256k1.ModNScalar).Mul2(&e, &d).Add(&nonce)
+
+	var sig [64]byte
+	copy(sig[:32], rx[:])
+	s.PutBytesUnchecked(sig[32:])
+	return sig
+}
+
+// challenge returns the challenge scalar of a signature.
+func challenge(rx, pub, msg []byte) secp256k1.ModNScalar {
+	h := taggedHash("BIP0340/challenge", rx, pub, msg)
+	var e secp256k1.ModNScalar
+	e.SetBytes(&h)
+	return e
+}
+
+// verifySchnorr reports whether sig is a valid signature of msg by the
+// x-only public key pub.
+func verifySchnorr(pub, msg, sig []byte) bool {
+	if len(pub) != 32 || len(sig) != 64 {
+		return false
+	}
+
+	var px, py secp256k1.FieldVal
+	if px.SetByteSlice(pub) || !secp256k1.DecompressY(&px, false, &py) {
+		return false
+	}
+	var rx secp256k1.FieldVal
+	if rx.SetByteSlice(sig[:32]) {
+		return false
+	}
+	var s secp256k1.ModNScalar
+	if s.SetByteSlice(sig[32:]) {
+		return false
+	}
+
+	// R = s⋅G - e⋅P must have an even y and the x of the signature
+	e := challenge(sig[:32], pub, msg)
+	e.Negate()
+	p := secp256k1.MakeJacobianPoint(&px, &py, new(secp256k1.FieldVal).SetInt(1))
+	var sG, eP, r secp256k1.JacobianPoint
+	secp256k1.ScalarBaseMultNonConst(&s, &sG)
+	secp256k1.ScalarMultNonConst(&e, &p, &eP)
+	secp256k1.AddNonConst(&sG, &eP, &r)
+	if (r.X.IsZero() && r.Y.IsZero()) || r.Z.IsZero() {
+		return false
+	}
+	r.ToAffine()
+	return !r.Y.IsOdd() && r.X.Equals(&rx)
+}
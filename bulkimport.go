@@ -0,0 +1,415 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	shell "github.com/stateless-minds/go-ipfs-api"
+)
+
+// Formats of report spreadsheets and maps.
+const (
+	formatCSV     = "csv"
+	formatGeoJSON = "geojson"
+)
+
+// defaultReportRate is the delay between two imported reports, so a bulk
+// import doesn't flood the channel.
+const defaultReportRate = 2 * time.Second
+
+var errBadReport = errors.New("invalid report")
+
+// draftReport is a report read from a file, before it is validated.
+type draftReport struct {
+	// Line is the CSV line or the GeoJSON feature number, counted from 1.
+	Line     int
+	Title    string
+	Details  string
+	Location string
+	Language string
+	Tags     []string
+	// Date is when the report was observed, if the file says.
+	Date     time.Time
+	Lat, Lon *float64
+	// err is set when a value of the report couldn't be read.
+	err error
+}
+
+// reportFormat guesses the format of a report file from its name.
+func reportFormat(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".geojson", ".json":
+		return formatGeoJSON
+	default:
+		return formatCSV
+	}
+}
+
+// reportField maps the column and property names a file may use to the
+// field they fill.
+var reportField = map[string]string{
+	"title":       "title",
+	"name":        "title",
+	"details":     "details",
+	"description": "details",
+	"location":    "location",
+	"address":     "location",
+	"place":       "location",
+	"language":    "language",
+	"lang":        "language",
+	"tags":        "tags",
+	"date":        "date",
+	"time":        "date",
+	"lat":         "lat",
+	"latitude":    "lat",
+	"lon":         "lon",
+	"lng":         "lon",
+	"longitude":   "lon",
+}
+
+// reportDateLayouts are the date formats accepted in report files.
+var reportDateLayouts = []string{time.RFC3339, "2006-01-02 15:04", "2006-01-02"}
+
+// set fills the field a column or property called name maps to. Unknown
+// names are ignored.
+func (d *draftReport) set(name, value string) error {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+
+	field := reportField[strings.ToLower(strings.TrimSpace(name))]
+	switch field {
+	case "title":
+		d.Title = value
+	case "details":
+		d.Details = value
+	case "location":
+		d.Location = value
+	case "language":
+		d.Language = baseLanguage(value)
+	case "tags":
+		d.Tags = parseTags(strings.ReplaceAll(value, ";", ","))
+	case "date":
+		for _, layout := range reportDateLayouts {
+			if t, err := time.Parse(layout, value); err == nil {
+				d.Date = t
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: unknown date format %q", errBadReport, value)
+	case "lat", "lon":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%w: %s is not a number", errBadReport, name)
+		}
+		if field == "lat" {
+			d.Lat = &f
+		} else {
+			d.Lon = &f
+		}
+	}
+	return nil
+}
+
+// readReports reads the reports of a file in the given format.
+func readReports(r io.Reader, format string) ([]draftReport, error) {
+	switch format {
+	case formatCSV:
+		return readReportsCSV(r)
+	case formatGeoJSON:
+		return readReportsGeoJSON(r)
+	default:
+		return nil, fmt.Errorf("unknown report format %q", format)
+	}
+}
+
+// readReportsCSV reads a spreadsheet of reports whose first row names the
+// columns.
+func readReportsCSV(r io.Reader) ([]draftReport, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading the CSV header: %w", err)
+	}
+	// spreadsheets saved as UTF-8 often start with a byte order mark
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	var drafts []draftReport
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return drafts, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+
+		d := draftReport{Line: line}
+		for i, value := range record {
+			if i >= len(header) {
+				break
+			}
+			if err := d.set(header[i], value); err != nil && d.err == nil {
+				d.err = err
+			}
+		}
+		drafts = append(drafts, d)
+	}
+}
+
+// geoJSONFeature is a GeoJSON feature, the part of it reports use.
+type geoJSONFeature struct {
+	Type     string `json:"type"`
+	Geometry *struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	} `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+// readReportsGeoJSON reads a GeoJSON feature collection, or a single
+// feature, of reports. Features must be points or have no geometry.
+func readReportsGeoJSON(r io.Reader) ([]draftReport, error) {
+	var doc struct {
+		Type     string           `json:"type"`
+		Features []geoJSONFeature `json:"features"`
+		geoJSONFeature
+	}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	features := doc.Features
+	switch doc.Type {
+	case "FeatureCollection":
+	case "Feature":
+		features = []geoJSONFeature{doc.geoJSONFeature}
+	default:
+		return nil, fmt.Errorf("GeoJSON of type %q holds no features", doc.Type)
+	}
+
+	var drafts []draftReport
+	for i, f := range features {
+		d := draftReport{Line: i + 1}
+		for name, v := range f.Properties {
+			var value string
+			switch v := v.(type) {
+			case string:
+				value = v
+			case float64:
+				value = strconv.FormatFloat(v, 'f', -1, 64)
+			case []any:
+				var items []string
+				for _, item := range v {
+					items = append(items, fmt.Sprint(item))
+				}
+				value = strings.Join(items, ",")
+			}
+			if err := d.set(name, value); err != nil && d.err == nil {
+				d.err = err
+			}
+		}
+
+		if f.Geometry != nil {
+			var position []float64
+			switch {
+			case f.Geometry.Type != "Point":
+				d.err = fmt.Errorf("%w: %s geometry, only points are supported", errBadReport, f.Geometry.Type)
+			case json.Unmarshal(f.Geometry.Coordinates, &position) != nil || len(position) < 2:
+				d.err = fmt.Errorf("%w: bad coordinates", errBadReport)
+			default:
+				// GeoJSON puts the longitude first
+				d.Lon, d.Lat = &position[0], &position[1]
+			}
+		}
+		drafts = append(drafts, d)
+	}
+	return drafts, nil
+}
+
+// validate checks that d can be published as a report.
+func (d draftReport) validate(now time.Time) error {
+	switch {
+	case d.err != nil:
+		return d.err
+	case d.Title == "":
+		return fmt.Errorf("%w: title is missing", errBadReport)
+	case d.Location == "" && (d.Lat == nil || d.Lon == nil):
+		return fmt.Errorf("%w: location is missing", errBadReport)
+	case (d.Lat == nil) != (d.Lon == nil):
+		return fmt.Errorf("%w: latitude and longitude go together", errBadReport)
+	case d.Lat != nil && (*d.Lat < -90 || *d.Lat > 90 || *d.Lon < -180 || *d.Lon > 180):
+		return fmt.Errorf("%w: coordinates out of range", errBadReport)
+	case d.Date.After(now):
+		return fmt.Errorf("%w: date is in the future", errBadReport)
+	}
+	if _, ok := languageNames[d.Language]; d.Language != "" && !ok {
+		return fmt.Errorf("%w: unknown language %q", errBadReport, d.Language)
+	}
+	return nil
+}
+
+// location returns the location of the report, with its coordinates if
+// the file has them and the location doesn't already name them, as it does
+// for reports exported after an earlier import.
+func (d draftReport) location() string {
+	if lat, _ := locationCoordinates(d.Location); d.Lat == nil || lat != nil {
+		return d.Location
+	}
+	coordinates := fmt.Sprintf("%.5f, %.5f", *d.Lat, *d.Lon)
+	if d.Location == "" {
+		return coordinates
+	}
+	return d.Location + " (" + coordinates + ")"
+}
+
+// coordinatesPattern finds a "latitude, longitude" pair in a location, as
+// location writes them. Decimals are required so that addresses like
+// "Building 12, 3rd floor" aren't taken for coordinates.
+var coordinatesPattern = regexp.MustCompile(`(-?\d{1,2}\.\d+)\s*,\s*(-?\d{1,3}\.\d+)`)
+
+// locationCoordinates returns the last latitude and longitude pair found in
+// a location.
+func locationCoordinates(location string) (lat, lon *float64) {
+	matches := coordinatesPattern.FindAllStringSubmatch(location, -1)
+	if len(matches) == 0 {
+		return nil, nil
+	}
+	m := matches[len(matches)-1]
+	la, err1 := strconv.ParseFloat(m[1], 64)
+	lo, err2 := strconv.ParseFloat(m[2], 64)
+	if err1 != nil || err2 != nil || la < -90 || la > 90 || lo < -180 || lo > 180 {
+		return nil, nil
+	}
+	return &la, &lo
+}
+
+// details returns the description of the report. Events are dated when
+// they are published, so the date the report was observed goes into the
+// description.
+func (d draftReport) details() string {
+	if d.Date.IsZero() {
+		return d.Details
+	}
+	date := d.Date.Format("2006-01-02 15:04 -07:00")
+	if d.Date.Equal(d.Date.Truncate(24 * time.Hour)) {
+		date = d.Date.Format("2006-01-02")
+	}
+	if d.Details == "" {
+		return "Observed " + date + "."
+	}
+	return "Observed " + date + ". " + d.Details
+}
+
+// plannedReport is what a bulk import does with a report.
+type plannedReport struct {
+	Draft draftReport
+	// Err is set for reports that can't be published.
+	Err error
+	// Duplicate names the stored event or the earlier line of the file the
+	// report duplicates.
+	Duplicate string
+}
+
+// duplicateKey is what two reports of the same event have in common: their
+// title, ignoring case and spacing, like the report form refuses titles
+// that were already reported.
+func duplicateKey(title string) string {
+	return strings.ToLower(strings.Join(strings.Fields(title), " "))
+}
+
+// planReports validates drafts and looks for duplicates among the events
+// already stored and earlier drafts.
+func planReports(drafts []draftReport, existing []Event, now time.Time) []plannedReport {
+	seen := make(map[string]string)
+	for _, e := range existing {
+		seen[duplicateKey(e.Title)] = fmt.Sprintf("event %s %q", e.ID, e.Title)
+	}
+
+	plan := make([]plannedReport, 0, len(drafts))
+	for _, d := range drafts {
+		p := plannedReport{Draft: d, Err: d.validate(now)}
+		if p.Err == nil {
+			key := duplicateKey(d.Title)
+			p.Duplicate = seen[key]
+			if p.Duplicate == "" {
+				seen[key] = fmt.Sprintf("line %d", d.Line)
+			}
+		}
+		plan = append(plan, p)
+	}
+	return plan
+}
+
+// String describes what happens to the report.
+func (p plannedReport) String() string {
+	switch {
+	case p.Err != nil:
+		return fmt.Sprintf("line %d: %v", p.Draft.Line, p.Err)
+	case p.Duplicate != "":
+		return fmt.Sprintf("line %d: %q duplicates %s", p.Draft.Line, p.Draft.Title, p.Duplicate)
+	default:
+		return fmt.Sprintf("line %d: %q at %s", p.Draft.Line, p.Draft.Title, p.Draft.location())
+	}
+}
+
+// publishReports publishes the reports of plan that are valid and not
+// duplicates through the report form's create path, one every rate. It
+// calls published with every report published and returns how many were.
+func publishReports(w *witness, plan []plannedReport, rate time.Duration, published func(p plannedReport, e Event)) (int, error) {
+	n := 0
+	for _, p := range plan {
+		if p.Err != nil || p.Duplicate != "" {
+			continue
+		}
+		if n > 0 {
+			time.Sleep(rate)
+		}
+
+		w.eventTitle = p.Draft.Title
+		w.eventDetails = p.Draft.details()
+		w.eventLocation = p.Draft.location()
+		w.eventLanguage = p.Draft.Language
+		w.eventTagInput = strings.Join(p.Draft.Tags, ",")
+		event, ok := w.newReport(time.Now())
+		if !ok {
+			continue
+		}
+		event, err := w.submitReport(event, nil)
+		if err != nil {
+			return n, err
+		}
+		w.events = append(w.events, event)
+		n++
+		published(p, event)
+	}
+	return n, nil
+}
+
+// newImportWitness returns the citizen bulk imports report as, knowing the
+// active events of channel c.
+func newImportWitness(sh *shell.Shell, key ed25519.PrivateKey, citizenID string, c channel, events []Event) *witness {
+	w := &witness{sh: sh, key: key, citizenID: citizenID, channel: c, history: make(map[string][]revision), noNews: true}
+	for _, e := range events {
+		if e.Type == eventType && parseChannel(e.Channel) == c {
+			w.events = append(w.events, e)
+		}
+	}
+	return w
+}
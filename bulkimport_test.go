@@ -0,0 +1,167 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	shell "github.com/stateless-minds/go-ipfs-api"
+)
+
+func TestReadReportsCSV(t *testing.T) {
+	csv := "\ufeffTitle,Description,Address,Latitude,Longitude,Lang,Tags,Date,Source\n" +
+		`Fire,"smoke, flames",Main St,42.6977,23.3219,EN,"Fire; Health",2024-05-01,notebook` + "\n" +
+		`Flood,,,,,,,yesterday,` + "\n" +
+		`,no title,Elm St` + "\n"
+
+	drafts, err := readReportsCSV(strings.NewReader(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drafts) != 3 {
+		t.Fatalf("read %d reports, want 3", len(drafts))
+	}
+
+	d := drafts[0]
+	if d.Line != 2 || d.Title != "Fire" || d.Details != "smoke, flames" || d.Location != "Main St" || d.Language != "en" {
+		t.Errorf("first report = %+v", d)
+	}
+	if !reflect.DeepEqual(d.Tags, []string{"fire", "health"}) {
+		t.Errorf("tags = %q", d.Tags)
+	}
+	if d.location() != "Main St (42.69770, 23.32190)" || d.details() != "Observed 2024-05-01. smoke, flames" {
+		t.Errorf("mapped to %q, %q", d.location(), d.details())
+	}
+
+	now := time.Now()
+	if err := d.validate(now); err != nil {
+		t.Errorf("first report: %v", err)
+	}
+	if err := drafts[1].validate(now); !errors.Is(err, errBadReport) || !strings.Contains(err.Error(), "date") {
+		t.Errorf("report with a bad date: %v", err)
+	}
+	if err := drafts[2].validate(now); !errors.Is(err, errBadReport) || !strings.Contains(err.Error(), "title") {
+		t.Errorf("report without a title: %v", err)
+	}
+}
+
+func TestReadReportsGeoJSON(t *testing.T) {
+	geojson := `{"type": "FeatureCollection", "features": [
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [23.3219, 42.6977]},
+		 "properties": {"name": "Fire", "description": "smoke", "tags": ["fire", "Health"]}},
+		{"type": "Feature", "geometry": null, "properties": {"title": "Flood", "location": "River Rd", "language": "xx"}},
+		{"type": "Feature", "geometry": {"type": "LineString", "coordinates": [[0, 0], [1, 1]]}, "properties": {"title": "Road"}}
+	]}`
+
+	drafts, err := readReportsGeoJSON(strings.NewReader(geojson))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drafts) != 3 {
+		t.Fatalf("read %d reports, want 3", len(drafts))
+	}
+
+	now := time.Now()
+	if d := drafts[0]; d.validate(now) != nil || d.location() != "42.69770, 23.32190" || !reflect.DeepEqual(d.Tags, []string{"fire", "health"}) {
+		t.Errorf("point feature = %+v, %v", d, d.validate(now))
+	}
+	if err := drafts[1].validate(now); !errors.Is(err, errBadReport) || !strings.Contains(err.Error(), "language") {
+		t.Errorf("report in an unknown language: %v", err)
+	}
+	if err := drafts[2].validate(now); !errors.Is(err, errBadReport) {
+		t.Errorf("line feature: %v", err)
+	}
+
+	if _, err := readReportsGeoJSON(strings.NewReader(`{"type": "Point", "coordinates": [0, 0]}`)); err == nil {
+		t.Error("bare geometry read as reports")
+	}
+}
+
+func TestDraftReportLocation(t *testing.T) {
+	lat, lon := 42.6977, 23.3219
+	tests := []struct {
+		name     string
+		location string
+		lat, lon *float64
+		want     string
+	}{
+		{"location only", "Main St", nil, nil, "Main St"},
+		{"coordinates only", "", &lat, &lon, "42.69770, 23.32190"},
+		{"location and coordinates", "Main St", &lat, &lon, "Main St (42.69770, 23.32190)"},
+		{"location naming the coordinates", "Main St (42.69770, 23.32190)", &lat, &lon, "Main St (42.69770, 23.32190)"},
+	}
+	for _, tt := range tests {
+		d := draftReport{Location: tt.location, Lat: tt.lat, Lon: tt.lon}
+		if got := d.location(); got != tt.want {
+			t.Errorf("%s: location = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPlanReportsFindsDuplicates(t *testing.T) {
+	existing := []Event{{ID: "1", Type: eventType, Title: "Fire on  Main St"}}
+	drafts := []draftReport{
+		{Line: 2, Title: "fire on main st", Location: "Main St"},
+		{Line: 3, Title: "Flood", Location: "River Rd"},
+		{Line: 4, Title: "FLOOD", Location: "River Rd"},
+		{Line: 5, Title: "Storm"},
+	}
+
+	plan := planReports(drafts, existing, time.Now())
+	if plan[0].Duplicate != `event 1 "Fire on  Main St"` {
+		t.Errorf("line 2 duplicates %q", plan[0].Duplicate)
+	}
+	if plan[1].Duplicate != "" || plan[1].Err != nil {
+		t.Errorf("line 3 = %v", plan[1])
+	}
+	if plan[2].Duplicate != "line 3" {
+		t.Errorf("line 4 duplicates %q", plan[2].Duplicate)
+	}
+	if plan[3].Err == nil {
+		t.Error("report without a location planned")
+	}
+}
+
+func TestImportedReportsReachPeers(t *testing.T) {
+	n := newFakeNetwork(t)
+	p := newPeer(t, n)
+	existing := p.report("Fire", "smoke", "Main St")
+	waitFor(t, "the report", func(p *peer) bool { _, ok := p.event(existing); return ok }, p)
+
+	sh := shell.NewShell(n.addNode())
+	citizenID, err := localCitizenID(sh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, key, _ := ed25519.GenerateKey(nil)
+	events, err := loadAllEvents(sh, []channel{{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	drafts, err := readReportsCSV(strings.NewReader("title,location,details,tags\nFire,Main St,,\nFlood,River Rd,water rising,weather\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := planReports(drafts, events, time.Now())
+
+	var ids []string
+	count, err := publishReports(newImportWitness(sh, key, citizenID, channel{}, events), plan, time.Millisecond, func(_ plannedReport, e Event) {
+		ids = append(ids, e.ID)
+	})
+	if err != nil || count != 1 {
+		t.Fatalf("published %d reports: %v", count, err)
+	}
+
+	waitFor(t, "the imported report", func(p *peer) bool { _, ok := p.event(ids[0]); return ok }, p)
+	e, _ := p.event(ids[0])
+	if e.Title != "Flood" || e.Reporter != citizenID || e.Details[0].Text != "water rising" || !e.hasTag("weather") || verifyEvent(e) != nil {
+		t.Errorf("imported report = %+v", e)
+	}
+	if steps := replayAudit(e.ID, e.auditLog()); len(steps) != 1 || steps[0].Entry.Op != opReport || steps[0].Err != nil {
+		t.Errorf("audit log of the imported report = %+v", steps)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+// cacheSaveDelay batches the cache writes caused by a burst of updates.
+const cacheSaveDelay = 2 * time.Second
+
+// eventCache keeps the last known events of every channel on the device, so
+// the app can show them before the docs store has answered. The browser
+// keeps them in IndexedDB and the native binary in a file, see
+// newEventCache.
+type eventCache interface {
+	// load returns the cached snapshot of channel c. A channel that was
+	// never cached has an empty snapshot.
+	load(c channel) (cachedEvents, error)
+	// save replaces the snapshot of channel c.
+	save(c channel, s cachedEvents) error
+}
+
+// cachedEvents is the snapshot of a channel kept in the cache. The revision
+// of every event tells whether the store has a newer version of it.
+type cachedEvents struct {
+	Events   []Event `json:"events"`
+	SyncedAt int64   `json:"syncedAt"`
+}
+
+// reconcileEvents compares the cached events of a channel with the ones the
+// store returned. It returns the stored events that are new or have another
+// revision than their cached copy, and the IDs of cached events the store no
+// longer lists.
+func reconcileEvents(cached, stored []Event) ([]Event, []string) {
+	revisions := make(map[string]string, len(cached))
+	for _, e := range cached {
+		revisions[e.ID] = e.Revision
+	}
+
+	var changed []Event
+	listed := make(map[string]bool, len(stored))
+	for _, e := range stored {
+		listed[e.ID] = true
+		if rev, ok := revisions[e.ID]; !ok || rev != e.Revision {
+			changed = append(changed, e)
+		}
+	}
+
+	var gone []string
+	for _, e := range cached {
+		if !listed[e.ID] {
+			gone = append(gone, e.ID)
+		}
+	}
+	return changed, gone
+}
+
+// loadEvents shows the cached events of the current channel, then syncs
+// them with the docs store in the background.
+func (w *witness) loadEvents(ctx app.Context) {
+	c := w.channel
+	w.syncing = true
+
+	ctx.Async(func() {
+		cached, err := w.cache.load(c)
+		if err != nil {
+			log.Println("Could not read cached events of " + c.name() + ": " + err.Error())
+		}
+
+		ctx.Dispatch(func(ctx app.Context) {
+			if c != w.channel {
+				return
+			}
+			for _, e := range cached.Events {
+				w.receiveUpdate(e)
+			}
+		})
+
+		events, err := queryEvents(w.sh, c, eventType)
+
+		ctx.Dispatch(func(ctx app.Context) {
+			if c != w.channel {
+				return
+			}
+			w.syncing = false
+			if err != nil {
+				log.Println(err)
+				if digestConfigured() {
+					w.createNotification(ctx, NotificationWarning, w.t("status.offline"), w.t("channel.loadFailed")+" "+c.label(w.language)+", "+w.t("digest.instead"))
+					w.loadDigest(ctx)
+					return
+				}
+				w.createNotification(ctx, NotificationDanger, w.t(ErrorHeader), w.t("channel.loadFailed")+" "+c.label(w.language)+". "+w.t("notifications.tryLater"))
+				return
+			}
+
+			changed, gone := reconcileEvents(cached.Events, events)
+			for _, id := range gone {
+				w.removeEvent(id)
+			}
+			for _, e := range changed {
+				w.receiveUpdate(e)
+			}
+			w.archiveExpired(ctx)
+			w.scheduleCacheSave(ctx)
+		})
+	})
+}
+
+// removeEvent drops an event from the active views.
+func (w *witness) removeEvent(id string) {
+	if n := w.eventIndex(id); n >= 0 {
+		w.events = append(w.events[:n], w.events[n+1:]...)
+		w.updateNoNews()
+	}
+}
+
+// scheduleCacheSave writes the events of the current channel to the cache
+// after cacheSaveDelay, unless a write is already pending. Nothing is
+// written while the channel is syncing since its events are not reconciled
+// with the store yet.
+func (w *witness) scheduleCacheSave(ctx app.Context) {
+	if w.cacheSavePending || w.syncing {
+		return
+	}
+	w.cacheSavePending = true
+
+	c := w.channel
+	ctx.After(cacheSaveDelay, func(ctx app.Context) {
+		w.cacheSavePending = false
+		if c != w.channel || w.syncing {
+			return
+		}
+
+		s := cachedEvents{
+			Events:   append([]Event(nil), w.events...),
+			SyncedAt: time.Now().Unix(),
+		}
+		ctx.Async(func() {
+			err := w.cache.save(c, s)
+			if err != nil {
+				log.Println("Could not cache events of " + c.name() + ": " + err.Error())
+			}
+		})
+	})
+}
+
+func (w *witness) renderSyncState() app.UI {
+	return app.If(w.syncing, func() app.UI {
+		return app.Span().Class("p-status-label--information").Role("status").Text(w.t("status.syncing"))
+	})
+}
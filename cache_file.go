@@ -0,0 +1,82 @@
+//go:build !(js && wasm)
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// newEventCache returns the cache of the native binary: one file per
+// channel in the user cache directory.
+func newEventCache() eventCache {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return fileCache{err: err}
+	}
+	return newFileCache(filepath.Join(dir, "cyber-witness"))
+}
+
+// fileCache keeps the snapshot of every channel in a JSON file of dir.
+type fileCache struct {
+	dir string
+	// err is returned by every call when no cache directory is available
+	err error
+}
+
+func newFileCache(dir string) fileCache {
+	return fileCache{dir: dir}
+}
+
+func (f fileCache) path(c channel) string {
+	return filepath.Join(f.dir, c.dbName()+".json")
+}
+
+func (f fileCache) load(c channel) (cachedEvents, error) {
+	var s cachedEvents
+	if f.err != nil {
+		return s, f.err
+	}
+
+	b, err := os.ReadFile(f.path(c))
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	return s, json.Unmarshal(b, &s)
+}
+
+// save writes the snapshot to a temporary file first so an interrupted
+// write never leaves a truncated cache behind.
+func (f fileCache) save(c channel, s cachedEvents) error {
+	if f.err != nil {
+		return f.err
+	}
+
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(f.dir, 0o700)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(f.dir, c.dbName()+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(b)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path(c))
+}
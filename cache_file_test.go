@@ -0,0 +1,67 @@
+//go:build !(js && wasm)
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFileCache(t *testing.T) {
+	cache := newFileCache(t.TempDir())
+	c := channel{Region: "u33"}
+
+	s, err := cache.load(c)
+	if err != nil || len(s.Events) != 0 {
+		t.Fatalf("empty cache returned %+v, %v", s, err)
+	}
+
+	want := cachedEvents{
+		Events:   []Event{{ID: "1", Title: "Flood", Channel: "u33", Details: []Detail{{Text: "water", Author: "10"}}}},
+		SyncedAt: 42,
+	}
+	err = cache.save(c, want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cache.save(channel{}, cachedEvents{Events: []Event{{ID: "2"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := cache.load(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded %+v, want %+v", got, want)
+	}
+}
+
+func TestCacheEventsByChannel(t *testing.T) {
+	cache := newFileCache(t.TempDir())
+	channels := []channel{{}, {Region: "u33"}}
+	events := []Event{
+		{ID: "1", CreatedAt: 2},
+		{ID: "2", CreatedAt: 1, Channel: "u33"},
+		{ID: "3", CreatedAt: 3, Channel: "u34"},
+	}
+
+	err := cacheEvents(cache, channels, events)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := cache.load(channel{Region: "u33"})
+	if err != nil || len(s.Events) != 1 || s.Events[0].ID != "2" {
+		t.Errorf("u33 snapshot = %+v, %v", s, err)
+	}
+
+	got, err := loadCachedEvents(cache, channels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].ID != "2" || got[1].ID != "1" {
+		t.Errorf("cached events = %+v, want 2 then 1", got)
+	}
+}
@@ -0,0 +1,128 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+// IndexedDB database holding the event cache. Snapshots are stored as JSON
+// strings keyed by the docs store name of their channel.
+const (
+	cacheDBName    = "cyber-witness"
+	cacheDBVersion = 1
+	cacheStoreName = "events"
+)
+
+var errNoIndexedDB = errors.New("IndexedDB is not available")
+
+// newEventCache returns the cache of the browser, kept in IndexedDB since
+// local storage is too small for the events of busy channels.
+func newEventCache() eventCache {
+	return &indexedDBCache{}
+}
+
+// indexedDBCache must only be used from goroutines that may block, such as
+// the ones of ctx.Async, since every call waits for IndexedDB callbacks.
+type indexedDBCache struct {
+	once sync.Once
+	db   app.Value
+	err  error
+}
+
+// open opens the database on first use.
+func (c *indexedDBCache) open() (app.Value, error) {
+	c.once.Do(func() {
+		idb := app.Window().Get("indexedDB")
+		if !idb.Truthy() {
+			c.err = errNoIndexedDB
+			return
+		}
+
+		req := idb.Call("open", cacheDBName, cacheDBVersion)
+		upgrade := app.FuncOf(func(this app.Value, args []app.Value) any {
+			req.Get("result").Call("createObjectStore", cacheStoreName)
+			return nil
+		})
+		defer upgrade.Release()
+		req.Set("onupgradeneeded", upgrade)
+
+		_, c.err = await(req, "onsuccess", "onerror")
+		if c.err == nil {
+			c.db = req.Get("result")
+		}
+	})
+	return c.db, c.err
+}
+
+func (c *indexedDBCache) load(ch channel) (cachedEvents, error) {
+	var s cachedEvents
+
+	db, err := c.open()
+	if err != nil {
+		return s, err
+	}
+
+	req := db.Call("transaction", cacheStoreName, "readonly").Call("objectStore", cacheStoreName).Call("get", ch.dbName())
+	_, err = await(req, "onsuccess", "onerror")
+	if err != nil {
+		return s, err
+	}
+
+	v := req.Get("result")
+	if v.Type() != app.TypeString {
+		return s, nil
+	}
+	return s, json.Unmarshal([]byte(v.String()), &s)
+}
+
+func (c *indexedDBCache) save(ch channel, s cachedEvents) error {
+	db, err := c.open()
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	tx := db.Call("transaction", cacheStoreName, "readwrite")
+	tx.Call("objectStore", cacheStoreName).Call("put", string(b), ch.dbName())
+	_, err = await(tx, "oncomplete", "onerror")
+	return err
+}
+
+// await blocks until target fires its success or its error event and
+// returns the event.
+func await(target app.Value, success, failure string) (app.Value, error) {
+	type result struct {
+		event app.Value
+		err   error
+	}
+	done := make(chan result, 1)
+
+	onSuccess := app.FuncOf(func(this app.Value, args []app.Value) any {
+		done <- result{event: args[0]}
+		return nil
+	})
+	defer onSuccess.Release()
+	onFailure := app.FuncOf(func(this app.Value, args []app.Value) any {
+		msg := "IndexedDB request failed"
+		if err := target.Get("error"); err.Truthy() {
+			msg = err.Get("message").String()
+		}
+		done <- result{err: errors.New(msg)}
+		return nil
+	})
+	defer onFailure.Release()
+
+	target.Set(success, onSuccess)
+	target.Set(failure, onFailure)
+	r := <-done
+	return r.event, r.err
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReconcileEvents(t *testing.T) {
+	cached := []Event{
+		{ID: "1", Revision: "a"},
+		{ID: "2", Revision: "b"},
+		{ID: "3", Revision: "c"},
+	}
+	stored := []Event{
+		{ID: "1", Revision: "a"},
+		{ID: "2", Revision: "b2"},
+		{ID: "4", Revision: "d"},
+	}
+
+	changed, gone := reconcileEvents(cached, stored)
+	var ids []string
+	for _, e := range changed {
+		ids = append(ids, e.ID)
+	}
+	if !reflect.DeepEqual(ids, []string{"2", "4"}) {
+		t.Errorf("changed = %v, want [2 4]", ids)
+	}
+	if !reflect.DeepEqual(gone, []string{"3"}) {
+		t.Errorf("gone = %v, want [3]", gone)
+	}
+}
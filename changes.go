@@ -0,0 +1,138 @@
+package main
+
+import (
+	"log"
+	"sync"
+)
+
+// Kinds of changes to events a changeWatcher reports.
+const (
+	changeCreated   = "event.created"
+	changeConfirmed = "event.confirmed"
+	changeDisputed  = "event.disputed"
+	changeNews      = "event.news"
+)
+
+var changeKinds = []string{changeCreated, changeConfirmed, changeDisputed, changeNews}
+
+// eventChanges returns the kinds of changes from old to new. created tells
+// whether new was announced as a new event.
+func eventChanges(old, new Event, created bool) []string {
+	var kinds []string
+	if created {
+		kinds = append(kinds, changeCreated)
+	}
+	if len(missing(new.Witnesses, old.Witnesses)) > 0 {
+		kinds = append(kinds, changeConfirmed)
+	}
+	if len(missing(new.Disputes, old.Disputes)) > 0 {
+		kinds = append(kinds, changeDisputed)
+	}
+	if new.state() == stateNews && old.state() != stateNews {
+		kinds = append(kinds, changeNews)
+	}
+	return kinds
+}
+
+// changeWatcher follows the events of some channels like a reader would, on
+// behalf of the native integrations, and reports the changes it sees.
+type changeWatcher struct {
+	onChange func(kind string, e Event)
+	// onUpdate, if set, is called with every event that is new or changed
+	// in any way, not only with the kinds of changes above
+	onUpdate func(e Event)
+
+	mu sync.Mutex
+	// witnesses hold the known events of every channel, so updates are
+	// checked and merged the same way the app does
+	witnesses map[channel]*witness
+}
+
+// newChangeWatcher returns a watcher of channels calling onChange with
+// every change and the event as it is after the change.
+func newChangeWatcher(channels []channel, onChange func(kind string, e Event)) *changeWatcher {
+	cw := &changeWatcher{onChange: onChange, witnesses: make(map[channel]*witness)}
+	for _, c := range channels {
+		cw.witnesses[c] = &witness{channel: c, history: make(map[string][]revision), noNews: true}
+	}
+	return cw
+}
+
+// seed loads the events known before watching, which are not reported.
+func (cw *changeWatcher) seed(events []Event) {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+
+	for _, e := range events {
+		if w, ok := cw.witnesses[parseChannel(e.Channel)]; ok && e.Type == eventType {
+			w.receiveUpdate(e)
+		}
+	}
+}
+
+// receive applies an event announced on the create topic, if created, or
+// the update topic of its channel and reports what changed.
+func (cw *changeWatcher) receive(e Event, created bool) {
+	cw.mu.Lock()
+	w, ok := cw.witnesses[parseChannel(e.Channel)]
+	if !ok {
+		cw.mu.Unlock()
+		return
+	}
+
+	var old Event
+	n := w.eventIndex(e.ID)
+	if n >= 0 {
+		old = w.events[n]
+	}
+	if created {
+		w.receiveEvent(e)
+	} else {
+		w.receiveUpdate(e)
+	}
+	updated, found := Event{}, false
+	if n := w.eventIndex(e.ID); n >= 0 {
+		updated, found = w.events[n], true
+	}
+	cw.mu.Unlock()
+
+	if !found {
+		return
+	}
+	for _, kind := range eventChanges(old, updated, created && n < 0) {
+		cw.onChange(kind, updated)
+	}
+	if cw.onUpdate != nil && (n < 0 || eventDigest(old) != eventDigest(updated)) {
+		cw.onUpdate(updated)
+	}
+}
+
+// event returns the current version of the event with the given ID.
+func (cw *changeWatcher) event(id string) (Event, bool) {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+
+	for _, w := range cw.witnesses {
+		if n := w.eventIndex(id); n >= 0 {
+			return w.events[n], true
+		}
+	}
+	return Event{}, false
+}
+
+// subscribe watches the create and update topics of every channel.
+func (cw *changeWatcher) subscribe(subs *subscriber) {
+	for c := range cw.witnesses {
+		for _, topic := range []string{topicCreateEvent, topicUpdateEvent} {
+			created := topic == topicCreateEvent
+			subs.subscribe(c.topic(topic), func(data []byte) {
+				e, err := decodeMessage(data)
+				if err != nil {
+					log.Println("Dropped event message: " + err.Error())
+					return
+				}
+				cw.receive(e, created)
+			})
+		}
+	}
+}
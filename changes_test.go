@@ -0,0 +1,32 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEventChanges(t *testing.T) {
+	rumor := Event{ID: "1", Type: eventType, ConfirmedBy: 1, Witnesses: []string{"11"}}
+	news := rumor
+	news.Witnesses = []string{"11", "12"}
+	news.ConfirmedBy = 2
+	disputed := news
+	disputed.Disputes = []string{"20"}
+
+	tests := []struct {
+		name     string
+		old, new Event
+		created  bool
+		want     []string
+	}{
+		{"created", Event{}, Event{ID: "1", Type: eventType}, true, []string{changeCreated}},
+		{"unchanged", rumor, rumor, false, nil},
+		{"promoted", rumor, news, false, []string{changeConfirmed, changeNews}},
+		{"disputed", news, disputed, false, []string{changeDisputed}},
+	}
+	for _, tt := range tests {
+		if got := eventChanges(tt.old, tt.new, tt.created); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: changes = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"log"
+	"strings"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+// Local storage keys of the followed channels and the one being shown.
+const (
+	channelsStorageKey      = "channels"
+	activeChannelStorageKey = "active-channel"
+)
+
+// regionPrecision is the geohash length used when the region is taken from
+// the browser location. Three characters cover roughly 150 km.
+const regionPrecision = 3
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// channel is the part of the event stream a citizen follows: the events of a
+// region, given as a geohash prefix, optionally narrowed to a topic. Every
+// channel has its own docs store and pubsub topics so peers only download
+// what they follow. The zero channel is the global one that predates
+// channels and keeps the original store and topic names.
+type channel struct {
+	Region string `json:"region"`
+	Topic  string `json:"topic"`
+}
+
+// newChannel normalises a region and topic typed by a citizen. It reports
+// false if the region is not a geohash.
+func newChannel(region, topic string) (channel, bool) {
+	c := channel{
+		Region: strings.ToLower(strings.TrimSpace(region)),
+		Topic:  strings.Join(strings.Fields(strings.ToLower(topic)), "-"),
+	}
+	for _, r := range c.Region {
+		if !strings.ContainsRune(geohashAlphabet, r) {
+			return c, false
+		}
+	}
+	return c, true
+}
+
+// parseChannel is the inverse of channel.id.
+func parseChannel(id string) channel {
+	region, topic, _ := strings.Cut(id, "-")
+	return channel{Region: region, Topic: topic}
+}
+
+// id identifies the channel in events, store names and topics. The region
+// comes first since geohashes never contain a dash.
+func (c channel) id() string {
+	if c.Topic == "" {
+		return c.Region
+	}
+	return c.Region + "-" + c.Topic
+}
+
+// name is the name of the channel in logs and on the command line.
+func (c channel) name() string {
+	return c.label(defaultLanguage)
+}
+
+// label is the name of the channel shown in language lang.
+func (c channel) label(lang string) string {
+	switch {
+	case c.Region == "" && c.Topic == "":
+		return translate(lang, "channel.global")
+	case c.Topic == "":
+		return c.Region
+	case c.Region == "":
+		return translate(lang, "channel.global") + " · " + c.Topic
+	default:
+		return c.Region + " · " + c.Topic
+	}
+}
+
+// dbName returns the docs store holding the events of the channel.
+func (c channel) dbName() string {
+	if c.id() == "" {
+		return dbNameEvent
+	}
+	return dbNameEvent + "-" + c.id()
+}
+
+// topic returns the channel's variant of one of the event topics.
+func (c channel) topic(base string) string {
+	if c.id() == "" {
+		return base
+	}
+	return base + "-" + c.id()
+}
+
+// geohash encodes a position with the given number of characters.
+func geohash(lat, lon float64, precision int) string {
+	minLat, maxLat := -90.0, 90.0
+	minLon, maxLon := -180.0, 180.0
+
+	var b strings.Builder
+	bits, ch, even := 0, 0, true
+	for b.Len() < precision {
+		if even {
+			mid := (minLon + maxLon) / 2
+			ch <<= 1
+			if lon >= mid {
+				ch |= 1
+				minLon = mid
+			} else {
+				maxLon = mid
+			}
+		} else {
+			mid := (minLat + maxLat) / 2
+			ch <<= 1
+			if lat >= mid {
+				ch |= 1
+				minLat = mid
+			} else {
+				maxLat = mid
+			}
+		}
+		even = !even
+
+		bits++
+		if bits == 5 {
+			b.WriteByte(geohashAlphabet[ch])
+			bits, ch = 0, 0
+		}
+	}
+	return b.String()
+}
+
+func (w *witness) loadChannels(ctx app.Context) {
+	err := ctx.LocalStorage().Get(channelsStorageKey, &w.channels)
+	if err != nil {
+		log.Println(err)
+	}
+	err = ctx.LocalStorage().Get(activeChannelStorageKey, &w.channel)
+	if err != nil {
+		log.Println(err)
+	}
+	w.savedChannel = w.channel
+}
+
+func (w *witness) saveChannels(ctx app.Context) {
+	err := ctx.LocalStorage().Set(channelsStorageKey, w.channels)
+	if err != nil {
+		w.createNotification(ctx, NotificationDanger, w.t(ErrorHeader), w.t("channel.saveFailed"))
+		log.Println(err)
+	}
+}
+
+// following returns the channels offered by the switcher. The global
+// channel is always available.
+func (w *witness) following() []channel {
+	channels := []channel{{}}
+	for _, c := range w.channels {
+		if c != (channel{}) {
+			channels = append(channels, c)
+		}
+	}
+	return channels
+}
+
+// containsChannel reports whether c is one of channels.
+func containsChannel(channels []channel, c channel) bool {
+	for _, f := range channels {
+		if f == c {
+			return true
+		}
+	}
+	return false
+}
+
+// switchChannel views c and remembers it as the active channel.
+func (w *witness) switchChannel(ctx app.Context, c channel) {
+	w.viewChannel(ctx, c)
+	w.savedChannel = c
+	err := ctx.LocalStorage().Set(activeChannelStorageKey, c)
+	if err != nil {
+		w.createNotification(ctx, NotificationDanger, w.t(ErrorHeader), w.t("channel.saveFailed"))
+		log.Println(err)
+	}
+}
+
+// viewChannel drops the events of the current channel and loads the ones of
+// c without remembering it, so that following a link into another channel
+// doesn't change the one the citizen comes back to, see OnNav.
+func (w *witness) viewChannel(ctx app.Context, c channel) {
+	if c == w.channel {
+		return
+	}
+
+	w.unsubscribeChannel(w.channel)
+	w.channel = c
+	w.events = nil
+	w.archive = nil
+	w.retired = nil
+	w.syncStats = syncStats{}
+	w.digestAt = 0
+	w.resetHistory()
+	w.noNews = true
+	w.isWitness = false
+	w.subscribeChannel(ctx, c)
+	w.loadEvents(ctx)
+}
+
+func (w *witness) renderChannelSwitcher() app.UI {
+	channels := w.following()
+	// a linked event may be shown from a channel the citizen doesn't follow
+	if !containsChannel(channels, w.channel) {
+		channels = append(channels, w.channel)
+	}
+
+	return app.Div().Class("p-form p-form--inline").Body(
+		app.Div().Class("p-form__group").Body(
+			app.Label().For("channel").Text(w.t("channel.label")),
+			app.Select().ID("channel").OnChange(w.onSwitchChannel).Body(
+				app.Range(channels).Slice(func(n int) app.UI {
+					return app.Option().Value(channels[n].id()).Text(channels[n].label(w.language)).Selected(channels[n] == w.channel)
+				}),
+			),
+		),
+		app.Button().Text(w.t("channel.manage")).OnClick(w.openChannelsDialog),
+	)
+}
+
+func (w *witness) renderChannelsModal() app.UI {
+	return app.Div().Class("p-modal").ID("channels-modal").Style("display", "none").Body(
+		app.Section().Class("p-modal__dialog").Role("dialog").Aria("modal", true).Aria("labelledby", "modal-title").Aria("describedby", "modal-description").Body(
+			app.Header().Class("p-modal__header").Body(
+				app.H2().Class("p-modal__title").ID("modal-title").Text(w.t("channel.manage")),
+				app.Button().Class("p-modal__close").Aria("label", w.t("modal.close")).Aria("controls", "modal").OnClick(w.closeChannelsModal),
+			),
+			app.P().ID("modal-description").Text(w.t("channel.description")),
+			app.Div().Class("p-form p-form--inline").Body(
+				app.Div().Class("p-form__group").Body(
+					app.Input().Name("channel-region").Placeholder(w.t("channel.regionHint")).Value(w.channelRegion).OnKeyUp(w.onChannelRegion),
+				),
+				app.Button().Class("is-dense").Text(w.t("channel.locate")).OnClick(w.onLocateRegion),
+				app.Div().Class("p-form__group").Body(
+					app.Input().Name("channel-topic").Placeholder(w.t("channel.topicHint")).Value(w.channelTopic).OnKeyUp(w.onChannelTopic),
+				),
+				app.Button().Class("p-button--positive").Text(w.t("channel.follow")).OnClick(w.onFollowChannel),
+			),
+			app.Range(w.channels).Slice(func(n int) app.UI {
+				return app.Span().Class("p-chip").Body(
+					app.Span().Class("p-chip__value").Text(w.channels[n].label(w.language)),
+					app.Button().Class("p-chip__dismiss").Value(w.channels[n].id()).Text(w.t("channel.unfollow")).OnClick(w.onUnfollowChannel),
+				)
+			}),
+		),
+	)
+}
+
+func (w *witness) openChannelsDialog(ctx app.Context, e app.Event) {
+	app.Window().GetElementByID("channels-modal").Set("style", "display:flex")
+}
+
+func (w *witness) closeChannelsModal(ctx app.Context, e app.Event) {
+	app.Window().GetElementByID("channels-modal").Set("style", "display:none")
+}
+
+func (w *witness) onSwitchChannel(ctx app.Context, e app.Event) {
+	w.switchChannel(ctx, parseChannel(ctx.JSSrc().Get("value").String()))
+}
+
+func (w *witness) onChannelRegion(ctx app.Context, e app.Event) {
+	w.channelRegion = ctx.JSSrc().Get("value").String()
+}
+
+func (w *witness) onChannelTopic(ctx app.Context, e app.Event) {
+	w.channelTopic = ctx.JSSrc().Get("value").String()
+}
+
+func (w *witness) onLocateRegion(ctx app.Context, e app.Event) {
+	geolocation := app.Window().Get("navigator").Get("geolocation")
+	if !geolocation.Truthy() {
+		w.createNotification(ctx, NotificationWarning, w.t("event.location"), w.t("channel.noLocation"))
+		return
+	}
+
+	var success, failure app.Func
+	release := func() {
+		success.Release()
+		failure.Release()
+	}
+	success = app.FuncOf(func(this app.Value, args []app.Value) any {
+		coords := args[0].Get("coords")
+		region := geohash(coords.Get("latitude").Float(), coords.Get("longitude").Float(), regionPrecision)
+		ctx.Dispatch(func(ctx app.Context) {
+			w.channelRegion = region
+		})
+		release()
+		return nil
+	})
+	failure = app.FuncOf(func(this app.Value, args []app.Value) any {
+		ctx.Dispatch(func(ctx app.Context) {
+			w.createNotification(ctx, NotificationWarning, w.t("event.location"), w.t("channel.locateFailed"))
+		})
+		release()
+		return nil
+	})
+	geolocation.Call("getCurrentPosition", success, failure)
+}
+
+func (w *witness) onFollowChannel(ctx app.Context, e app.Event) {
+	c, ok := newChannel(w.channelRegion, w.channelTopic)
+	if !ok {
+		w.createNotification(ctx, NotificationDanger, w.t(ErrorHeader), w.t("channel.badRegion"))
+		return
+	}
+
+	if containsChannel(w.following(), c) {
+		w.switchChannel(ctx, c)
+		return
+	}
+
+	w.channels = append(w.channels, c)
+	w.channelRegion = ""
+	w.channelTopic = ""
+	w.saveChannels(ctx)
+	w.switchChannel(ctx, c)
+}
+
+func (w *witness) onUnfollowChannel(ctx app.Context, e app.Event) {
+	c := parseChannel(ctx.JSSrc().Get("value").String())
+	for n, f := range w.channels {
+		if f == c {
+			w.channels = append(w.channels[:n], w.channels[n+1:]...)
+			break
+		}
+	}
+
+	w.saveChannels(ctx)
+	if c == w.channel {
+		w.switchChannel(ctx, channel{})
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import "testing"
+
+func TestGeohash(t *testing.T) {
+	tests := []struct {
+		lat, lon float64
+		want     string
+	}{
+		{52.5200, 13.4050, "u33dc0"},
+		{57.64911, 10.40744, "u4pruy"},
+		{-33.8688, 151.2093, "r3gx2f"},
+	}
+	for _, tt := range tests {
+		if got := geohash(tt.lat, tt.lon, 6); got != tt.want {
+			t.Errorf("geohash(%v, %v) = %s, want %s", tt.lat, tt.lon, got, tt.want)
+		}
+	}
+}
+
+func TestChannelNames(t *testing.T) {
+	tests := []struct {
+		region, topic string
+		id, db, topc  string
+		ok            bool
+	}{
+		{"", "", "", "event", "create-event", true},
+		{" U33 ", "", "u33", "event-u33", "create-event-u33", true},
+		{"u33", "Road Works", "u33-road-works", "event-u33-road-works", "create-event-u33-road-works", true},
+		{"", "traffic", "-traffic", "event--traffic", "create-event--traffic", true},
+		{"berlin", "", "", "", "", false},
+	}
+	for _, tt := range tests {
+		c, ok := newChannel(tt.region, tt.topic)
+		if ok != tt.ok {
+			t.Errorf("newChannel(%q, %q) ok = %v", tt.region, tt.topic, ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if c.id() != tt.id || c.dbName() != tt.db || c.topic(topicCreateEvent) != tt.topc {
+			t.Errorf("newChannel(%q, %q) = %q, %q, %q", tt.region, tt.topic, c.id(), c.dbName(), c.topic(topicCreateEvent))
+		}
+		if parseChannel(c.id()) != c {
+			t.Errorf("parseChannel(%q) = %+v, want %+v", c.id(), parseChannel(c.id()), c)
+		}
+	}
+}
@@ -0,0 +1,616 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	shell "github.com/stateless-minds/go-ipfs-api"
+)
+
+// defaultAPI is the address of the local IPFS node's HTTP API.
+const defaultAPI = "localhost:5001"
+
+// runCommand runs the command line interface of the native binary. It is
+// used instead of serving the app whenever arguments are given.
+func runCommand(args []string) error {
+	switch args[0] {
+	case "export":
+		return runExport(args[1:])
+	case "import":
+		return runImport(args[1:])
+	case "audit":
+		return runAudit(args[1:])
+	case "publish-digest":
+		return runPublishDigest(args[1:])
+	case "daily":
+		return runDaily(args[1:])
+	case "webhooks":
+		return runWebhooks(args[1:])
+	case "nostr-bridge":
+		return runNostrBridge(args[1:])
+	case "import-reports":
+		return runImportReports(args[1:])
+	case "export-feed":
+		return runExportFeed(args[1:])
+	default:
+		return fmt.Errorf("unknown command %q, expected export, export-feed, import, import-reports, audit, publish-digest, daily, webhooks or nostr-bridge", args[0])
+	}
+}
+
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	api := fs.String("api", defaultAPI, "IPFS HTTP API address")
+	format := fs.String("format", "", "backup format, jsonl or car (default guessed from -o)")
+	output := fs.String("o", "", "output file (default stdout)")
+	offline := fs.Bool("offline", false, "export the events cached by the last export instead of querying the node, jsonl only")
+	channels := channelsFlag(fs, "export")
+	fs.Parse(args)
+
+	if *format == "" {
+		*format = backupFormat(*output)
+	}
+	if *offline && *format != formatJSONL {
+		return fmt.Errorf("offline export only supports jsonl, CAR blocks come from the node")
+	}
+
+	sh := shell.NewShell(*api)
+	cache := newEventCache()
+	var events []Event
+	var err error
+	if *offline {
+		events, err = loadCachedEvents(cache, *channels)
+	} else {
+		events, err = loadAllEvents(sh, *channels)
+	}
+	if err != nil {
+		return err
+	}
+	if !*offline {
+		// a stale cache only affects later offline exports
+		if err := cacheEvents(cache, *channels, events); err != nil {
+			fmt.Fprintln(os.Stderr, "could not cache events:", err)
+		}
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	return writeEvents(sh, out, *format, events)
+}
+
+// channelsFlag defines the repeatable -channel flag of a command. The global
+// channel is used when the flag isn't given.
+func channelsFlag(fs *flag.FlagSet, verb string) *[]channel {
+	channels := []channel{{}}
+	given := false
+	fs.Func("channel", "channel to "+verb+" as region or region-topic, may be repeated (default the global channel)", func(id string) error {
+		c := parseChannel(id)
+		if _, ok := newChannel(c.Region, c.Topic); !ok {
+			return fmt.Errorf("invalid region %q", c.Region)
+		}
+		if !given {
+			channels, given = nil, true
+		}
+		channels = append(channels, c)
+		return nil
+	})
+	return &channels
+}
+
+// loadCachedEvents returns the cached events of the given channels.
+func loadCachedEvents(cache eventCache, channels []channel) ([]Event, error) {
+	var events []Event
+	for _, c := range channels {
+		s, err := cache.load(c)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, s.Events...)
+	}
+	sortEvents(events)
+	return events, nil
+}
+
+// cacheEvents replaces the cached snapshot of every channel with its
+// events.
+func cacheEvents(cache eventCache, channels []channel, events []Event) error {
+	now := time.Now().Unix()
+	for _, c := range channels {
+		s := cachedEvents{SyncedAt: now}
+		for _, e := range events {
+			if parseChannel(e.Channel) == c {
+				s.Events = append(s.Events, e)
+			}
+		}
+
+		err := cache.save(c, s)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	api := fs.String("api", defaultAPI, "IPFS HTTP API address")
+	format := fs.String("format", "", "backup format, jsonl or car (default guessed from the file name)")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: cyber-witness import [-api address] [-format jsonl|car] file")
+	}
+	if *format == "" {
+		*format = backupFormat(fs.Arg(0))
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sh := shell.NewShell(*api)
+	citizenID, err := localCitizenID(sh)
+	if err != nil {
+		return err
+	}
+	key, err := loadSigningKeyFile()
+	if err != nil {
+		return err
+	}
+
+	events, err := readBackup(sh, f, *format)
+	if err != nil {
+		return err
+	}
+
+	report, err := importEvents(sh, key, citizenID, defaultLifecycle, events)
+	for _, r := range report.Rejected {
+		fmt.Println("rejected", r)
+	}
+	for _, c := range report.Conflicts {
+		fmt.Println("conflict", c)
+	}
+	fmt.Println(report)
+	return err
+}
+
+// localCitizenID returns the citizen ID of the node behind sh.
+func localCitizenID(sh *shell.Shell) (string, error) {
+	myPeer, err := sh.ID()
+	if err != nil {
+		return "", err
+	}
+	return citizenIDFromPeer(myPeer.ID), nil
+}
+
+func runAudit(args []string) error {
+	fs := flag.NewFlagSet("audit", flag.ExitOnError)
+	api := fs.String("api", defaultAPI, "IPFS HTTP API address")
+	offline := fs.Bool("offline", false, "read the events cached by the last export instead of querying the node")
+	file := fs.String("f", "", "verify an exported audit log instead of loading events")
+	output := fs.String("o", "", "export the audit log of the event to this file")
+	channels := channelsFlag(fs, "look events up in")
+	fs.Parse(args)
+
+	if *file != "" {
+		b, err := os.ReadFile(*file)
+		if err != nil {
+			return err
+		}
+		var a auditExport
+		err = json.Unmarshal(b, &a)
+		if err != nil {
+			return err
+		}
+		return printAudit(os.Stdout, a)
+	}
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: cyber-witness audit [-api address] [-offline] [-channel id] [-o file] event-id, or cyber-witness audit -f file")
+	}
+
+	var events []Event
+	var err error
+	if *offline {
+		events, err = loadCachedEvents(newEventCache(), *channels)
+	} else {
+		events, err = loadAllEvents(shell.NewShell(*api), *channels)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, e := range events {
+		if e.ID != fs.Arg(0) {
+			continue
+		}
+		if *output != "" {
+			b, err := exportAudit(e)
+			if err != nil {
+				return err
+			}
+			err = os.WriteFile(*output, b, 0o644)
+			if err != nil {
+				return err
+			}
+		}
+		return printAudit(os.Stdout, auditExport{Event: e.ID, Title: e.Title, ReporterKey: e.ReporterKey, Entries: e.auditLog()})
+	}
+	return fmt.Errorf("event %s not found", fs.Arg(0))
+}
+
+// printAudit writes the replayed audit log of an event and fails if any of
+// its entries doesn't verify.
+func printAudit(out io.Writer, a auditExport) error {
+	fmt.Fprintf(out, "%s %q\n", a.Event, a.Title)
+
+	invalid := 0
+	for _, s := range replayAudit(a.Event, a.Entries) {
+		status := "ok"
+		if s.Err != nil {
+			status = "INVALID"
+			invalid++
+		}
+		fmt.Fprintf(out, "%s\t%s\t%s\t%s\t%s\t%q\n", time.Unix(s.Entry.At, 0).UTC().Format(time.RFC3339), status, s.State, s.Entry.Op, s.Entry.Actor, s.Entry.Ref)
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d audit entries failed verification", invalid, len(a.Entries))
+	}
+	return nil
+}
+
+func runPublishDigest(args []string) error {
+	fs := flag.NewFlagSet("publish-digest", flag.ExitOnError)
+	api := fs.String("api", defaultAPI, "IPFS HTTP API address")
+	keyName := fs.String("key", defaultDigestKey, "name of the IPNS key to publish under, created if missing")
+	interval := fs.Duration("interval", 0, "publish a new digest at this interval instead of once")
+	channels := channelsFlag(fs, "publish the news of")
+	fs.Parse(args)
+
+	sh := shell.NewShell(*api)
+	key, err := loadSigningKeyFile()
+	if err != nil {
+		return err
+	}
+
+	for {
+		res, d, err := publishDigest(sh, key, *keyName, *channels, *interval, time.Now())
+		switch {
+		case err != nil && *interval == 0:
+			return err
+		case err != nil:
+			// a publisher keeps running through network hiccups
+			fmt.Fprintln(os.Stderr, "could not publish digest:", err)
+		default:
+			fmt.Printf("published %d news as /ipns/%s -> %s\n", len(d.Events), res.Name, res.Value)
+		}
+
+		if *interval == 0 {
+			return nil
+		}
+		time.Sleep(*interval)
+	}
+}
+
+func runDaily(args []string) error {
+	fs := flag.NewFlagSet("daily", flag.ExitOnError)
+	api := fs.String("api", defaultAPI, "IPFS HTTP API address")
+	offline := fs.Bool("offline", false, "read the events cached by the last export instead of querying the node")
+	format := fs.String("format", "", "digest format, html or md (default guessed from -o, html for stdout)")
+	output := fs.String("o", "", "output file (default stdout)")
+	since := fs.Duration("since", 24*time.Hour, "period the digest covers, up to now")
+	rumors := fs.Int("rumors", defaultTopRumors, "how many of the most confirmed rumors to list")
+	mutes := fs.String("mute", "", "mute list exported from the app to apply")
+	language := fs.String("language", "", "only list events written in this language")
+	tag := fs.String("tag", "", "only list events with this tag")
+	ui := fs.String("ui", defaultLanguage, "language of the headings")
+	channels := channelsFlag(fs, "summarize")
+	fs.Parse(args)
+
+	if *format == "" {
+		*format = dailyFormat(*output)
+	}
+
+	w := &witness{language: *ui, languageFilter: *language, tagFilter: normalizeTag(*tag)}
+	if *mutes != "" {
+		b, err := os.ReadFile(*mutes)
+		if err != nil {
+			return err
+		}
+		err = json.Unmarshal(b, &w.mutes)
+		if err != nil {
+			return fmt.Errorf("invalid mute list: %w", err)
+		}
+	}
+
+	var events []Event
+	var err error
+	if *offline {
+		events, err = loadCachedEvents(newEventCache(), *channels)
+	} else {
+		events, err = loadAllEvents(shell.NewShell(*api), *channels)
+	}
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	d := w.dailyDigest(events, *channels, now.Add(-*since), now, *rumors)
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	return writeDaily(out, *format, d)
+}
+
+func runWebhooks(args []string) error {
+	fs := flag.NewFlagSet("webhooks", flag.ExitOnError)
+	api := fs.String("api", defaultAPI, "IPFS HTTP API address")
+	var urls []string
+	fs.Func("url", "webhook URL to POST to, may be repeated", func(u string) error {
+		urls = append(urls, u)
+		return nil
+	})
+	on := fs.String("on", "created,confirmed,disputed,news", "comma separated changes to call the webhooks about")
+	logFile := fs.String("log", "", "file to append the delivery log to (default stdout)")
+	attempts := fs.Int("attempts", defaultHookAttempts, "how many times a delivery is tried")
+	channels := channelsFlag(fs, "watch")
+	fs.Parse(args)
+
+	if len(urls) == 0 {
+		return fmt.Errorf("usage: cyber-witness webhooks [-api address] [-channel id]... [-on kinds] [-log file] -url url...")
+	}
+	secret := os.Getenv(envWebhookSecret)
+	if secret == "" {
+		return fmt.Errorf("%s must hold the secret webhook payloads are signed with", envWebhookSecret)
+	}
+	kinds, err := parseHookKinds(*on)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if *logFile != "" {
+		f, err := os.OpenFile(*logFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	sh := shell.NewShell(*api)
+	events, err := loadAllEvents(sh, *channels)
+	if err != nil {
+		return err
+	}
+
+	h := newWebhooks(urls, []byte(secret), kinds, *channels, out)
+	h.attempts = *attempts
+	h.watcher.seed(events)
+
+	subs := newSubscriber(sh, func(topic string, s subState) {
+		fmt.Fprintln(os.Stderr, topic, s)
+	})
+	h.watcher.subscribe(subs)
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+	subs.close()
+	h.wait()
+	return nil
+}
+
+func runNostrBridge(args []string) error {
+	fs := flag.NewFlagSet("nostr-bridge", flag.ExitOnError)
+	api := fs.String("api", defaultAPI, "IPFS HTTP API address")
+	relay := fs.String("relay", "", "ws:// or wss:// URL of the Nostr relay")
+	reactors := make(map[string]bool)
+	fs.Func("reactor", "Nostr public key whose \"+\" reactions to the mirrored events are imported as confirmations, may be repeated", func(pubkey string) error {
+		if b, err := hex.DecodeString(pubkey); err != nil || len(b) != 32 {
+			return fmt.Errorf("invalid Nostr public key %q", pubkey)
+		}
+		reactors[strings.ToLower(pubkey)] = true
+		return nil
+	})
+	since := fs.Duration("since", defaultNostrSince, "mirror events of this age when starting")
+	channels := channelsFlag(fs, "bridge")
+	fs.Parse(args)
+
+	if *relay == "" {
+		return fmt.Errorf("usage: cyber-witness nostr-bridge [-api address] [-channel id]... [-reactor pubkey]... [-since duration] -relay url")
+	}
+	key, err := loadNostrKey()
+	if err != nil {
+		return err
+	}
+	signingKey, err := loadSigningKeyFile()
+	if err != nil {
+		return err
+	}
+
+	sh := shell.NewShell(*api)
+	events, err := loadAllEvents(sh, *channels)
+	if err != nil {
+		return err
+	}
+
+	b := newNostrBridge(sh, *relay, key, signingKey, *channels)
+	b.reactors = reactors
+	b.watcher.seed(events)
+	start := time.Now().Add(-*since)
+	for _, e := range events {
+		if e.Type == eventType && max(e.CreatedAt, e.NewsAt) >= start.Unix() {
+			b.onUpdate(e)
+		}
+	}
+	fmt.Fprintln(os.Stderr, "bridging as Nostr public key", key.publicKey())
+
+	subs := newSubscriber(sh, func(topic string, s subState) {
+		fmt.Fprintln(os.Stderr, topic, s)
+	})
+	b.watcher.subscribe(subs)
+	b.start(start)
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+	subs.close()
+	b.close()
+	return nil
+}
+
+func runImportReports(args []string) error {
+	fs := flag.NewFlagSet("import-reports", flag.ExitOnError)
+	api := fs.String("api", defaultAPI, "IPFS HTTP API address")
+	format := fs.String("format", "", "file format, csv or geojson (default guessed from the file name)")
+	channelID := fs.String("channel", "", "channel to report to as region or region-topic (default the global channel)")
+	rate := fs.Duration("rate", defaultReportRate, "delay between two published reports")
+	preview := fs.Bool("preview", false, "only show what would be published and which reports are duplicates")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: cyber-witness import-reports [-api address] [-channel id] [-format csv|geojson] [-rate duration] [-preview] file")
+	}
+	c := parseChannel(*channelID)
+	if _, ok := newChannel(c.Region, c.Topic); !ok {
+		return fmt.Errorf("invalid region %q", c.Region)
+	}
+	if *format == "" {
+		*format = reportFormat(fs.Arg(0))
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	drafts, err := readReports(f, *format)
+	if err != nil {
+		return err
+	}
+
+	sh := shell.NewShell(*api)
+	events, err := loadAllEvents(sh, []channel{c})
+	if err != nil {
+		return err
+	}
+	plan := planReports(drafts, events, time.Now())
+
+	ready := 0
+	for _, p := range plan {
+		if p.Err == nil && p.Duplicate == "" {
+			ready++
+			if *preview {
+				fmt.Println("new", p)
+			}
+			continue
+		}
+		fmt.Println("skip", p)
+	}
+	if *preview {
+		fmt.Printf("%d of %d reports would be published\n", ready, len(plan))
+		return nil
+	}
+
+	citizenID, err := localCitizenID(sh)
+	if err != nil {
+		return err
+	}
+	key, err := loadSigningKeyFile()
+	if err != nil {
+		return err
+	}
+
+	w := newImportWitness(sh, key, citizenID, c, events)
+	n, err := publishReports(w, plan, *rate, func(p plannedReport, e Event) {
+		fmt.Println("published", e.ID, p)
+	})
+	fmt.Printf("%d of %d reports published\n", n, len(plan))
+	return err
+}
+
+func runExportFeed(args []string) error {
+	fs := flag.NewFlagSet("export-feed", flag.ExitOnError)
+	api := fs.String("api", defaultAPI, "IPFS HTTP API address")
+	offline := fs.Bool("offline", false, "read the events cached by the last export instead of querying the node")
+	format := fs.String("format", "", "export format, geojson, kml or csv (default guessed from -o, geojson for stdout)")
+	output := fs.String("o", "", "output file (default stdout)")
+	news := fs.Bool("news", false, "only export the news")
+	mutes := fs.String("mute", "", "mute list exported from the app to apply")
+	language := fs.String("language", "", "only export events written in this language")
+	tag := fs.String("tag", "", "only export events with this tag")
+	state := fs.String("state", "", "only export events in this state")
+	channels := channelsFlag(fs, "export")
+	fs.Parse(args)
+
+	if *format == "" {
+		*format = feedFormat(*output)
+	}
+	if _, ok := feedMIMETypes[*format]; !ok {
+		return fmt.Errorf("unknown export format %q, expected geojson, kml or csv", *format)
+	}
+
+	w := &witness{languageFilter: *language, tagFilter: normalizeTag(*tag), stateFilter: eventState(*state)}
+	if *mutes != "" {
+		b, err := os.ReadFile(*mutes)
+		if err != nil {
+			return err
+		}
+		err = json.Unmarshal(b, &w.mutes)
+		if err != nil {
+			return fmt.Errorf("invalid mute list: %w", err)
+		}
+	}
+	v := viewRumors
+	if *news {
+		v = viewNews
+	}
+
+	var events []Event
+	var err error
+	if *offline {
+		events, err = loadCachedEvents(newEventCache(), *channels)
+	} else {
+		events, err = loadAllEvents(shell.NewShell(*api), *channels)
+	}
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	return writeFeed(out, *format, w.feedRecords(events, v))
+}
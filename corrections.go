@@ -0,0 +1,366 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+// Kinds of statements the reporter signs about their own report.
+const (
+	correctionStatement = "correction"
+	retractionStatement = "retraction"
+)
+
+var errBadCorrection = errors.New("correction is not signed by the reporter")
+
+// Correction is a change the reporter made to their own report. Corrections
+// never overwrite the report: they are kept next to it and applied when it
+// is shown, so the original text stays in the event and in its history.
+type Correction struct {
+	// Title, Location and Text replace the title, location and description
+	// of the report when set.
+	Title    string `mapstructure:"title" json:"title,omitempty" validate:"uuid_rfc4122"`
+	Location string `mapstructure:"location" json:"location,omitempty" validate:"uuid_rfc4122"`
+	Text     string `mapstructure:"text" json:"text,omitempty" validate:"uuid_rfc4122"`
+	Reason   string `mapstructure:"reason" json:"reason" validate:"uuid_rfc4122"`
+	At       int64  `mapstructure:"at" json:"at" validate:"uuid_rfc4122"`
+	// Signature is made with the reporter key over the rest of the
+	// correction, see signStatement.
+	Signature string `mapstructure:"signature" json:"signature" validate:"uuid_rfc4122"`
+}
+
+// Retraction records the reporter withdrawing their report.
+type Retraction struct {
+	Reason    string `mapstructure:"reason" json:"reason" validate:"uuid_rfc4122"`
+	At        int64  `mapstructure:"at" json:"at" validate:"uuid_rfc4122"`
+	Signature string `mapstructure:"signature" json:"signature" validate:"uuid_rfc4122"`
+}
+
+func signCorrection(key ed25519.PrivateKey, id string, c Correction) (Correction, error) {
+	c.Signature = ""
+	sig, err := signStatement(key, id, correctionStatement, c)
+	c.Signature = sig
+	return c, err
+}
+
+// verifyCorrection checks that c was signed by reporterKey for the event
+// with the given ID.
+func verifyCorrection(reporterKey, id string, c Correction) error {
+	sig := c.Signature
+	c.Signature = ""
+	return verifyStatement(reporterKey, sig, id, correctionStatement, c)
+}
+
+func signRetraction(key ed25519.PrivateKey, id string, r Retraction) (Retraction, error) {
+	r.Signature = ""
+	sig, err := signStatement(key, id, retractionStatement, r)
+	r.Signature = sig
+	return r, err
+}
+
+// verifyRetraction checks that r was signed by reporterKey for the event
+// with the given ID.
+func verifyRetraction(reporterKey, id string, r Retraction) error {
+	sig := r.Signature
+	r.Signature = ""
+	return verifyStatement(reporterKey, sig, id, retractionStatement, r)
+}
+
+// corrections returns the corrections of e in the order they were made.
+func (e Event) corrections() []Correction {
+	cs := append([]Correction(nil), e.Corrections...)
+	sort.SliceStable(cs, func(i, j int) bool {
+		if cs[i].At != cs[j].At {
+			return cs[i].At < cs[j].At
+		}
+		return cs[i].Signature < cs[j].Signature
+	})
+	return cs
+}
+
+// corrected returns e with the title and location of its latest corrections
+// applied.
+func (e Event) corrected() Event {
+	for _, c := range e.corrections() {
+		if c.Title != "" {
+			e.Title = c.Title
+		}
+		if c.Location != "" {
+			e.Location = c.Location
+		}
+	}
+	return e
+}
+
+// hasCorrection reports whether cs holds c, correction signatures being
+// unique.
+func hasCorrection(cs []Correction, c Correction) bool {
+	for _, v := range cs {
+		if v.Signature == c.Signature {
+			return true
+		}
+	}
+	return false
+}
+
+// unionCorrections returns a followed by the corrections of b it doesn't
+// hold.
+func unionCorrections(a, b []Correction) []Correction {
+	out := a
+	for _, c := range b {
+		if !hasCorrection(out, c) {
+			out = append(out[:len(out):len(out)], c)
+		}
+	}
+	return out
+}
+
+// validCorrections checks that the corrections incoming adds to local are
+// signed by the reporter. The key recorded on the loaded event is trusted
+// over the one the update carries.
+func validCorrections(local, incoming Event) error {
+	key := local.ReporterKey
+	if local.ID == "" {
+		key = incoming.ReporterKey
+	}
+
+	for _, c := range incoming.Corrections {
+		if hasCorrection(local.Corrections, c) {
+			continue
+		}
+		if verifyCorrection(key, incoming.ID, c) != nil {
+			return errBadCorrection
+		}
+	}
+	return nil
+}
+
+// amendable reports whether the reporter can still correct or retract e.
+func amendable(e Event) bool {
+	switch e.state() {
+	case stateRumor, stateNews, stateDisputed:
+		return true
+	}
+	return false
+}
+
+// isReporter reports whether the citizen holds the key e was reported with.
+// Reports published before reporter keys were recorded can't be amended.
+func (w *witness) isReporter(e Event) bool {
+	return e.ReporterKey != "" && w.key != nil && e.ReporterKey == signerID(w.key)
+}
+
+// correct returns the event with the given ID carrying c, signed by the
+// reporter. It returns false for citizens other than the reporter, for
+// events that can't be amended and for corrections that change nothing.
+func (w *witness) correct(id string, c Correction, now time.Time) (Event, bool) {
+	n := w.eventIndex(id)
+	if n < 0 {
+		return Event{}, false
+	}
+
+	event := w.events[n]
+	if !w.isReporter(event) || !amendable(event) {
+		return event, false
+	}
+
+	current := event.corrected()
+	c.Title = strings.TrimSpace(c.Title)
+	if c.Title == current.Title {
+		c.Title = ""
+	}
+	c.Location = strings.TrimSpace(c.Location)
+	if c.Location == current.Location {
+		c.Location = ""
+	}
+	c.Text = strings.TrimSpace(c.Text)
+	if c.Title == "" && c.Location == "" && c.Text == "" {
+		return event, false
+	}
+	c.Reason = strings.TrimSpace(c.Reason)
+	c.At = now.Unix()
+
+	c, err := signCorrection(w.key, event.ID, c)
+	if err != nil {
+		log.Println(err)
+		return event, false
+	}
+	event.Corrections = append(event.Corrections[:len(event.Corrections):len(event.Corrections)], c)
+	return w.logged(event, opCorrection, c.Reason, now), true
+}
+
+// retract returns the event with the given ID retracted by its reporter. It
+// returns false for citizens other than the reporter and for events that
+// can't be amended.
+func (w *witness) retract(id, reason string, now time.Time) (Event, bool) {
+	n := w.eventIndex(id)
+	if n < 0 {
+		return Event{}, false
+	}
+
+	event := w.events[n]
+	if !w.isReporter(event) || !amendable(event) {
+		return event, false
+	}
+
+	r, err := signRetraction(w.key, event.ID, Retraction{Reason: strings.TrimSpace(reason), At: now.Unix()})
+	if err != nil {
+		log.Println(err)
+		return event, false
+	}
+	event.Retraction = &r
+	event.State = string(stateRetracted)
+	return w.logged(event, opRetraction, r.Reason, now), true
+}
+
+func (w *witness) onCorrectionTitle(ctx app.Context, e app.Event) {
+	w.correction.Title = ctx.JSSrc().Get("value").String()
+}
+
+func (w *witness) onCorrectionLocation(ctx app.Context, e app.Event) {
+	w.correction.Location = ctx.JSSrc().Get("value").String()
+}
+
+func (w *witness) onCorrectionText(ctx app.Context, e app.Event) {
+	w.correction.Text = ctx.JSSrc().Get("value").String()
+}
+
+func (w *witness) onCorrectionReason(ctx app.Context, e app.Event) {
+	w.correction.Reason = ctx.JSSrc().Get("value").String()
+}
+
+func (w *witness) onCorrectEvent(ctx app.Context, e app.Event) {
+	id := ctx.JSSrc().Get("value").String()
+	event, ok := w.correct(id, w.correction, time.Now())
+	if !ok {
+		w.createNotification(ctx, NotificationWarning, w.t("correction.header"), w.t("correction.unchanged"))
+		return
+	}
+	w.correction = Correction{}
+
+	w.publishAmendment(ctx, event, w.t("correction.published"), w.t("correction.publishFailed"))
+}
+
+func (w *witness) onRetractEvent(ctx app.Context, e app.Event) {
+	id := ctx.JSSrc().Get("value").String()
+	event, ok := w.retract(id, w.correction.Reason, time.Now())
+	if !ok {
+		return
+	}
+	w.correction = Correction{}
+
+	w.publishAmendment(ctx, event, w.t("correction.retractedReport"), w.t("correction.retractFailed"))
+}
+
+// publishAmendment publishes a corrected or retracted event.
+func (w *witness) publishAmendment(ctx app.Context, event Event, success, failure string) {
+	ctx.Async(func() {
+		event, err := w.putEvent(event, topicUpdateEvent)
+		if err != nil {
+			ctx.Dispatch(func(ctx app.Context) {
+				w.createNotification(ctx, NotificationDanger, w.t(ErrorHeader), failure)
+			})
+			log.Println(err)
+			return
+		}
+
+		ctx.Dispatch(func(ctx app.Context) {
+			w.receiveUpdate(event)
+			w.createNotification(ctx, NotificationSuccess, w.t(SuccessHeader), success)
+		})
+	})
+}
+
+// renderCorrectedLabel marks events their reporter corrected.
+func (w *witness) renderCorrectedLabel(e Event) app.UI {
+	return app.If(len(e.Corrections) > 0, func() app.UI {
+		return app.Span().Class("p-status-label--information").Text(w.t("event.corrected"))
+	})
+}
+
+// renderAmendments shows the retraction and the corrections of e above
+// anything else about it, together with what was originally reported.
+func (w *witness) renderAmendments(e Event) app.UI {
+	cs := e.corrections()
+
+	return app.Div().Body(
+		app.If(e.Retraction != nil, func() app.UI {
+			r := e.Retraction
+			return app.Div().Class("p-notification--negative").Body(
+				app.Div().Class("p-notification__content").Body(
+					app.H5().Class("p-notification__title").Text(w.t("correction.retracted")),
+					app.P().Class("p-notification__message").Text(renderReason(r.Reason, r.At)),
+				),
+			)
+		}),
+		app.If(len(cs) > 0, func() app.UI {
+			return app.Div().Class("p-notification--caution").Body(
+				app.Div().Class("p-notification__content").Body(
+					app.H5().Class("p-notification__title").Text(w.t("correction.corrected")),
+					app.P().Class("p-notification__message").Text(w.t("correction.original")+" \""+e.Title+"\" "+w.t("correction.at")+" "+e.Location+"."),
+					app.Range(cs).Slice(func(n int) app.UI {
+						c := cs[n]
+						return app.Div().Body(
+							app.P().Class("p-text--small").Text(renderReason(c.Reason, c.At)),
+							app.If(c.Title != "", func() app.UI {
+								return app.P().Text(w.t("event.title") + ": " + c.Title)
+							}),
+							app.If(c.Location != "", func() app.UI {
+								return app.P().Text(w.t("event.location") + ": " + c.Location)
+							}),
+							app.If(c.Text != "", func() app.UI {
+								return app.P().Text(w.t("correction.description") + ": " + c.Text)
+							}),
+						)
+					}),
+				),
+			)
+		}),
+	)
+}
+
+// renderReason describes when and why the reporter amended their report.
+func renderReason(reason string, at int64) string {
+	s := time.Unix(at, 0).Format(time.RFC822)
+	if reason != "" {
+		s += " · " + reason
+	}
+	return s
+}
+
+// renderCorrectionForm lets the reporter correct or retract their report.
+func (w *witness) renderCorrectionForm(e Event) app.UI {
+	current := e.corrected()
+
+	return app.If(w.isReporter(e) && amendable(e), func() app.UI {
+		return app.Div().Class("p-form p-form--stacked").Body(
+			app.H4().Text(w.t("correction.heading")),
+			app.Div().Class("p-form__group row").Body(
+				app.Label().For("correction-title").Text(w.t("event.title")),
+				app.Input().ID("correction-title").Placeholder(current.Title).Value(w.correction.Title).OnKeyUp(w.onCorrectionTitle),
+			),
+			app.Div().Class("p-form__group row").Body(
+				app.Label().For("correction-location").Text(w.t("event.location")),
+				app.Input().ID("correction-location").Placeholder(current.Location).Value(w.correction.Location).OnKeyUp(w.onCorrectionLocation),
+			),
+			app.Div().Class("p-form__group row").Body(
+				app.Label().For("correction-text").Text(w.t("correction.description")),
+				app.Textarea().ID("correction-text").Class("is-dense").Rows(2).Text(w.correction.Text).OnKeyUp(w.onCorrectionText),
+			),
+			app.Div().Class("p-form__group row").Body(
+				app.Label().For("correction-reason").Text(w.t("correction.reason")),
+				app.Input().ID("correction-reason").Value(w.correction.Reason).OnKeyUp(w.onCorrectionReason),
+			),
+			app.Div().Class("p-form__group row").Body(
+				app.Button().Class("u-vertically-centered").Value(e.ID).Text(w.t("correction.publish")).OnClick(w.onCorrectEvent),
+				app.Button().Class("u-vertically-centered p-button--negative").Value(e.ID).Text(w.t("correction.retract")).OnClick(w.onRetractEvent),
+			),
+		)
+	})
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCorrectReport(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, other, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	original := Event{ID: "1", Type: eventType, Title: "Fire", Location: "u33d", Reporter: "10", ReporterKey: signerID(key),
+		Details: []Detail{{Text: "smoke", Author: "10"}}}
+	w := &witness{citizenID: "10", key: other}
+	w.receiveUpdate(original)
+
+	if _, ok := w.correct("1", Correction{Title: "Big fire"}, time.Unix(100, 0)); ok {
+		t.Fatal("correction by a key other than the reporter's")
+	}
+
+	w.key = key
+	if _, ok := w.correct("1", Correction{Title: "Fire", Location: "u33d"}, time.Unix(100, 0)); ok {
+		t.Error("correction changing nothing accepted")
+	}
+
+	e, ok := w.correct("1", Correction{Title: "Big fire", Reason: "it spread"}, time.Unix(100, 0))
+	if !ok {
+		t.Fatal("reporter could not correct their report")
+	}
+	w.receiveUpdate(e)
+	e, _ = w.correct("1", Correction{Location: "u33e", Text: "flames"}, time.Unix(200, 0))
+	w.receiveUpdate(e)
+
+	e = w.events[w.eventIndex("1")]
+	if e.Title != "Fire" || e.Location != "u33d" || e.Details[0].Text != "smoke" {
+		t.Errorf("original report overwritten: %+v", e)
+	}
+	if c := e.corrected(); c.Title != "Big fire" || c.Location != "u33e" {
+		t.Errorf("corrected = %q at %q, want \"Big fire\" at \"u33e\"", c.Title, c.Location)
+	}
+	if len(e.Corrections) != 2 {
+		t.Fatalf("corrections = %+v, want 2", e.Corrections)
+	}
+
+	// another client only seeing the second correction keeps both
+	merged, _ := mergeEvents(e, Event{ID: "1", Type: eventType, ReporterKey: signerID(key), Corrections: e.Corrections[1:]})
+	if len(merged.Corrections) != 2 {
+		t.Errorf("merged corrections = %+v, want 2", merged.Corrections)
+	}
+
+	forged, err := signCorrection(other, "1", Correction{Title: "Nothing happened", At: 300})
+	if err != nil {
+		t.Fatal(err)
+	}
+	update := e
+	update.Corrections = append(update.Corrections[:2:2], forged)
+	if err := validCorrections(e, update); !errors.Is(err, errBadCorrection) {
+		t.Errorf("forged correction: %v, want %v", err, errBadCorrection)
+	}
+	w.receiveUpdate(update)
+	if n := len(w.events[w.eventIndex("1")].Corrections); n != 2 {
+		t.Errorf("forged correction kept, %d corrections", n)
+	}
+}
+
+func TestRetractReport(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := &witness{citizenID: "10", key: key}
+	w.receiveUpdate(Event{ID: "1", Type: eventType, Reporter: "10", ReporterKey: signerID(key), Witnesses: []string{"11", "12"}, ConfirmedBy: 2})
+
+	e, ok := w.retract("1", "wrong street", time.Unix(100, 0))
+	if !ok {
+		t.Fatal("reporter could not retract their report")
+	}
+	w.receiveUpdate(e)
+
+	e = w.events[w.eventIndex("1")]
+	if e.state() != stateRetracted || e.Retraction == nil || e.Retraction.Reason != "wrong street" {
+		t.Fatalf("retracted event = %+v", e)
+	}
+	if _, ok := w.correct("1", Correction{Title: "Fire"}, time.Unix(200, 0)); ok {
+		t.Error("retracted report corrected")
+	}
+
+	w.citizenID = "20"
+	if _, ok := w.confirmation("1", time.Unix(200, 0)); ok {
+		t.Error("retracted report confirmed")
+	}
+
+	// a stale version doesn't undo the retraction
+	merged, _ := mergeEvents(e, Event{ID: "1", Type: eventType, Witnesses: []string{"11", "12"}, ConfirmedBy: 2})
+	if merged.state() != stateRetracted {
+		t.Errorf("state after stale update = %s, want %s", merged.state(), stateRetracted)
+	}
+}
@@ -1,9 +1,14 @@
 package main
 
 import (
+	"bytes"
+	"crypto/ed25519"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"os"
+	"reflect"
 	"sort"
 	"strconv"
 	"time"
@@ -17,6 +22,9 @@ import (
 
 const dbNameEvent = "event"
 
+// gatewayURL is where evidence files are opened from.
+const gatewayURL = "http://localhost:8080/ipfs/"
+
 const (
 	topicCreateEvent = "create-event"
 	topicUpdateEvent = "update-event"
@@ -28,8 +36,10 @@ const (
 	NotificationInfo    NotificationStatus = "info"
 	NotificationWarning NotificationStatus = "warning"
 	NotificationDanger  NotificationStatus = "negative"
-	SuccessHeader                          = "Success"
-	ErrorHeader                            = "Error"
+	// SuccessHeader and ErrorHeader are the catalog IDs of the headers of
+	// most notifications.
+	SuccessHeader = "notifications.success"
+	ErrorHeader   = "notifications.error"
 )
 
 // pubsub is a component that does a simple pubsub on ipfs. A component is a
@@ -37,37 +47,155 @@ const (
 // embedding app.Compo into a struct.
 type witness struct {
 	app.Compo
-	sh             *shell.Shell
-	sub            *shell.PubSubSubscription
-	citizenID      string
-	events         []Event
-	eventTitle     string
-	eventDetails   string
-	eventLocation  string
-	notifications  map[string]notification
-	notificationID int
-	noNews         bool
-	isWitness      bool
+	sh                   *shell.Shell
+	subs                 *subscriber
+	subStates            map[string]subState
+	citizenID            string
+	key                  ed25519.PrivateKey
+	events               []Event
+	eventTitle           string
+	eventDetails         string
+	eventLocation        string
+	notifications        []notification
+	notificationID       int
+	notificationHistory  []notification
+	noNews               bool
+	isWitness            bool
+	mutes                muteList
+	lifecycle            lifecycle
+	archive              []Event
+	history              map[string][]revision
+	revisionCID          map[string]string
+	fetchedRevision      map[string]*revision
+	evidence             []byte
+	importReport         *importReport
+	muteKind             string
+	muteValue            string
+	channel              channel
+	savedChannel         channel
+	channels             []channel
+	channelRegion        string
+	channelTopic         string
+	view                 view
+	viewEventID          string
+	linkedEvent          *Event
+	language             string
+	eventLanguage        string
+	languageFilter       string
+	cache                eventCache
+	syncing              bool
+	cacheSavePending     bool
+	eventTagInput        string
+	tagFilter            string
+	followedTags         []string
+	mergeTarget          string
+	stateFilter          eventState
+	correction           Correction
+	watched              []string
+	browserNotifications bool
+	syncID               string
+	retired              map[string]Event
+	sources              map[string][]Event
+	syncStats            syncStats
+	digestAt             int64
 }
 
 type NotificationStatus string
 
-type notification struct {
-	id      int
-	status  string
-	header  string
-	message string
-}
-
 type Event struct {
 	ID          string   `mapstructure:"_id" json:"_id" validate:"uuid_rfc4122"`
 	Type        string   `mapstructure:"type" json:"type" validate:"uuid_rfc4122"`
 	ConfirmedBy int      `mapstructure:"confirmedBy" json:"confirmedBy" validate:"uuid_rfc4122"`
 	Title       string   `mapstructure:"title" json:"title" validate:"uuid_rfc4122"`
-	Details     []string `mapstructure:"details" json:"details" validate:"uuid_rfc4122"`
+	Details     []Detail `mapstructure:"details" json:"details" validate:"uuid_rfc4122"`
 	Location    string   `mapstructure:"location" json:"location" validate:"uuid_rfc4122"`
 	Reporter    string   `mapstructure:"reporter" json:"reporter" validate:"uuid_rfc4122"`
 	Witnesses   []string `mapstructure:"witnesses" json:"witnesses" validate:"uuid_rfc4122"`
+	CreatedAt   int64    `mapstructure:"createdAt" json:"createdAt" validate:"uuid_rfc4122"`
+	NewsAt      int64    `mapstructure:"newsAt" json:"newsAt" validate:"uuid_rfc4122"`
+	ArchivedAt  int64    `mapstructure:"archivedAt" json:"archivedAt" validate:"uuid_rfc4122"`
+	Revision    string   `mapstructure:"revision" json:"revision" validate:"uuid_rfc4122"`
+	Evidence    []string `mapstructure:"evidence" json:"evidence" validate:"uuid_rfc4122"`
+	Signer      string   `mapstructure:"signer" json:"signer" validate:"uuid_rfc4122"`
+	Signature   string   `mapstructure:"signature" json:"signature" validate:"uuid_rfc4122"`
+	// Channel is left out when empty so that events of the global channel
+	// signed before channels existed still verify.
+	Channel string `mapstructure:"channel" json:"channel,omitempty" validate:"uuid_rfc4122"`
+	// Language is the ISO 639-1 code of the language the report is written
+	// in. Like Channel it is left out when empty to keep older signatures
+	// valid.
+	Language string `mapstructure:"language" json:"language,omitempty" validate:"uuid_rfc4122"`
+	// Tags are normalised with normalizeTag and left out when empty, like
+	// Channel.
+	Tags []string `mapstructure:"tags" json:"tags,omitempty" validate:"uuid_rfc4122"`
+	// MergeInto is the ID of the earlier report this one is proposed to be
+	// a duplicate of, and once merged the event links redirect to.
+	MergeInto string `mapstructure:"mergeInto" json:"mergeInto,omitempty" validate:"uuid_rfc4122"`
+	// MergeVotes are the citizens who agreed to the merge.
+	MergeVotes []string `mapstructure:"mergeVotes" json:"mergeVotes,omitempty" validate:"uuid_rfc4122"`
+	// MergedFrom are the IDs of the duplicates merged into this event.
+	MergedFrom []string `mapstructure:"mergedFrom" json:"mergedFrom,omitempty" validate:"uuid_rfc4122"`
+	// State records the lifecycle state, see Event.state. Events stored
+	// before it existed leave it out.
+	State string `mapstructure:"state" json:"state,omitempty" validate:"uuid_rfc4122"`
+	// Disputes are the citizens who dispute the event.
+	Disputes []string `mapstructure:"disputes" json:"disputes,omitempty" validate:"uuid_rfc4122"`
+	// ReporterKey is the signer of the original report, the only one who
+	// may retract it.
+	ReporterKey string `mapstructure:"reporterKey" json:"reporterKey,omitempty" validate:"uuid_rfc4122"`
+	// Corrections are the changes the reporter made to the report, see
+	// Correction.
+	Corrections []Correction `mapstructure:"corrections" json:"corrections,omitempty" validate:"uuid_rfc4122"`
+	// Retraction is set once the reporter withdrew the report.
+	Retraction *Retraction `mapstructure:"retraction" json:"retraction,omitempty" validate:"uuid_rfc4122"`
+	// Audit is the append-only log of the operations performed on the
+	// event, see AuditEntry.
+	Audit []AuditEntry `mapstructure:"audit" json:"audit,omitempty" validate:"uuid_rfc4122"`
+}
+
+// Detail is a single account of an event together with the citizen who
+// provided it.
+type Detail struct {
+	Text   string `mapstructure:"text" json:"text" validate:"uuid_rfc4122"`
+	Author string `mapstructure:"author" json:"author" validate:"uuid_rfc4122"`
+	// Language is the ISO 639-1 code of the language the detail is written
+	// in, empty for details added before languages were tagged.
+	Language string `mapstructure:"language" json:"language,omitempty" validate:"uuid_rfc4122"`
+	// From is the ID of the duplicate report the detail was given on before
+	// it was merged, empty for details given on the event itself.
+	From string `mapstructure:"from" json:"from,omitempty" validate:"uuid_rfc4122"`
+}
+
+// UnmarshalJSON accepts both the object form and the plain strings older
+// clients published as details.
+func (d *Detail) UnmarshalJSON(b []byte) error {
+	var text string
+	if err := json.Unmarshal(b, &text); err == nil {
+		d.Text = text
+		return nil
+	}
+
+	type detail Detail
+	return json.Unmarshal(b, (*detail)(d))
+}
+
+// decodeEvent decodes a document returned by the docs store into an Event.
+func decodeEvent(input interface{}) (Event, error) {
+	e := Event{}
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: func(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+			if from.Kind() == reflect.String && to == reflect.TypeOf(Detail{}) {
+				return Detail{Text: data.(string)}, nil
+			}
+			return data, nil
+		},
+		Result: &e,
+	})
+	if err != nil {
+		return e, err
+	}
+
+	return e, decoder.Decode(input)
 }
 
 func (w *witness) OnMount(ctx app.Context) {
@@ -78,150 +206,125 @@ func (w *witness) OnMount(ctx app.Context) {
 		log.Fatal(err)
 	}
 
-	citizenID := myPeer.ID[len(myPeer.ID)-8:]
-	// replace password with your own
-	password := "mysecretpassword"
-
-	w.citizenID = mixer.EncodeString(password, citizenID)
+	w.citizenID = citizenIDFromPeer(myPeer.ID)
 	w.citizenID = "10"
+	w.key = loadSigningKey(ctx)
+	w.syncID = newSyncID()
+	w.cache = newEventCache()
+	w.loadChannels(ctx)
 
-	w.subscribeToCreateEventTopic(ctx)
-	w.subscribeToUpdateEventTopic(ctx)
-	w.notifications = make(map[string]notification)
+	w.subStates = make(map[string]subState)
+	w.subs = newSubscriber(w.sh, func(topic string, s subState) {
+		ctx.Dispatch(func(ctx app.Context) {
+			w.subStates[topic] = s
+		})
+	})
+	w.subscribeChannel(ctx, w.channel)
+	w.loadNotificationHistory(ctx)
+	w.loadLanguage(ctx)
+	w.loadFollowedTags(ctx)
+	w.loadWatched(ctx)
+	w.resetHistory()
+	w.loadMuteList(ctx)
 
 	// set defaults
 	w.noNews = true
+	w.lifecycle = lifecycleFromEnv()
 
-	ctx.Async(func() {
-		// err := w.sh.OrbitDocsDelete(dbNameEvent, "all")
-		// if err != nil {
-		// 	log.Fatal(err)
-		// }
-
-		v, err := w.sh.OrbitDocsQuery(dbNameEvent, "type", "event")
-		if err != nil {
-			log.Fatal(err)
-		}
-
-		var vv []interface{}
-		err = json.Unmarshal(v, &vv)
-		if err != nil {
-			log.Fatal(err)
-		}
-
-		for _, ii := range vv {
-			e := Event{}
-			err = mapstructure.Decode(ii, &e)
-			if err != nil {
-				log.Fatal(err)
-			}
-			ctx.Dispatch(func(ctx app.Context) {
-				if e.ConfirmedBy > 1 {
-					w.noNews = false
-				}
-				for _, v := range e.Witnesses {
-					if w.citizenID == v {
-						w.isWitness = true
-					}
-				}
-				w.events = append(w.events, e)
-				sort.SliceStable(w.events, func(i, j int) bool {
-					return w.events[i].ID < w.events[j].ID
-				})
-			})
-		}
-	})
+	w.loadEvents(ctx)
+	ctx.After(archiveCheckInterval, w.checkExpired)
+	ctx.After(syncFirstDelay, w.syncRound)
 }
 
-func (w *witness) subscribeToCreateEventTopic(ctx app.Context) {
-	ctx.Async(func() {
-		topic := topicCreateEvent
-		subscription, err := w.sh.PubSubSubscribe(topic)
-		if err != nil {
-			log.Fatal(err)
-		}
-		w.sub = subscription
-		w.subscriptionCreateEvent(ctx)
-	})
+func (w *witness) OnDismount() {
+	w.subs.close()
 }
 
-func (w *witness) subscribeToUpdateEventTopic(ctx app.Context) {
-	ctx.Async(func() {
-		topic := topicUpdateEvent
-		subscription, err := w.sh.PubSubSubscribe(topic)
-		if err != nil {
-			log.Fatal(err)
-		}
-		w.sub = subscription
-		w.subscriptionUpdateEvent(ctx)
-	})
+// citizenIDFromPeer derives the anonymous citizen ID from a peer ID.
+func citizenIDFromPeer(peerID string) string {
+	citizenID := peerID[len(peerID)-8:]
+	// replace password with your own
+	password := "mysecretpassword"
+
+	return mixer.EncodeString(password, citizenID)
 }
 
-func (w *witness) subscriptionCreateEvent(ctx app.Context) {
-	ctx.Async(func() {
-		defer w.sub.Cancel()
-		// wait on pubsub
-		res, err := w.sub.Next()
-		if err != nil {
-			log.Fatal(err)
-		}
-		// Decode the string data.
-		str := string(res.Data)
-		log.Println("Subscriber of topic create-event received message: " + str)
-		ctx.Async(func() {
-			w.subscribeToCreateEventTopic(ctx)
-		})
+// decodeMessage decodes an event published on one of the event topics.
+// Events with an invalid signature are refused with errBadSignature. Whether
+// an unsigned event may replace the known one is up to receiveUpdate.
+func decodeMessage(data []byte) (Event, error) {
+	e := Event{}
+	err := json.Unmarshal(data, &e)
+	if err != nil {
+		return e, err
+	}
 
-		e := Event{}
-		err = json.Unmarshal([]byte(str), &e)
-		if err != nil {
-			log.Fatal(err)
-		}
+	if err := verifyEvent(e); errors.Is(err, errBadSignature) {
+		return e, err
+	}
+	return e, nil
+}
 
-		ctx.Dispatch(func(ctx app.Context) {
-			w.events = append(w.events, e)
-		})
-	})
+// receiveEvent adds an event announced on the create topic. Reports pass
+// the same checks as updates, so a new event can't claim confirmations it
+// doesn't have.
+func (w *witness) receiveEvent(e Event) {
+	w.receiveUpdate(e)
 }
 
-func (w *witness) subscriptionUpdateEvent(ctx app.Context) {
-	ctx.Async(func() {
-		defer w.sub.Cancel()
-		// wait on pubsub
-		res, err := w.sub.Next()
-		if err != nil {
-			log.Fatal(err)
-		}
-		// Decode the string data.
-		str := string(res.Data)
-		log.Println("Subscriber of topic update-event received message: " + str)
-		ctx.Async(func() {
-			w.subscribeToUpdateEventTopic(ctx)
-		})
+// receiveUpdate merges an event announced on the update topic into the
+// loaded events. Events retired this session are checked against their
+// retired version and stay retired, so a stale update can't bring them back.
+func (w *witness) receiveUpdate(e Event) {
+	n := w.eventIndex(e.ID)
+	local, known := Event{}, n >= 0
+	if known {
+		local = w.events[n]
+	} else if r, ok := w.retired[e.ID]; ok {
+		local, known = r, true
+	}
+	if err := w.validUpdate(local, known, e); err != nil {
+		log.Println("Dropped update of event " + e.ID + ": " + err.Error())
+		return
+	}
+	if known && n < 0 {
+		w.retire(e)
+		return
+	}
 
-		e := Event{}
-		err = json.Unmarshal([]byte(str), &e)
-		if err != nil {
-			log.Fatal(err)
+	if n >= 0 {
+		merged, conflicts := mergeEvents(local, e)
+		for _, c := range conflicts {
+			log.Println("Conflicting update of event " + e.ID + ": " + c)
 		}
+		w.keepSources(local, e, merged)
+		e = merged
+	}
 
-		ctx.Dispatch(func(ctx app.Context) {
-			if e.ConfirmedBy > 1 {
-				w.noNews = false
-			}
-			for _, v := range e.Witnesses {
-				if w.citizenID == v {
-					w.isWitness = true
-				}
-			}
+	if e.state() == stateNews {
+		w.noNews = false
+	}
+	for _, v := range e.Witnesses {
+		if w.citizenID == v {
+			w.isWitness = true
+		}
+	}
 
-			id, err := strconv.Atoi(e.ID)
-			if err != nil {
-				log.Fatal(err)
-			}
-			w.events[id-1] = e
-		})
-	})
+	inactive := e.Type == archivedType || e.Type == mergedType
+	if inactive {
+		w.retire(e)
+	}
+	switch {
+	case inactive && n >= 0:
+		w.events = append(w.events[:n], w.events[n+1:]...)
+		w.updateNoNews()
+	case inactive:
+	case n >= 0:
+		w.events[n] = e
+	default:
+		w.events = append(w.events, e)
+		sortEvents(w.events)
+	}
 }
 
 // The Render method is where the component appearance is defined. Here, a
@@ -231,51 +334,56 @@ func (w *witness) Render() app.UI {
 		app.Link().Rel("stylesheet").Href("https://assets.ubuntu.com/v1/vanilla-framework-version-3.8.0.min.css"),
 		app.Link().Rel("stylesheet").Href("https://use.fontawesome.com/releases/v6.2.0/css/all.css"),
 		app.Link().Rel("stylesheet").Href("/app.css"),
-		app.If(len(w.notifications) > 0, func() app.UI {
-			return app.Range(w.notifications).Map(func(s string) app.UI {
-				return app.Div().Class("p-notification--"+w.notifications[s].status).Body(
-					app.Div().Class("p-notification__content").Body(
-						app.H5().Class("p-notification__title").Text(w.notifications[s].header),
-						app.P().Class("p-notification__message").Text(w.notifications[s].message),
-					),
-				).Style("position", "fixed").Style("width", "100%").Style("z-index", "999")
-			})
-		}),
+		w.renderToasts(),
 		app.Section().Class("p-strip--suru").Body(
 			app.Div().Class("row u-vertically-center").Body(
 				app.Div().Class("col-12").Body(
-					app.H1().Text("Cyber Witness - the news as they should be"),
-					app.P().Text("P2P community of independent reporters and witnesses - an alternative to mass media. Reporters publish events they have personally seen with no interpretation. Until confirmed they show up as rumors. Witnesses confirm rumors they have witnessed and add their own details. Event details aggregate and become more accurate with the input of each new witness. Once a rumor has been confirmed by at least 2 witnesses it becomes news. The more witnesses the greater accuracy of news."),
-					app.Button().Text("How it works").OnClick(w.openHowToDialog),
+					app.H1().Text(w.t("hero.title")),
+					app.P().Text(w.t("hero.intro")),
+					app.Button().Text(w.t("hero.howItWorks")).OnClick(w.openHowToDialog),
+					app.Button().Text(w.t("hero.muteList")).OnClick(w.openMuteDialog),
+					app.Button().Text(w.t("hero.backup")).OnClick(w.openBackupDialog),
+					app.Button().Text(w.t("hero.notifications")).OnClick(w.openNotificationsDialog),
+					app.If(digestConfigured(), func() app.UI {
+						return app.Button().Text(w.t("hero.digest")).OnClick(w.onLoadDigest)
+					}),
+					w.renderLanguageSwitcher(),
+					w.renderChannelSwitcher(),
+					w.renderConnectionState(),
+					w.renderSyncState(),
+					w.renderSyncStats(),
+					w.renderDigestState(),
 				),
 			),
 		),
 		app.Section().Class("p-strip--suru").Body(
 			app.Div().Class("row u-vertically-center").Body(
-				app.Div().Class("col-12").Body(
-					app.H1().Text("Have an event to report?"),
+				app.Div().Class("col-12").ID("report").Body(
+					app.H1().Text(w.t("report.heading")),
 					app.Div().Class("p-form p-form--stacked").Body(
 						app.Div().Class("p-form__group row").Body(
 							// app.Div().Class("p-form__group row").Body(
-							app.P().Class("p-form-help-text").ID("reportEvent").Text("Check rumors first as it may already exist.").Style("color", "#fff").Style("margin-top", "0").Style("margin-bottom", "10px"),
+							app.P().Class("p-form-help-text").ID("reportEvent").Text(w.t("report.help")).Style("color", "#fff").Style("margin-top", "0").Style("margin-bottom", "10px"),
 							// ),
-							app.Label().For("title").Text("Title"),
+							app.Label().For("title").Text(w.t("event.title")),
 							app.Input().ID("title").Name("title").OnKeyUp(w.onEventTitle),
 						),
 						app.Div().Class("p-form__group row").Body(
-							app.Label().For("details").Text("Details"),
+							app.Label().For("details").Text(w.t("event.details")),
 							app.Textarea().Class("is-dense").ID("details").Name("details").Rows(2).OnKeyUp(w.onEventDetails),
 						),
 						app.Div().Class("p-form__group row").Body(
-							app.Label().For("location").Text("Location"),
+							app.Label().For("location").Text(w.t("event.location")),
 							app.Textarea().Class("is-dense").ID("location").Name("location").Rows(2).OnKeyUp(w.onEventLocation),
 						),
-						// app.Div().Class("p-form__group row").Body(
-						// 	app.Label().For("file").Text("Optional Image/Video Evidence"),
-						// 	app.Input().Class("is-dense").ID("file").Name("file").Type("file"),
-						// ),
 						app.Div().Class("p-form__group row").Body(
-							app.Button().Class("u-vertically-centered").Text("Report event").OnClick(w.onSubmitEvent),
+							app.Label().For("file").Text(w.t("report.evidence")),
+							app.Input().Class("is-dense").ID("file").Name("file").Type("file").Accept("image/*,video/*").OnChange(w.onEventEvidence),
+						),
+						w.renderReportTags(),
+						w.renderReportLanguage(),
+						app.Div().Class("p-form__group row").Body(
+							app.Button().Class("u-vertically-centered").Text(w.t("report.submit")).OnClick(w.onSubmitEvent),
 						),
 					),
 				),
@@ -284,85 +392,118 @@ func (w *witness) Render() app.UI {
 		app.Section().Class("p-strip--suru").Body(
 			app.Div().Class("row u-vertically-center").Body(
 				app.Div().Class("col-12").Body(
-					app.H1().Text("Been a witness of an event?"),
-					app.Button().Text("Confirm rumors").OnClick(w.openRumorsDialog),
+					app.H1().Text(w.t("witness.heading")),
+					app.Button().Text(w.t("witness.confirmRumors")).OnClick(w.openRumorsDialog),
 				),
 			),
 			app.Div().Class("row u-vertically-center").Body(
 				app.Div().Class("col-12").Body(
-					app.H1().Text("Just want to read the news?"),
-					app.P().Text("Your personal news feed at your fingertips. All witnessed. No ads, paywalls, censorship or fact checkers."),
-					app.Button().Text("Read the news").OnClick(w.openNewsDialog),
+					app.H1().Text(w.t("reader.heading")),
+					app.P().Text(w.t("reader.intro")),
+					app.Button().Text(w.t("reader.readNews")).OnClick(w.openNewsDialog),
+					app.Button().Text(w.t("reader.archive")).OnClick(w.openArchiveDialog),
 				),
 			),
 		).Style("background-image", "linear-gradient(to bottom right, rgba(205, 205, 205, 0.55) 0%, rgba(205, 205, 205, 0.55) 49.8%, transparent 50%, transparent 100%),linear-gradient(to bottom left, rgba(205, 205, 205, 0.55) 0%, rgba(205, 205, 205, 0.55) 49.8%, transparent 50%, transparent 100%),linear-gradient(to top right, #fff 0%, #fff 49%, transparent 50%, transparent 100%),linear-gradient(#fff 0%, #fff 100%),linear-gradient(111deg, #2F4858 10%, #2F4858 37%, #2F4858 100%)"),
-		app.Div().Class("p-modal").ID("rumors-modal").Style("display", "none").Body(
+		app.Div().Class("p-modal").ID("rumors-modal").Style("display", w.modalDisplay(viewRumors)).Body(
 			app.Section().Class("p-modal__dialog").Role("dialog").Aria("modal", true).Aria("labelledby", "modal-title").Aria("describedby", "modal-description").Body(
 				app.Header().Class("p-modal__header").Body(
-					app.H2().Class("p-modal__title").ID("modal-title").Text("Rumors"),
-					app.Button().Class("p-modal__close").Aria("label", "Close active modal").Aria("controls", "modal").OnClick(w.closeRumorsModal),
+					app.H2().Class("p-modal__title").ID("modal-title").Text(w.t("feed.rumors")),
+					app.Button().Class("p-modal__close").Aria("label", w.t("modal.close")).Aria("controls", "modal").OnClick(w.closeRumorsModal),
 				),
+				w.renderLanguageFilter("rumors-language"),
+				w.renderTagFilter(viewRumors),
+				w.renderStateFilter("rumors-state"),
+				w.renderFeedExport(),
 				app.Table().Aria("label", "rumors-table").Class("p-table--expanding").Body(
 					app.THead().Body(
 						app.Tr().Body(
 							app.Th().Body(
-								app.Span().Class("status-icon is-blocked").Text("Title"),
+								app.Span().Class("status-icon is-blocked").Text(w.t("event.title")),
 							),
-							app.Th().Text("Location"),
-							app.Th().Text("Action"),
-							app.Th().Class("u-align--right").Text("Details"),
+							app.Th().Text(w.t("event.location")),
+							app.Th().Text(w.t("feed.action")),
+							app.Th().Class("u-align--right").Text(w.t("event.details")),
 						),
 					),
 					app.If(len(w.events) > 0, func() app.UI {
 						return app.TBody().Body(
 							app.Range(w.events).Slice(func(i int) app.UI {
-								return app.Tr().DataSet("title", i).Body(
-									app.Td().Class("has-overflow").DataSet("column", "title").Body(
-										app.Div().Text(w.events[i].Title),
-									),
-									app.Td().Class("has-overflow").DataSet("column", "location").Body(
-										app.Div().Text(w.events[i].Location),
-									),
-									app.Td().Class("has-overflow").DataSet("column", "action").Body(
-										app.If(w.citizenID == w.events[i].Reporter || w.isWitness, func() app.UI {
-											return app.Button().Class("is-dense").Value(w.events[i].ID).Text("Confirm").Disabled(true).OnClick(w.confirmRumor)
-										}).Else(func() app.UI {
-											return app.Button().Class("is-dense").Value(w.events[i].ID).Text("Confirm").OnClick(w.confirmRumor)
-										}),
-									),
-									app.Td().Class("has-overflow u-align--right").DataSet("column", "details").Body(
-										app.Button().Class("u-toggle is-dense").Aria("controls", "expanded-row").Aria("expanded", "true").DataSet("shown-text", "Hide").DataSet("hidden-text", "Show").Value(w.events[i].ID).Text("Hide").OnClick(w.expandDetails),
-									),
-									app.Td().ID("expanded-row-"+w.events[i].ID).Class("has-overflow p-table__expanding-panel").Aria("hidden", "false").Body(
-										app.H4().Text("Details"),
-										app.Range(w.events[i].Details).Slice(func(n int) app.UI {
-											return app.Div().Class("row").Body(
-												app.Div().Class("col-8 p-card").Body(
-													app.P().Text(w.events[i].Details[n]),
-												),
-											)
-										}),
-										app.If(w.citizenID != w.events[i].Reporter && !w.isWitness, func() app.UI {
-											return app.Div().Class("p-form p-form--stacked").Body(
-												app.H4().Text("Add new details: "),
-												app.Div().Class("p-form__group row").Body(
-													app.Textarea().Class("is-dense").ID("details").Name("details").Rows(2).OnKeyUp(w.onEventDetails),
-												),
-												app.Div().Class("p-form__group row").Body(
-													app.Button().Class("u-vertically-centered").Value(w.events[i].ID).Text("Add details").OnClick(w.onAddDetails),
-												),
-											)											
-										}),
-									),
-								)
+								return app.If(w.inFeed(w.events[i]), func() app.UI {
+									return app.Tr().DataSet("title", i).Body(
+										app.Td().Class("has-overflow").DataSet("column", "title").Body(
+											app.Div().Lang(w.events[i].Language).Body(
+												app.Text(w.events[i].corrected().Title+" "),
+												renderLanguage(w.events[i].Language),
+												w.renderState(w.events[i]),
+												w.renderCorrectedLabel(w.events[i]),
+											),
+											w.renderTags(viewRumors, w.events[i].Tags),
+										),
+										app.Td().Class("has-overflow").DataSet("column", "location").Body(
+											app.Div().Text(w.events[i].corrected().Location),
+										),
+										app.Td().Class("has-overflow").DataSet("column", "action").Body(
+											app.If(w.citizenID == w.events[i].Reporter || w.isWitness || !amendable(w.events[i]), func() app.UI {
+												return app.Button().Class("is-dense").Value(w.events[i].ID).Text(w.t("event.confirm")).Disabled(true).OnClick(w.confirmRumor)
+											}).Else(func() app.UI {
+												return app.Button().Class("is-dense").Value(w.events[i].ID).Text(w.t("event.confirm")).OnClick(w.confirmRumor)
+											}),
+											w.renderDispute(w.events[i]),
+										),
+										app.Td().Class("has-overflow u-align--right").DataSet("column", "details").Body(
+											app.Button().Class("u-toggle is-dense").Aria("controls", "expanded-row").Aria("expanded", "true").DataSet("shown-text", w.t("event.hide")).DataSet("hidden-text", w.t("event.show")).Value(w.events[i].ID).Text(w.t("event.hide")).OnClick(w.expandDetails),
+										),
+										app.Td().ID("expanded-row-"+w.events[i].ID).Class("has-overflow p-table__expanding-panel").Aria("hidden", "false").Body(
+											w.renderAmendments(w.events[i]),
+											app.H4().Text(w.t("event.details")),
+											app.Range(w.events[i].Details).Slice(func(n int) app.UI {
+												return app.If(!w.mutes.hidesDetail(w.events[i].Details[n]), func() app.UI {
+													d := w.events[i].Details[n]
+													return app.Div().Class("row").Body(
+														app.Div().Class("col-8 p-card").Lang(d.Language).Body(
+															app.P().Text(d.Text),
+															app.If(d.Language != w.events[i].Language, func() app.UI {
+																return renderLanguage(d.Language)
+															}),
+														),
+													)
+												})
+											}),
+											app.Range(w.events[i].Evidence).Slice(func(n int) app.UI {
+												return app.A().Class("p-button--base is-dense").Href(gatewayURL + w.events[i].Evidence[n]).Target("_blank").Text(w.t("event.evidence"))
+											}),
+											app.Button().Class("is-dense p-button--base").Value(w.events[i].ID).Text(w.t("event.history")).OnClick(w.onShowHistory),
+											app.A().Class("p-button--base is-dense").Href(eventPath(w.events[i])).Text(w.t("event.link")),
+											w.renderWatchButton(w.events[i]),
+											app.If(w.citizenID != w.events[i].Reporter, func() app.UI {
+												return app.Button().Class("is-dense p-button--base").Value(w.events[i].Reporter).Text(w.t("event.muteReporter")).OnClick(w.onMuteReporter)
+											}),
+											w.renderHistory(w.events[i].ID),
+											w.renderAuditLog(w.events[i]),
+											w.renderMergeControls(w.events[i]),
+											app.If(w.citizenID != w.events[i].Reporter && !w.isWitness, func() app.UI {
+												return app.Div().Class("p-form p-form--stacked").Body(
+													app.H4().Text(w.t("event.newDetails")),
+													app.Div().Class("p-form__group row").Body(
+														app.Textarea().Class("is-dense").ID("details").Name("details").Rows(2).OnKeyUp(w.onEventDetails),
+													),
+													app.Div().Class("p-form__group row").Body(
+														app.Button().Class("u-vertically-centered").Value(w.events[i].ID).Text(w.t("event.addDetails")).OnClick(w.onAddDetails),
+													),
+												)
+											}),
+										),
+									)
+								})
 							}),
 						)
 					}).Else(func() app.UI {
 						return app.Caption().Class("p-strip").Body(
 							app.Div().Class("row").Body(
 								app.Div().Class("u-align--left col-8 col-medium-4 col-small-3").Body(
-									app.P().Class("p-heading--4 u-no-margin--bottom").Text("No recent rumors"),
-									app.P().Text("Check back later or report an event"),
+									app.P().Class("p-heading--4 u-no-margin--bottom").Text(w.t("feed.noRumors")),
+									app.P().Text(w.t("feed.emptyHint")),
 								),
 							),
 						)
@@ -370,49 +511,79 @@ func (w *witness) Render() app.UI {
 				),
 			),
 		),
-		app.Div().Class("p-modal").ID("news-modal").Style("display", "none").Body(
+		app.Div().Class("p-modal").ID("news-modal").Style("display", w.modalDisplay(viewNews)).Body(
 			app.Section().Class("p-modal__dialog").Role("dialog").Aria("modal", true).Aria("labelledby", "modal-title").Aria("describedby", "modal-description").Body(
 				app.Header().Class("p-modal__header").Body(
-					app.H2().Class("p-modal__title").ID("modal-title").Text("News"),
-					app.Button().Class("p-modal__close").Aria("label", "Close active modal").Aria("controls", "modal").OnClick(w.closeNewsModal),
+					app.H2().Class("p-modal__title").ID("modal-title").Text(w.t("feed.news")),
+					app.Button().Class("p-modal__close").Aria("label", w.t("modal.close")).Aria("controls", "modal").OnClick(w.closeNewsModal),
 				),
+				w.renderLanguageFilter("news-language"),
+				w.renderTagFilter(viewNews),
+				w.renderStateFilter("news-state"),
+				w.renderFeedExport(),
 				app.Table().Aria("label", "news-table").Class("p-table--expanding").Body(
 					app.THead().Body(
 						app.Tr().Body(
 							app.Th().Body(
-								app.Span().Class("status-icon is-blocked").Text("Title"),
+								app.Span().Class("status-icon is-blocked").Text(w.t("event.title")),
 							),
-							app.Th().Text("Location"),
-							app.Th().Text("Confirmed By"),
-							app.Th().Class("u-align--right").Text("Details"),
+							app.Th().Text(w.t("event.location")),
+							app.Th().Text(w.t("feed.confirmedBy")),
+							app.Th().Class("u-align--right").Text(w.t("event.details")),
 						),
 					),
 					app.If(!w.noNews, func() app.UI {
 						return app.TBody().Body(
 							app.Range(w.events).Slice(func(i int) app.UI {
-								return app.If(w.events[i].ConfirmedBy > 1, func() app.UI {
+								return app.If(w.inNews(w.events[i]), func() app.UI {
 									return app.Tr().DataSet("title", i).Body(
 										app.Td().Class("has-overflow").DataSet("column", "title").Body(
-											app.Div().Text(w.events[i].Title),
+											app.Div().Lang(w.events[i].Language).Body(
+												app.Text(w.events[i].corrected().Title+" "),
+												renderLanguage(w.events[i].Language),
+												w.renderState(w.events[i]),
+												w.renderCorrectedLabel(w.events[i]),
+											),
+											w.renderTags(viewNews, w.events[i].Tags),
 										),
 										app.Td().Class("has-overflow").DataSet("column", "location").Body(
-											app.Div().Text(w.events[i].Location),
+											app.Div().Text(w.events[i].corrected().Location),
 										),
 										app.Td().Class("has-overflow").DataSet("column", "confirmedBy").Body(
 											app.Div().Text(w.events[i].ConfirmedBy),
 										),
 										app.Td().Class("has-overflow u-align--right").DataSet("column", "details").Body(
-											app.Button().Class("u-toggle is-dense").Aria("controls", "expanded-row").Aria("expanded", "true").DataSet("shown-text", "Hide").DataSet("hidden-text", "Show").Value(w.events[i].ID).Text("Hide").OnClick(w.expandDetails),
+											app.Button().Class("u-toggle is-dense").Aria("controls", "expanded-row").Aria("expanded", "true").DataSet("shown-text", w.t("event.hide")).DataSet("hidden-text", w.t("event.show")).Value(w.events[i].ID).Text(w.t("event.hide")).OnClick(w.expandDetails),
 										),
 										app.Td().ID("expanded-row-"+w.events[i].ID).Class("has-overflow p-table__expanding-panel").Aria("hidden", "false").Body(
-											app.H4().Text("Details"),
+											w.renderAmendments(w.events[i]),
+											app.H4().Text(w.t("event.details")),
 											app.Range(w.events[i].Details).Slice(func(n int) app.UI {
-												return app.Div().Class("row").Body(
-													app.Div().Class("col-8 p-card").Body(
-														app.P().Text(w.events[i].Details[n]),
-													),
-												)
+												return app.If(!w.mutes.hidesDetail(w.events[i].Details[n]), func() app.UI {
+													d := w.events[i].Details[n]
+													return app.Div().Class("row").Body(
+														app.Div().Class("col-8 p-card").Lang(d.Language).Body(
+															app.P().Text(d.Text),
+															app.If(d.Language != w.events[i].Language, func() app.UI {
+																return renderLanguage(d.Language)
+															}),
+														),
+													)
+												})
 											}),
+											app.Range(w.events[i].Evidence).Slice(func(n int) app.UI {
+												return app.A().Class("p-button--base is-dense").Href(gatewayURL + w.events[i].Evidence[n]).Target("_blank").Text(w.t("event.evidence"))
+											}),
+											app.Button().Class("is-dense p-button--base").Value(w.events[i].ID).Text(w.t("event.history")).OnClick(w.onShowHistory),
+											app.A().Class("p-button--base is-dense").Href(eventPath(w.events[i])).Text(w.t("event.link")),
+											w.renderWatchButton(w.events[i]),
+											app.If(w.citizenID != w.events[i].Reporter, func() app.UI {
+												return app.Button().Class("is-dense p-button--base").Value(w.events[i].Reporter).Text(w.t("event.muteReporter")).OnClick(w.onMuteReporter)
+											}),
+											w.renderHistory(w.events[i].ID),
+											w.renderAuditLog(w.events[i]),
+											w.renderDispute(w.events[i]),
+											w.renderMergeControls(w.events[i]),
 										),
 									)
 								})
@@ -422,8 +593,8 @@ func (w *witness) Render() app.UI {
 						return app.Caption().Class("p-strip").Body(
 							app.Div().Class("row").Body(
 								app.Div().Class("u-align--left col-8 col-medium-4 col-small-3").Body(
-									app.P().Class("p-heading--4 u-no-margin--bottom").Text("No recent news"),
-									app.P().Text("Check back later or report an event"),
+									app.P().Class("p-heading--4 u-no-margin--bottom").Text(w.t("feed.noNews")),
+									app.P().Text(w.t("feed.emptyHint")),
 								),
 							),
 						)
@@ -431,90 +602,96 @@ func (w *witness) Render() app.UI {
 				),
 			),
 		),
+		w.renderNotificationsModal(),
+		w.renderEventModal(),
+		w.renderChannelsModal(),
+		w.renderMuteModal(),
+		w.renderArchiveModal(),
+		w.renderBackupModal(),
 		app.Div().Class("p-modal").ID("howto-modal").Style("display", "none").Body(
 			app.Section().Class("p-modal__dialog").Role("dialog").Aria("modal", true).Aria("labelledby", "modal-title").Aria("describedby", "modal-description").Body(
 				app.Header().Class("p-modal__header").Body(
-					app.H2().Class("p-modal__title").ID("modal-title").Text("How to play"),
-					app.Button().Class("p-modal__close").Aria("label", "Close active modal").Aria("controls", "modal").OnClick(w.closeHowToModal),
+					app.H2().Class("p-modal__title").ID("modal-title").Text(w.t("howto.heading")),
+					app.Button().Class("p-modal__close").Aria("label", w.t("modal.close")).Aria("controls", "modal").OnClick(w.closeHowToModal),
 				),
 				app.Div().Class("p-heading-icon--small").Body(
 					app.Aside().Class("p-accordion").Body(
 						app.Ul().Class("p-accordion__list").Body(
 							app.Li().Class("p-accordion__group").Body(
 								app.Div().Role("heading").Aria("level", "3").Class("p-accordion__heading").Body(
-									app.Button().Type("button").Class("p-accordion__tab").ID("tab1").Aria("controls", "tab1-section").Aria("expanded", true).Text("What is Cyber Witness").Value("tab1-section").OnClick(w.toggleAccordion),
+									app.Button().Type("button").Class("p-accordion__tab").ID("tab1").Aria("controls", "tab1-section").Aria("expanded", true).Text(w.t("howto.what")).Value("tab1-section").OnClick(w.toggleAccordion),
 								),
 								app.Section().Class("p-accordion__panel").ID("tab1-section").Aria("hidden", false).Aria("labelledby", "tab1").Body(
-									app.P().Text("Cyber Witness is a p2p media simulator based on the reporter and witnesses concept."),
+									app.P().Text(w.t("howto.whatText")),
 								),
 							),
 							app.Li().Class("p-accordion__group").Body(
 								app.Div().Role("heading").Aria("level", "3").Class("p-accordion__heading").Body(
-									app.Button().Type("button").Class("p-accordion__tab").ID("tab2").Aria("controls", "tab2-section").Aria("expanded", true).Text("What's the problem with mass media?").Value("tab2-section").OnClick(w.toggleAccordion),
+									app.Button().Type("button").Class("p-accordion__tab").ID("tab2").Aria("controls", "tab2-section").Aria("expanded", true).Text(w.t("howto.problem")).Value("tab2-section").OnClick(w.toggleAccordion),
 								),
 								app.Section().Class("p-accordion__panel").ID("tab2-section").Aria("hidden", true).Aria("labelledby", "tab1").Body(
-									app.P().Text("It's centralized, censored, fact-checked, unaccountable and non-trasparent."),
+									app.P().Text(w.t("howto.problemText")),
 								),
 							),
 							app.Li().Class("p-accordion__group").Body(
 								app.Div().Role("heading").Aria("level", "3").Class("p-accordion__heading").Body(
-									app.Button().Type("button").Class("p-accordion__tab").ID("tab3").Aria("controls", "tab3-section").Aria("expanded", true).Text("How Cyber Witness replaces mass media").Value("tab3-section").OnClick(w.toggleAccordion),
+									app.Button().Type("button").Class("p-accordion__tab").ID("tab3").Aria("controls", "tab3-section").Aria("expanded", true).Text(w.t("howto.replace")).Value("tab3-section").OnClick(w.toggleAccordion),
 								),
 								app.Section().Class("p-accordion__panel").ID("tab3-section").Aria("hidden", true).Aria("labelledby", "tab3").Body(
-									app.P().Text("By switching to p2p interactions and the reporter and witnesses model we emulate a transparent environment with a feedback loop."),
+									app.P().Text(w.t("howto.replaceText")),
 								),
 							),
 							app.Li().Class("p-accordion__group").Body(
 								app.Div().Role("heading").Aria("level", "3").Class("p-accordion__heading").Body(
-									app.Button().Type("button").Class("p-accordion__tab").ID("tab4").Aria("controls", "tab4-section").Aria("expanded", true).Text("Features").Value("tab4-section").OnClick(w.toggleAccordion),
+									app.Button().Type("button").Class("p-accordion__tab").ID("tab4").Aria("controls", "tab4-section").Aria("expanded", true).Text(w.t("howto.features")).Value("tab4-section").OnClick(w.toggleAccordion),
 								),
 								app.Section().Class("p-accordion__panel").ID("tab4-section").Aria("hidden", true).Aria("labelledby", "tab3").Body(
 									app.Ul().Class("p-matrix").Body(
 										app.Li().Class("p-matrix__item").Body(
 											app.Div().Class("p-matrix__content").Body(
-												app.H3().Class("p-matrix__title").Text("Report events"),
+												app.H3().Class("p-matrix__title").Text(w.t("feature.report")),
 												app.Div().Class("p-matrix__desc").Body(
-													app.P().Text("Provide details about the event."),
+													app.P().Text(w.t("feature.reportText")),
 												),
 											),
 										),
 										app.Li().Class("p-matrix__item").Body(
 											app.Div().Class("p-matrix__content").Body(
-												app.H3().Class("p-matrix__title").Text("Confirm rumors"),
+												app.H3().Class("p-matrix__title").Text(w.t("witness.confirmRumors")),
 												app.Div().Class("p-matrix__desc").Body(
-													app.P().Text("Browse reported events, confirm what you have witnessed and provide more details."),
+													app.P().Text(w.t("feature.confirmText")),
 												),
 											),
 										),
 										app.Li().Class("p-matrix__item").Body(
 											app.Div().Class("p-matrix__content").Body(
-												app.H3().Class("p-matrix__title").Text("Event details aggregation"),
+												app.H3().Class("p-matrix__title").Text(w.t("feature.aggregation")),
 												app.Div().Class("p-matrix__desc").Body(
-													app.P().Text("The more witnesses the better the accuracy the higher the chance a rumor is real news."),
+													app.P().Text(w.t("feature.aggregationText")),
 												),
 											),
 										),
 										app.Li().Class("p-matrix__item").Body(
 											app.Div().Class("p-matrix__content").Body(
-												app.H3().Class("p-matrix__title").Text("Read the real news"),
+												app.H3().Class("p-matrix__title").Text(w.t("feature.read")),
 												app.Div().Class("p-matrix__desc").Body(
-													app.P().Text("Your personal news feed at your fingertips. All witnessed. No ads, paywalls, censorship or fact checkers."),
+													app.P().Text(w.t("reader.intro")),
 												),
 											),
 										),
 										app.Li().Class("p-matrix__item").Body(
 											app.Div().Class("p-matrix__content").Body(
-												app.H3().Class("p-matrix__title").Text("Anonymity by default"),
+												app.H3().Class("p-matrix__title").Text(w.t("feature.anonymity")),
 												app.Div().Class("p-matrix__desc").Body(
-													app.P().Text("Anonymity guarantees everyone is protected."),
+													app.P().Text(w.t("feature.anonymityText")),
 												),
 											),
 										),
 										app.Li().Class("p-matrix__item").Body(
 											app.Div().Class("p-matrix__content").Body(
-												app.H3().Class("p-matrix__title").Text("Flat interactions"),
+												app.H3().Class("p-matrix__title").Text(w.t("feature.flat")),
 												app.Div().Class("p-matrix__desc").Body(
-													app.P().Text("No centralized control, no fact checkers and no ads."),
+													app.P().Text(w.t("feature.flatText")),
 												),
 											),
 										),
@@ -523,7 +700,7 @@ func (w *witness) Render() app.UI {
 							),
 							app.Li().Class("p-accordion__group").Body(
 								app.Div().Role("heading").Aria("level", "3").Class("p-accordion__heading").Body(
-									app.Button().Type("button").Class("p-accordion__tab").ID("tab5").Aria("controls", "tab5-section").Aria("expanded", true).Text("Support us").Value("tab5-section").OnClick(w.toggleAccordion),
+									app.Button().Type("button").Class("p-accordion__tab").ID("tab5").Aria("controls", "tab5-section").Aria("expanded", true).Text(w.t("howto.support")).Value("tab5-section").OnClick(w.toggleAccordion),
 								),
 								app.Section().Class("p-accordion__panel").ID("tab5-section").Aria("hidden", true).Aria("labelledby", "tab5").Body(
 									app.A().Href("https://opencollective.com/stateless-minds-collective").Text("https://opencollective.com/stateless-minds-collective"),
@@ -531,39 +708,39 @@ func (w *witness) Render() app.UI {
 							),
 							app.Li().Class("p-accordion__group").Body(
 								app.Div().Role("heading").Aria("level", "3").Class("p-accordion__heading").Body(
-									app.Button().Type("button").Class("p-accordion__tab").ID("tab6").Aria("controls", "tab6-section").Aria("expanded", true).Text("Terms of service").Value("tab6-section").OnClick(w.toggleAccordion),
+									app.Button().Type("button").Class("p-accordion__tab").ID("tab6").Aria("controls", "tab6-section").Aria("expanded", true).Text(w.t("terms.heading")).Value("tab6-section").OnClick(w.toggleAccordion),
 								),
 								app.Section().Class("p-accordion__panel").ID("tab6-section").Aria("hidden", true).Aria("labelledby", "tab6").Body(
 									app.Div().Class("p-card").Body(
-										app.H3().Text("Introduction"),
-										app.P().Class("p-card__content").Text("Cyber Witness is a p2p media simulator based on the reporter and witnesses concept in the form of a fictional game based on real-time data. By using the application you are implicitly agreeing to share your peer id with the IPFS public network."),
+										app.H3().Text(w.t("terms.intro")),
+										app.P().Class("p-card__content").Text(w.t("terms.introText")),
 									),
 									app.Div().Class("p-card").Body(
-										app.H3().Text("Application Hosting"),
-										app.P().Class("p-card__content").Text("Cyber Witness is a decentralized application and is hosted on a public peer to peer network. By using the application you agree to host it on the public IPFS network free of charge for as long as your usage is."),
+										app.H3().Text(w.t("terms.hosting")),
+										app.P().Class("p-card__content").Text(w.t("terms.hostingText")),
 									),
 									app.Div().Class("p-card").Body(
-										app.H3().Text("User-Generated Content"),
-										app.P().Class("p-card__content").Text("All published content is user-generated, fictional and creators are not responsible for it."),
+										app.H3().Text(w.t("terms.content")),
+										app.P().Class("p-card__content").Text(w.t("terms.contentText")),
 									),
 								),
 							),
 							app.Li().Class("p-accordion__group").Body(
 								app.Div().Role("heading").Aria("level", "3").Class("p-accordion__heading").Body(
-									app.Button().Type("button").Class("p-accordion__tab").ID("tab7").Aria("controls", "tab7-section").Aria("expanded", true).Text("Privacy policy").Value("tab7-section").OnClick(w.toggleAccordion),
+									app.Button().Type("button").Class("p-accordion__tab").ID("tab7").Aria("controls", "tab7-section").Aria("expanded", true).Text(w.t("privacy.heading")).Value("tab7-section").OnClick(w.toggleAccordion),
 								),
 								app.Section().Class("p-accordion__panel").ID("tab7-section").Aria("hidden", true).Aria("labelledby", "tab7").Body(
 									app.Div().Class("p-card").Body(
-										app.H3().Text("Personal data"),
-										app.P().Class("p-card__content").Text("There is no personal information collected within Cyber Witness. We store a small portion of your peer ID encrypted as a non-unique identifier which is used for displaying the ranks interface."),
+										app.H3().Text(w.t("privacy.data")),
+										app.P().Class("p-card__content").Text(w.t("privacy.dataText")),
 									),
 									app.Div().Class("p-card").Body(
-										app.H3().Text("Coookies"),
-										app.P().Class("p-card__content").Text("Cyber Witness does not use cookies."),
+										app.H3().Text(w.t("privacy.cookies")),
+										app.P().Class("p-card__content").Text(w.t("privacy.cookiesText")),
 									),
 									app.Div().Class("p-card").Body(
-										app.H3().Text("Changes to this privacy policy"),
-										app.P().Class("p-card__content").Text("This Privacy Policy might be updated from time to time. Thus, it is advised to review this page periodically for any changes. You will be notified of any changes from this page. Changes are effective immediately after they are posted on this page."),
+										app.H3().Text(w.t("privacy.changes")),
+										app.P().Class("p-card__content").Text(w.t("privacy.changesText")),
 									),
 								),
 							),
@@ -587,130 +764,125 @@ func (w *witness) onEventLocation(ctx app.Context, e app.Event) {
 	w.eventLocation = ctx.JSSrc().Get("value").String()
 }
 
+func (w *witness) onEventEvidence(ctx app.Context, e app.Event) {
+	w.evidence = nil
+	readFile(ctx, ctx.JSSrc(), func(b []byte) {
+		w.evidence = b
+	})
+}
+
 func (w *witness) onSubmitEvent(ctx app.Context, e app.Event) {
-	lastSolutionID := 0
-	unique := true
-	for n, ev := range w.events {
-		if w.eventTitle == ev.Title {
-			unique = false
+	event, ok := w.newReport(time.Now())
+	if !ok {
+		return
+	}
+	evidence := w.evidence
+
+	ctx.Async(func() {
+		_, err := w.submitReport(event, evidence)
+		if err != nil {
+			ctx.Dispatch(func(ctx app.Context) {
+				w.createNotification(ctx, NotificationDanger, w.t(ErrorHeader), w.t("event.createFailed"))
+			})
+			log.Println(err)
+			return
 		}
 
-		if n > 0 {
-			currentID, err := strconv.Atoi(ev.ID)
-			if err != nil {
-				log.Fatal(err)
-			}
-			previousID, err := strconv.Atoi(w.events[n-1].ID)
-			if err != nil {
-				log.Fatal(err)
-			}
-			if currentID > previousID {
-				lastSolutionID = currentID
-			}
-		} else {
-			lastSolutionID = 1
+		ctx.Dispatch(func(ctx app.Context) {
+			w.evidence = nil
+			w.watchEvent(ctx, event.ID)
+			w.createNotification(ctx, NotificationSuccess, w.t(SuccessHeader), w.t("event.submitted"))
+		})
+	})
+}
+
+// newReport builds an event from the report form. It returns false when an
+// event with the same title has already been reported.
+func (w *witness) newReport(now time.Time) (Event, bool) {
+	for _, ev := range w.events {
+		if w.eventTitle == ev.Title {
+			return Event{}, false
 		}
 	}
 
-	if unique {
-		event := Event{
-			ID:       strconv.Itoa(lastSolutionID + 1),
-			Type:     eventType,
-			Title:    w.eventTitle,
-			Location: w.eventLocation,
-			Reporter: w.citizenID,
-		}
+	event := Event{
+		// archived events are not loaded, so IDs can't be derived from
+		// the ones in memory without reusing an archived one
+		ID:        strconv.FormatInt(now.UnixNano(), 10),
+		Type:      eventType,
+		Channel:   w.channel.id(),
+		Title:     w.eventTitle,
+		Location:  w.eventLocation,
+		Reporter:  w.citizenID,
+		CreatedAt: now.Unix(),
+		Language:  w.reportLanguage(),
+		Tags:      parseTags(w.eventTagInput),
+		// only the holder of this key may retract the report
+		ReporterKey: signerID(w.key),
+	}
 
-		event.Details = append(event.Details, w.eventDetails)
+	event.Details = append(event.Details, Detail{Text: w.eventDetails, Author: w.citizenID, Language: event.Language})
+	return w.logged(event, opReport, "", now), true
+}
 
-		ev, err := json.Marshal(event)
+// submitReport uploads the evidence of a new event and publishes it.
+func (w *witness) submitReport(event Event, evidence []byte) (Event, error) {
+	if evidence != nil {
+		cid, err := w.sh.Add(bytes.NewReader(evidence))
 		if err != nil {
-			log.Fatal(err)
+			return event, err
 		}
-
-		ctx.Async(func() {
-			err = w.sh.OrbitDocsPut(dbNameEvent, ev)
-			if err != nil {
-				ctx.Dispatch(func(ctx app.Context) {
-					w.createNotification(ctx, NotificationDanger, ErrorHeader, "Could not create event. Try again later.")
-					log.Fatal(err)
-				})
-			}
-			err = w.sh.PubSubPublish(topicCreateEvent, string(ev))
-			if err != nil {
-				log.Fatal(err)
-			}
-
-			ctx.Dispatch(func(ctx app.Context) {
-				w.createNotification(ctx, NotificationSuccess, SuccessHeader, "Event submited.")
-			})
-		})
+		event.Evidence = append(event.Evidence, cid)
 	}
+
+	return w.putEvent(event, topicCreateEvent)
 }
 
 func (w *witness) onAddDetails(ctx app.Context, e app.Event) {
 	id := ctx.JSSrc().Get("value").String()
-	idInt, err := strconv.Atoi(id)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	// add new details to slice
-	w.events[idInt-1].Details = append(w.events[idInt-1].Details, w.eventDetails)
-
-	ev, err := json.Marshal(w.events[idInt-1])
-	if err != nil {
-		log.Fatal(err)
+	event, ok := w.withDetails(id, Detail{Text: w.eventDetails, Author: w.citizenID, Language: w.reportLanguage()}, time.Now())
+	if !ok {
+		return
 	}
 
 	ctx.Async(func() {
-		err = w.sh.OrbitDocsPut(dbNameEvent, ev)
+		event, err := w.putEvent(event, topicUpdateEvent)
 		if err != nil {
 			ctx.Dispatch(func(ctx app.Context) {
-				w.createNotification(ctx, NotificationDanger, ErrorHeader, "Could not add details. Try again later.")
-				log.Fatal(err)
+				w.createNotification(ctx, NotificationDanger, w.t(ErrorHeader), w.t("event.detailsFailed"))
 			})
-		}
-		err = w.sh.PubSubPublish(topicUpdateEvent, string(ev))
-		if err != nil {
-			log.Fatal(err)
+			log.Println(err)
+			return
 		}
 
 		ctx.Dispatch(func(ctx app.Context) {
-			w.createNotification(ctx, NotificationSuccess, SuccessHeader, "Event details added.")
+			if n := w.eventIndex(id); n >= 0 {
+				w.events[n] = event
+			}
+			w.createNotification(ctx, NotificationSuccess, w.t(SuccessHeader), w.t("event.detailsAdded"))
 		})
 	})
 }
 
-func (w *witness) createNotification(ctx app.Context, s NotificationStatus, h string, msg string) {
-	w.notificationID++
-	w.notifications[strconv.Itoa(w.notificationID)] = notification{
-		id:      w.notificationID,
-		status:  string(s),
-		header:  h,
-		message: msg,
+// withDetails returns the event with the given ID with d added to its
+// details.
+func (w *witness) withDetails(id string, d Detail, now time.Time) (Event, bool) {
+	n := w.eventIndex(id)
+	if n < 0 {
+		return Event{}, false
 	}
 
-	ntfs := w.notifications
-	ctx.Async(func() {
-		for n := range ntfs {
-			time.Sleep(5 * time.Second)
-			delete(ntfs, n)
-			ctx.Async(func() {
-				ctx.Dispatch(func(ctx app.Context) {
-					w.notifications = ntfs
-				})
-			})
-		}
-	})
+	event := w.events[n]
+	event.Details = append(event.Details[:len(event.Details):len(event.Details)], d)
+	return w.logged(event, opDetail, d.Text, now), true
 }
 
 func (w *witness) openRumorsDialog(ctx app.Context, e app.Event) {
-	app.Window().GetElementByID("rumors-modal").Set("style", "display:flex")
+	ctx.Navigate(routeRumors)
 }
 
 func (w *witness) openNewsDialog(ctx app.Context, e app.Event) {
-	app.Window().GetElementByID("news-modal").Set("style", "display:flex")
+	ctx.Navigate(routeNews)
 }
 
 func (w *witness) openHowToDialog(ctx app.Context, e app.Event) {
@@ -718,11 +890,11 @@ func (w *witness) openHowToDialog(ctx app.Context, e app.Event) {
 }
 
 func (w *witness) closeRumorsModal(ctx app.Context, e app.Event) {
-	app.Window().GetElementByID("rumors-modal").Set("style", "display:none")
+	ctx.Navigate(routeHome)
 }
 
 func (w *witness) closeNewsModal(ctx app.Context, e app.Event) {
-	app.Window().GetElementByID("news-modal").Set("style", "display:none")
+	ctx.Navigate(routeHome)
 }
 
 func (w *witness) closeHowToModal(ctx app.Context, e app.Event) {
@@ -755,48 +927,97 @@ func (w *witness) expandDetails(ctx app.Context, e app.Event) {
 
 func (w *witness) confirmRumor(ctx app.Context, e app.Event) {
 	id := ctx.JSSrc().Get("value").String()
-	idInt, err := strconv.Atoi(id)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	event := w.events[idInt-1]
-
-	if w.citizenID != event.Reporter {
-		// increment confirmedBy counter
-		event.ConfirmedBy++
-		event.Witnesses = append(event.Witnesses, w.citizenID)
-		w.isWitness = true
-	} else {
-		// return if reporter somehow made a request
+	event, ok := w.confirmation(id, time.Now())
+	if !ok {
 		return
 	}
-
-	ev, err := json.Marshal(event)
-	if err != nil {
-		log.Fatal(err)
-	}
+	w.isWitness = true
 
 	ctx.Async(func() {
-		err = w.sh.OrbitDocsPut(dbNameEvent, ev)
+		event, err := w.putEvent(event, topicUpdateEvent)
 		if err != nil {
 			ctx.Dispatch(func(ctx app.Context) {
-				w.createNotification(ctx, NotificationDanger, ErrorHeader, "Could not confirm rumor. Try again later.")
-				log.Fatal(err)
+				w.createNotification(ctx, NotificationDanger, w.t(ErrorHeader), w.t("event.confirmFailed"))
 			})
-		}
-		err = w.sh.PubSubPublish(topicUpdateEvent, string(ev))
-		if err != nil {
-			log.Fatal(err)
+			log.Println(err)
+			return
 		}
 
 		ctx.Dispatch(func(ctx app.Context) {
-			w.events[idInt-1] = event
-			w.createNotification(ctx, NotificationSuccess, SuccessHeader, "Rumor confirmed.")
+			if n := w.eventIndex(id); n >= 0 {
+				w.events[n] = event
+			}
+			w.watchEvent(ctx, id)
+			w.createNotification(ctx, NotificationSuccess, w.t(SuccessHeader), w.t("event.rumorConfirmed"))
 		})
 	})
 }
 
+// confirmation returns the event with the given ID confirmed by the citizen.
+// It returns false for the reporter and for citizens who already confirmed
+// it.
+func (w *witness) confirmation(id string, now time.Time) (Event, bool) {
+	n := w.eventIndex(id)
+	if n < 0 {
+		return Event{}, false
+	}
+
+	event := w.events[n]
+	// return if reporter somehow made a request
+	if w.citizenID == event.Reporter || contains(event.Witnesses, w.citizenID) || contains(event.Disputes, w.citizenID) || !amendable(event) {
+		return event, false
+	}
+
+	// increment confirmedBy counter
+	event.ConfirmedBy++
+	event.Witnesses = append(event.Witnesses[:len(event.Witnesses):len(event.Witnesses)], w.citizenID)
+	if event.ConfirmedBy > 1 && event.NewsAt == 0 {
+		event.NewsAt = now.Unix()
+	}
+	return w.logged(event, opConfirm, "", now), true
+}
+
+// eventIndex returns the position of the event with the given ID in
+// w.events or -1 if it isn't loaded.
+func (w *witness) eventIndex(id string) int {
+	for n, e := range w.events {
+		if e.ID == id {
+			return n
+		}
+	}
+	return -1
+}
+
+// inFeed reports whether the citizen's mute list and feed filters leave e
+// in the rumors and news tables.
+func (w *witness) inFeed(e Event) bool {
+	return !w.mutes.hidesEvent(e) && !w.hidesLanguage(e) && !w.hidesTag(e) && !w.hidesState(e)
+}
+
+// inNews reports whether e shows up in the news table.
+func (w *witness) inNews(e Event) bool {
+	return e.state() == stateNews && w.inFeed(e)
+}
+
+func (w *witness) updateNoNews() {
+	w.noNews = true
+	for _, e := range w.events {
+		if e.state() == stateNews {
+			w.noNews = false
+		}
+	}
+}
+
+// sortEvents orders events from oldest to newest.
+func sortEvents(events []Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].CreatedAt != events[j].CreatedAt {
+			return events[i].CreatedAt < events[j].CreatedAt
+		}
+		return events[i].ID < events[j].ID
+	})
+}
+
 func (w *witness) toggleAccordion(ctx app.Context, e app.Event) {
 	id := ctx.JSSrc().Get("value").String()
 	attr := app.Window().GetElementByID(id).Get("attributes")
@@ -816,9 +1037,8 @@ func main() {
 	//
 	// This is done by calling the Route() function,  which tells go-app what
 	// component to display for a given path, on both client and server-side.
-	app.Route("/", func() app.Composer{
-		return &witness{}
-	})
+	// Every path shows the witness component, see registerRoutes.
+	registerRoutes()
 
 	// Once the routes set up, the next thing to do is to either launch the app
 	// or the server that serves the app.
@@ -833,6 +1053,15 @@ func main() {
 	// instructions.
 	app.RunWhenOnBrowser()
 
+	// Arguments run a command against the local node instead of serving the
+	// app.
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	// Finally, launching the server that serves the app is done by using the Go
 	// standard HTTP package.
 	//
@@ -848,9 +1077,22 @@ func main() {
 			"https://use.fontawesome.com/releases/v6.2.0/css/all.css",
 		},
 		Scripts: []string{},
+		Env: map[string]string{
+			envRumorTTL:        os.Getenv(envRumorTTL),
+			envNewsTTL:         os.Getenv(envNewsTTL),
+			envDigestName:      os.Getenv(envDigestName),
+			envDigestGateway:   os.Getenv(envDigestGateway),
+			envDigestPublisher: os.Getenv(envDigestPublisher),
+		},
 	})
 	http.Handle("/", withGz)
 
+	// The news are also followable from the fediverse when the server
+	// knows its public URL.
+	if err := startFederation(http.DefaultServeMux); err != nil {
+		log.Fatal(err)
+	}
+
 	if err := http.ListenAndServe(":7000", nil); err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formats of the daily digest.
+const (
+	formatHTML     = "html"
+	formatMarkdown = "md"
+)
+
+// defaultTopRumors is how many rumors a daily digest lists unless told
+// otherwise.
+const defaultTopRumors = 10
+
+// dailyDigest is a printable summary of the news and most confirmed rumors
+// of a period, as the news and rumors tables of the reader would show them.
+type dailyDigest struct {
+	// Language is the interface language of the headings.
+	Language string
+	Channels []string
+	From     time.Time
+	To       time.Time
+	News     []dailyEvent
+	Rumors   []dailyEvent
+}
+
+// dailyEvent is an event as listed in a daily digest, with the latest
+// corrections applied and muted details left out.
+type dailyEvent struct {
+	ID          string
+	Title       string
+	Location    string
+	Language    string
+	State       eventState
+	ConfirmedBy int
+	Corrected   bool
+	Tags        []string
+	Details     []string
+}
+
+// dailyDigest returns the digest of the news confirmed and the rumors
+// reported in channels between since and now. It applies the mute list and
+// feed filters of w like the news and rumors tables do. At most maxRumors
+// rumors are kept, the most confirmed first.
+func (w *witness) dailyDigest(events []Event, channels []channel, since, now time.Time, maxRumors int) dailyDigest {
+	d := dailyDigest{Language: w.language, From: since, To: now}
+	for _, c := range channels {
+		d.Channels = append(d.Channels, c.label(d.Language))
+	}
+
+	var news, rumors []Event
+	for _, e := range events {
+		if e.Type != eventType || !slices.Contains(channels, parseChannel(e.Channel)) {
+			continue
+		}
+		switch {
+		case w.inNews(e):
+			at := e.NewsAt
+			if at == 0 {
+				at = e.CreatedAt
+			}
+			if at >= since.Unix() && at <= now.Unix() {
+				news = append(news, e)
+			}
+		case e.state() != stateNews && w.inFeed(e):
+			if e.CreatedAt >= since.Unix() && e.CreatedAt <= now.Unix() {
+				rumors = append(rumors, e)
+			}
+		}
+	}
+
+	sort.SliceStable(news, func(i, j int) bool {
+		return news[i].NewsAt > news[j].NewsAt
+	})
+	sort.SliceStable(rumors, func(i, j int) bool {
+		if rumors[i].ConfirmedBy != rumors[j].ConfirmedBy {
+			return rumors[i].ConfirmedBy > rumors[j].ConfirmedBy
+		}
+		return rumors[i].CreatedAt > rumors[j].CreatedAt
+	})
+	if len(rumors) > maxRumors {
+		rumors = rumors[:maxRumors]
+	}
+
+	for _, e := range news {
+		d.News = append(d.News, w.dailyEvent(e))
+	}
+	for _, e := range rumors {
+		d.Rumors = append(d.Rumors, w.dailyEvent(e))
+	}
+	return d
+}
+
+func (w *witness) dailyEvent(e Event) dailyEvent {
+	c := e.corrected()
+	de := dailyEvent{
+		ID:          e.ID,
+		Title:       c.Title,
+		Location:    c.Location,
+		Language:    e.Language,
+		State:       e.state(),
+		ConfirmedBy: e.ConfirmedBy,
+		Corrected:   len(e.Corrections) > 0,
+		Tags:        e.Tags,
+	}
+	for _, dt := range e.Details {
+		if !w.mutes.hidesDetail(dt) {
+			de.Details = append(de.Details, dt.Text)
+		}
+	}
+	return de
+}
+
+// dailyFormat guesses the format of a daily digest from the name of the
+// file it is written to.
+func dailyFormat(name string) string {
+	if strings.HasSuffix(name, ".md") || strings.HasSuffix(name, ".markdown") {
+		return formatMarkdown
+	}
+	return formatHTML
+}
+
+// writeDaily renders d in format.
+func writeDaily(out io.Writer, format string, d dailyDigest) error {
+	switch format {
+	case formatHTML:
+		return writeDailyHTML(out, d)
+	case formatMarkdown:
+		return writeDailyMarkdown(out, d)
+	default:
+		return fmt.Errorf("unknown digest format %q, expected html or md", format)
+	}
+}
+
+// dailyTemplate renders a digest as a single HTML page with inline styles
+// and no scripts, so it prints well and survives being mailed or pinned.
+const dailyTemplate = `<!DOCTYPE html>
+<html lang="{{.Language}}">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Cyber Witness - {{t "daily.title"}} {{date .To}}</title>
+<style>
+body{font-family:Georgia,serif;max-width:44em;margin:2em auto;padding:0 1em;color:#111;line-height:1.5}
+h1{margin-bottom:0}
+h2{border-bottom:1px solid #999;margin-top:2em}
+h3{margin-bottom:.2em}
+.meta{color:#555;font-size:.9em;margin:0}
+.label{border:1px solid #555;border-radius:.2em;font-size:.75em;padding:0 .3em;margin-left:.3em}
+ul{padding-left:1.2em}
+@media print{body{margin:0;max-width:none}}
+</style>
+</head>
+<body>
+<h1>Cyber Witness - {{t "daily.title"}}</h1>
+<p class="meta">{{date .From}} – {{date .To}} · {{join .Channels ", "}}</p>
+<h2>{{t "feed.news"}}</h2>
+{{range .News}}{{template "event" .}}{{else}}<p>{{t "feed.noNews"}}</p>
+{{end}}<h2>{{t "daily.topRumors"}}</h2>
+{{range .Rumors}}{{template "event" .}}{{else}}<p>{{t "feed.noRumors"}}</p>
+{{end}}</body>
+</html>
+{{define "event"}}<article id="{{.ID}}" lang="{{.Language}}">
+<h3>{{.Title}}{{if and (ne .State "news") (ne .State "rumor")}}<span class="label">{{t (print "state." .State)}}</span>{{end}}{{if .Corrected}}<span class="label">{{t "event.corrected"}}</span>{{end}}</h3>
+<p class="meta">{{t "event.location"}}: {{.Location}} · {{t "feed.confirmedBy"}}: {{.ConfirmedBy}}{{if .Tags}} · {{join .Tags ", "}}{{end}}</p>
+{{if .Details}}<ul>
+{{range .Details}}<li>{{.}}</li>
+{{end}}</ul>
+{{end}}</article>
+{{end}}`
+
+func writeDailyHTML(out io.Writer, d dailyDigest) error {
+	tmpl, err := template.New("daily").Funcs(dailyFuncs(d.Language)).Parse(dailyTemplate)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(out, d)
+}
+
+func dailyFuncs(lang string) template.FuncMap {
+	return template.FuncMap{
+		"t":    func(id string) string { return translate(lang, id) },
+		"date": dailyDate,
+		"join": strings.Join,
+	}
+}
+
+// dailyDate formats the bounds of a digest's period.
+func dailyDate(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04 UTC")
+}
+
+func writeDailyMarkdown(out io.Writer, d dailyDigest) error {
+	t := func(id string) string { return translate(d.Language, id) }
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Cyber Witness - %s\n\n", t("daily.title"))
+	fmt.Fprintf(&b, "%s – %s · %s\n", dailyDate(d.From), dailyDate(d.To), markdownEscape(strings.Join(d.Channels, ", ")))
+
+	sections := []struct {
+		heading string
+		empty   string
+		events  []dailyEvent
+	}{
+		{t("feed.news"), t("feed.noNews"), d.News},
+		{t("daily.topRumors"), t("feed.noRumors"), d.Rumors},
+	}
+	for _, s := range sections {
+		fmt.Fprintf(&b, "\n## %s\n\n", s.heading)
+		if len(s.events) == 0 {
+			fmt.Fprintf(&b, "%s\n", s.empty)
+		}
+		for _, e := range s.events {
+			b.WriteString("### " + markdownEscape(e.Title))
+			if e.State != stateNews && e.State != stateRumor {
+				b.WriteString(" [" + t("state."+string(e.State)) + "]")
+			}
+			if e.Corrected {
+				b.WriteString(" [" + t("event.corrected") + "]")
+			}
+			b.WriteString("\n\n")
+			fmt.Fprintf(&b, "%s: %s · %s: %s", t("event.location"), markdownEscape(e.Location), t("feed.confirmedBy"), strconv.Itoa(e.ConfirmedBy))
+			if len(e.Tags) > 0 {
+				b.WriteString(" · " + markdownEscape(strings.Join(e.Tags, ", ")))
+			}
+			b.WriteString("\n\n")
+			for _, dt := range e.Details {
+				b.WriteString("- " + markdownEscape(dt) + "\n")
+			}
+			if len(e.Details) > 0 {
+				b.WriteString("\n")
+			}
+		}
+	}
+
+	_, err := io.WriteString(out, strings.TrimRight(b.String(), "\n")+"\n")
+	return err
+}
+
+// markdownEscape keeps reported text from being read as Markdown or HTML
+// and from breaking out of its line.
+func markdownEscape(s string) string {
+	var b strings.Builder
+	for _, r := range strings.Join(strings.Fields(s), " ") {
+		if strings.ContainsRune("\\`*_[]<>#|~", r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func dailyEvents() []Event {
+	return []Event{
+		{ID: "1", Type: eventType, Title: "Fire <b>downtown</b>", Location: "Main St", ConfirmedBy: 2, Witnesses: []string{"11", "12"}, CreatedAt: 1000, NewsAt: 1500,
+			Details: []Detail{{Text: "smoke", Author: "11"}, {Text: "spam", Author: "66"}}},
+		{ID: "2", Type: eventType, Title: "Old news", ConfirmedBy: 3, Witnesses: []string{"11", "12", "13"}, CreatedAt: 10, NewsAt: 20},
+		{ID: "3", Type: eventType, Title: "Protest", ConfirmedBy: 1, Witnesses: []string{"12"}, CreatedAt: 1100},
+		{ID: "4", Type: eventType, Title: "Traffic jam", CreatedAt: 1200},
+		{ID: "5", Type: eventType, Title: "Parade", CreatedAt: 1300, Reporter: "66"},
+		{ID: "6", Type: archivedType, Title: "Archived", ConfirmedBy: 2, Witnesses: []string{"11", "12"}, CreatedAt: 1000, NewsAt: 1600},
+		{ID: "7", Type: eventType, Title: "Elsewhere", ConfirmedBy: 2, Witnesses: []string{"11", "12"}, CreatedAt: 1000, NewsAt: 1700, Channel: channel{Region: "u0"}.id()},
+	}
+}
+
+func TestDailyDigestFiltersLikeTheFeeds(t *testing.T) {
+	w := &witness{mutes: muteList{Citizens: []string{"66"}}}
+	d := w.dailyDigest(dailyEvents(), []channel{{}}, time.Unix(900, 0), time.Unix(2000, 0), 1)
+
+	if len(d.News) != 1 || d.News[0].ID != "1" {
+		t.Fatalf("news = %+v, want the news of the period only", d.News)
+	}
+	if len(d.News[0].Details) != 1 || d.News[0].Details[0] != "smoke" {
+		t.Errorf("details = %q, want the muted one left out", d.News[0].Details)
+	}
+	if len(d.Rumors) != 1 || d.Rumors[0].ID != "3" {
+		t.Errorf("rumors = %+v, want the most confirmed one", d.Rumors)
+	}
+
+	w.tagFilter = "weather"
+	if d := w.dailyDigest(dailyEvents(), []channel{{}}, time.Unix(900, 0), time.Unix(2000, 0), 10); len(d.News)+len(d.Rumors) != 0 {
+		t.Errorf("tag filter ignored: %+v", d)
+	}
+}
+
+func TestWriteDaily(t *testing.T) {
+	w := &witness{language: "es"}
+	d := w.dailyDigest(dailyEvents(), []channel{{}}, time.Unix(900, 0), time.Unix(2000, 0), 10)
+
+	var out bytes.Buffer
+	if err := writeDaily(&out, formatHTML, d); err != nil {
+		t.Fatal(err)
+	}
+	html := out.String()
+	for _, want := range []string{"Resumen diario", "Fire &lt;b&gt;downtown&lt;/b&gt;", "<li>smoke</li>", "Protest"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML digest lacks %q:\n%s", want, html)
+		}
+	}
+	if strings.Contains(html, "<script") || strings.Contains(html, "<b>") {
+		t.Errorf("HTML digest has markup from events or scripts:\n%s", html)
+	}
+
+	out.Reset()
+	if err := writeDaily(&out, formatMarkdown, d); err != nil {
+		t.Fatal(err)
+	}
+	md := out.String()
+	for _, want := range []string{"## Noticias", "### Fire \\<b\\>downtown\\</b\\>", "- smoke\n"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Markdown digest lacks %q:\n%s", want, md)
+		}
+	}
+}
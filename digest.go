@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+	shell "github.com/stateless-minds/go-ipfs-api"
+)
+
+// Environment variables telling the app where to find a news digest when
+// the live network is out of reach: the IPNS name it is published under,
+// the gateway to load it from and, optionally, the signer the digest must
+// come from.
+const (
+	envDigestName      = "DIGEST_NAME"
+	envDigestGateway   = "DIGEST_GATEWAY"
+	envDigestPublisher = "DIGEST_PUBLISHER"
+)
+
+// defaultDigestGateway is used when the server doesn't name a gateway.
+const defaultDigestGateway = "https://ipfs.io"
+
+const (
+	// digestVersion is the version of the digest format.
+	digestVersion = 1
+	// maxDigestEvents bounds the news of a digest, the latest are kept.
+	maxDigestEvents = 200
+	// maxDigestSize bounds the digest a client downloads.
+	maxDigestSize = 8 << 20
+	// defaultDigestKey is the name of the IPNS key a digest is published
+	// under unless another one is given.
+	defaultDigestKey = "cyber-witness-digest"
+	// digestLifetime is how long a published digest record stays valid.
+	digestLifetime = 48 * time.Hour
+)
+
+var errBadDigest = errors.New("digest is invalid")
+
+// digest is a signed snapshot of the confirmed news of some channels. Every
+// event keeps its own signature, so clients check both the publisher and
+// each report.
+type digest struct {
+	Version     int      `json:"version"`
+	Channels    []string `json:"channels"`
+	GeneratedAt int64    `json:"generatedAt"`
+	Events      []Event  `json:"events"`
+	Publisher   string   `json:"publisher"`
+	Signature   string   `json:"signature,omitempty"`
+}
+
+// buildDigest returns the unsigned digest of the news among events that
+// belong to channels, newest first.
+func buildDigest(events []Event, channels []channel, now time.Time) digest {
+	d := digest{Version: digestVersion, GeneratedAt: now.Unix(), Events: []Event{}}
+	for _, c := range channels {
+		d.Channels = append(d.Channels, c.id())
+	}
+
+	for _, e := range events {
+		if e.Type != eventType || e.state() != stateNews || !contains(d.Channels, parseChannel(e.Channel).id()) {
+			continue
+		}
+		d.Events = append(d.Events, e)
+	}
+
+	sort.SliceStable(d.Events, func(i, j int) bool {
+		if d.Events[i].NewsAt != d.Events[j].NewsAt {
+			return d.Events[i].NewsAt > d.Events[j].NewsAt
+		}
+		return d.Events[i].CreatedAt > d.Events[j].CreatedAt
+	})
+	if len(d.Events) > maxDigestEvents {
+		d.Events = d.Events[:maxDigestEvents]
+	}
+	return d
+}
+
+// signDigest signs d as its publisher.
+func signDigest(d digest, key ed25519.PrivateKey) (digest, error) {
+	d.Publisher = signerID(key)
+	d.Signature = ""
+	sig, err := signStatement(key, "", "digest", d)
+	if err != nil {
+		return d, err
+	}
+	d.Signature = sig
+	return d, nil
+}
+
+// verifyDigest checks the signature of d and, when publisher isn't empty,
+// that it comes from that signer.
+func verifyDigest(d digest, publisher string) error {
+	if d.Version != digestVersion {
+		return fmt.Errorf("%w: unknown version %d", errBadDigest, d.Version)
+	}
+	if publisher != "" && d.Publisher != publisher {
+		return fmt.Errorf("%w: published by %s", errBadDigest, d.Publisher)
+	}
+
+	sig := d.Signature
+	d.Signature = ""
+	if err := verifyStatement(d.Publisher, sig, "", "digest", d); err != nil {
+		return fmt.Errorf("%w: %v", errBadDigest, err)
+	}
+	return nil
+}
+
+// digestKey returns the IPNS key called name, creating it if the node
+// doesn't have it yet.
+func digestKey(sh *shell.Shell, name string) (string, error) {
+	keys, err := sh.KeyList(context.Background())
+	if err != nil {
+		return "", err
+	}
+	for _, k := range keys {
+		if k.Name == name {
+			return k.Id, nil
+		}
+	}
+
+	k, err := sh.KeyGen(context.Background(), name)
+	if err != nil {
+		return "", err
+	}
+	return k.Id, nil
+}
+
+// publishDigest builds and signs the digest of channels, adds it to the
+// node behind sh and points the IPNS name of keyName at it. ttl tells
+// resolvers how long to cache the record, zero keeps the node's default.
+func publishDigest(sh *shell.Shell, key ed25519.PrivateKey, keyName string, channels []channel, ttl time.Duration, now time.Time) (*shell.PublishResponse, digest, error) {
+	events, err := loadAllEvents(sh, channels)
+	if err != nil {
+		return nil, digest{}, err
+	}
+
+	d, err := signDigest(buildDigest(events, channels, now), key)
+	if err != nil {
+		return nil, d, err
+	}
+
+	b, err := json.Marshal(d)
+	if err != nil {
+		return nil, d, err
+	}
+	cid, err := sh.Add(bytes.NewReader(b))
+	if err != nil {
+		return nil, d, err
+	}
+
+	_, err = digestKey(sh, keyName)
+	if err != nil {
+		return nil, d, err
+	}
+	res, err := sh.PublishWithDetails("/ipfs/"+cid, keyName, digestLifetime, ttl, false)
+	return res, d, err
+}
+
+// fetchDigest downloads the digest published under an IPNS name from a
+// gateway and verifies it.
+func fetchDigest(client *http.Client, gateway, name, publisher string) (digest, error) {
+	var d digest
+	res, err := client.Get(strings.TrimSuffix(gateway, "/") + "/ipns/" + name)
+	if err != nil {
+		return d, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return d, fmt.Errorf("gateway answered %s", res.Status)
+	}
+
+	b, err := io.ReadAll(io.LimitReader(res.Body, maxDigestSize+1))
+	if err != nil {
+		return d, err
+	}
+	if len(b) > maxDigestSize {
+		return d, fmt.Errorf("%w: larger than %d bytes", errBadDigest, maxDigestSize)
+	}
+
+	err = json.Unmarshal(b, &d)
+	if err != nil {
+		return d, fmt.Errorf("%w: %v", errBadDigest, err)
+	}
+	return d, verifyDigest(d, publisher)
+}
+
+// applyDigest adds the news of d to the current channel like events
+// recovered by sync, so a digest never overrides what the peer knows. It
+// returns how many events it added or changed.
+func (w *witness) applyDigest(d digest) int {
+	changed := 0
+	for _, e := range d.Events {
+		if w.receiveSynced(e) {
+			changed++
+		}
+	}
+	if d.GeneratedAt > w.digestAt {
+		w.digestAt = d.GeneratedAt
+	}
+	return changed
+}
+
+// digestConfigured reports whether the server named a digest to fall back
+// on.
+func digestConfigured() bool {
+	return app.Getenv(envDigestName) != ""
+}
+
+// loadDigest fetches the configured digest and shows its news of the
+// current channel.
+func (w *witness) loadDigest(ctx app.Context) {
+	gateway := app.Getenv(envDigestGateway)
+	if gateway == "" {
+		gateway = defaultDigestGateway
+	}
+	c := w.channel
+
+	ctx.Async(func() {
+		d, err := fetchDigest(http.DefaultClient, gateway, app.Getenv(envDigestName), app.Getenv(envDigestPublisher))
+
+		ctx.Dispatch(func(ctx app.Context) {
+			if c != w.channel {
+				return
+			}
+			if err != nil {
+				w.createNotification(ctx, NotificationDanger, w.t(ErrorHeader), w.t("digest.loadFailed"))
+				log.Println(err)
+				return
+			}
+
+			n := w.applyDigest(d)
+			w.scheduleCacheSave(ctx)
+			generated := time.Unix(d.GeneratedAt, 0).Format("2006-01-02 15:04")
+			w.createNotification(ctx, NotificationInfo, w.t("digest.header"), strconv.Itoa(n)+" "+w.t("digest.loaded")+" "+generated+".")
+		})
+	})
+}
+
+func (w *witness) onLoadDigest(ctx app.Context, e app.Event) {
+	w.loadDigest(ctx)
+}
+
+// renderDigestState shows when the digest the feeds fell back on was
+// generated.
+func (w *witness) renderDigestState() app.UI {
+	return app.If(w.digestAt > 0, func() app.UI {
+		generated := time.Unix(w.digestAt, 0).Format("2006-01-02 15:04")
+		return app.Span().Class("p-status-label--information").Text(w.t("digest.from") + " " + generated)
+	})
+}
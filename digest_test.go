@@ -0,0 +1,115 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	shell "github.com/stateless-minds/go-ipfs-api"
+)
+
+func TestBuildDigest(t *testing.T) {
+	local := channel{Region: "u0"}
+	events := []Event{
+		{ID: "1", Type: eventType, ConfirmedBy: 2, Witnesses: []string{"11", "12"}, NewsAt: 100},
+		{ID: "2", Type: eventType, ConfirmedBy: 1},
+		{ID: "3", Type: eventType, ConfirmedBy: 3, Witnesses: []string{"11", "12", "13"}, NewsAt: 300},
+		{ID: "4", Type: archivedType, ConfirmedBy: 2, Witnesses: []string{"11", "12"}, NewsAt: 400},
+		{ID: "5", Type: eventType, ConfirmedBy: 2, Witnesses: []string{"11", "12"}, NewsAt: 500, Disputes: []string{"20", "21"}},
+		{ID: "6", Type: eventType, ConfirmedBy: 2, Witnesses: []string{"11", "12"}, NewsAt: 600, Channel: local.id()},
+	}
+
+	d := buildDigest(events, []channel{{}}, time.Unix(1000, 0))
+	var ids []string
+	for _, e := range d.Events {
+		ids = append(ids, e.ID)
+	}
+	if len(ids) != 2 || ids[0] != "3" || ids[1] != "1" {
+		t.Errorf("digest of the global channel has events %v, want news 3 and 1", ids)
+	}
+}
+
+func TestDigestSignature(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(nil)
+	d, err := signDigest(buildDigest([]Event{{ID: "1", Type: eventType, ConfirmedBy: 2, Witnesses: []string{"11", "12"}}}, []channel{{}}, time.Unix(1000, 0)), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyDigest(d, ""); err != nil {
+		t.Errorf("signed digest: %v", err)
+	}
+	if err := verifyDigest(d, signerID(key)); err != nil {
+		t.Errorf("digest of the expected publisher: %v", err)
+	}
+
+	_, other, _ := ed25519.GenerateKey(nil)
+	if err := verifyDigest(d, signerID(other)); !errors.Is(err, errBadDigest) {
+		t.Errorf("digest of another publisher: %v, want %v", err, errBadDigest)
+	}
+
+	tampered := d
+	tampered.Events = append([]Event(nil), d.Events...)
+	tampered.Events[0].Title = "Nothing happened"
+	if err := verifyDigest(tampered, ""); !errors.Is(err, errBadDigest) {
+		t.Errorf("tampered digest: %v, want %v", err, errBadDigest)
+	}
+}
+
+func TestPublishAndFetchDigest(t *testing.T) {
+	n := newFakeNetwork(t)
+	node := n.addNode()
+	sh := shell.NewShell(node)
+	_, key, _ := ed25519.GenerateKey(nil)
+
+	news, err := storeEvent(sh, key, "10", Event{ID: "1", Type: eventType, Title: "Fire",
+		Witnesses: []string{"11", "12"}, ConfirmedBy: 2, CreatedAt: 100, NewsAt: 200}, topicUpdateEvent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = storeEvent(sh, key, "10", Event{ID: "2", Type: eventType, Title: "Smoke", CreatedAt: 300}, topicCreateEvent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, published, err := publishDigest(sh, key, defaultDigestKey, []channel{{}}, time.Minute, time.Unix(1000, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(published.Events) != 1 {
+		t.Fatalf("published %d events, want the news only", len(published.Events))
+	}
+
+	// republishing reuses the key and moves the name
+	again, _, err := publishDigest(sh, key, defaultDigestKey, []channel{{}}, time.Minute, time.Unix(2000, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Name != res.Name || again.Value == res.Value {
+		t.Errorf("republished %s -> %s, want %s moved from %s", again.Name, again.Value, res.Name, res.Value)
+	}
+
+	d, err := fetchDigest(http.DefaultClient, node+"/", res.Name, signerID(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.GeneratedAt != 2000 {
+		t.Errorf("fetched digest of %d, want the latest", d.GeneratedAt)
+	}
+
+	w := auditWitness(t, "30")
+	if got := w.applyDigest(d); got != 1 || w.eventIndex(news.ID) < 0 {
+		t.Errorf("applied %d events: %+v", got, w.events)
+	}
+	if w.digestAt != 2000 {
+		t.Errorf("digest time = %d, want 2000", w.digestAt)
+	}
+	if got := w.applyDigest(d); got != 0 {
+		t.Errorf("applying the digest again changed %d events", got)
+	}
+
+	if _, err := fetchDigest(http.DefaultClient, node, "unknown", ""); err == nil {
+		t.Error("fetched a digest of an unpublished name")
+	}
+}
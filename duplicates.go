@@ -0,0 +1,278 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+// mergedType replaces eventType on a duplicate report once it has been
+// merged. The document stays in the store so links to it can redirect to
+// the surviving event.
+const mergedType = "merged-event"
+
+// mergeQuorum is how many participants of each event must agree before two
+// reports are merged. Events with fewer participants need all of them.
+const mergeQuorum = 2
+
+// participants returns the citizens who took part in an event: its reporter
+// and its witnesses.
+func participants(e Event) []string {
+	return union([]string{e.Reporter}, e.Witnesses)
+}
+
+// mergeOrder returns the two reports of the same event as the one that
+// survives a merge and the duplicate merged into it. The earlier report
+// survives, so proposals can never form a cycle.
+func mergeOrder(a, b Event) (survivor, duplicate Event) {
+	if b.CreatedAt < a.CreatedAt || (b.CreatedAt == a.CreatedAt && b.ID < a.ID) {
+		return b, a
+	}
+	return a, b
+}
+
+// proposeMerge returns the duplicate of a and b carrying a proposal to merge
+// it into the other, agreed by citizen. Only participants of either event
+// can propose, and only one proposal can be pending on an event.
+func proposeMerge(a, b Event, citizen string) (Event, bool) {
+	survivor, duplicate := mergeOrder(a, b)
+	switch {
+	case a.ID == b.ID, a.Channel != b.Channel:
+		return duplicate, false
+	case duplicate.MergeInto != "", survivor.MergeInto != "":
+		return duplicate, false
+	case !contains(participants(survivor), citizen) && !contains(participants(duplicate), citizen):
+		return duplicate, false
+	}
+
+	duplicate.MergeInto = survivor.ID
+	duplicate.MergeVotes = []string{citizen}
+	return duplicate, true
+}
+
+// agreeMerge returns duplicate with the agreement of citizen to the pending
+// proposal. It returns false if citizen can't or already did agree.
+func agreeMerge(survivor, duplicate Event, citizen string) (Event, bool) {
+	if duplicate.MergeInto != survivor.ID || contains(duplicate.MergeVotes, citizen) {
+		return duplicate, false
+	}
+	if !contains(participants(survivor), citizen) && !contains(participants(duplicate), citizen) {
+		return duplicate, false
+	}
+
+	duplicate.MergeVotes = append(duplicate.MergeVotes[:len(duplicate.MergeVotes):len(duplicate.MergeVotes)], citizen)
+	return duplicate, true
+}
+
+// mergeAgreed reports whether enough participants of both events agreed to
+// merge duplicate into survivor.
+func mergeAgreed(survivor, duplicate Event) bool {
+	for _, e := range []Event{survivor, duplicate} {
+		p := participants(e)
+		votes := 0
+		for _, v := range duplicate.MergeVotes {
+			if contains(p, v) {
+				votes++
+			}
+		}
+		if votes < min(mergeQuorum, len(p)) {
+			return false
+		}
+	}
+	return true
+}
+
+// combineDuplicates merges duplicate into survivor. The survivor gains the
+// witnesses, details, evidence and tags of the duplicate, and its reporter
+// as a witness. Details keep their author and record the report they were
+// given on. The duplicate is returned marked as merged.
+func combineDuplicates(survivor, duplicate Event, now time.Time) (Event, Event) {
+	for _, d := range duplicate.Details {
+		if d.From == "" {
+			d.From = duplicate.ID
+		}
+		survivor.Details = append(survivor.Details[:len(survivor.Details):len(survivor.Details)], d)
+	}
+
+	witnesses := duplicate.Witnesses
+	if duplicate.Reporter != survivor.Reporter {
+		witnesses = union([]string{duplicate.Reporter}, witnesses)
+	}
+	survivor.Witnesses = union(survivor.Witnesses, witnesses)
+	survivor.Witnesses = removeString(survivor.Witnesses, survivor.Reporter)
+	survivor.ConfirmedBy = len(survivor.Witnesses)
+	if survivor.ConfirmedBy > 1 && survivor.NewsAt == 0 {
+		survivor.NewsAt = now.Unix()
+	}
+
+	survivor.Evidence = union(survivor.Evidence, duplicate.Evidence)
+	survivor.Tags = limitTags(union(survivor.Tags, duplicate.Tags))
+	survivor.MergedFrom = union(survivor.MergedFrom, append([]string{duplicate.ID}, duplicate.MergedFrom...))
+
+	duplicate.Type = mergedType
+	return survivor, duplicate
+}
+
+// removeString returns list without v.
+func removeString(list []string, v string) []string {
+	out := list[:0:0]
+	for _, l := range list {
+		if l != v {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// pendingMerges returns the loaded events proposed to be merged into the
+// event with the given ID.
+func (w *witness) pendingMerges(id string) []Event {
+	var events []Event
+	for _, e := range w.events {
+		if e.MergeInto == id && e.Type == eventType {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// submitMerge publishes a duplicate carrying a new agreement and, once the
+// merge is agreed, performs it.
+func (w *witness) submitMerge(ctx app.Context, survivor, duplicate Event) {
+	now := time.Now()
+	agreed := mergeAgreed(survivor, duplicate)
+	if agreed {
+		survivor, duplicate = combineDuplicates(survivor, duplicate, now)
+		survivor = w.logged(survivor, opMergedFrom, duplicate.ID, now)
+		duplicate = w.logged(duplicate, opMerge, survivor.ID, now)
+	}
+
+	ctx.Async(func() {
+		var err error
+		if agreed {
+			survivor, err = w.putEvent(survivor, topicUpdateEvent)
+		}
+		if err == nil {
+			duplicate, err = w.putEvent(duplicate, topicUpdateEvent)
+		}
+		if err != nil {
+			ctx.Dispatch(func(ctx app.Context) {
+				w.createNotification(ctx, NotificationDanger, w.t(ErrorHeader), w.t("duplicate.mergeFailed"))
+			})
+			log.Println(err)
+			return
+		}
+
+		ctx.Dispatch(func(ctx app.Context) {
+			if agreed {
+				w.receiveUpdate(survivor)
+				w.createNotification(ctx, NotificationSuccess, w.t(SuccessHeader), w.t("duplicate.merged")+" \""+survivor.Title+"\".")
+			} else {
+				w.createNotification(ctx, NotificationSuccess, w.t(SuccessHeader), w.t("duplicate.proposed"))
+			}
+			w.receiveUpdate(duplicate)
+		})
+	})
+}
+
+func (w *witness) renderMergeControls(e Event) app.UI {
+	pending := w.pendingMerges(e.ID)
+	// the survivor may have been archived in the meantime
+	target := Event{ID: e.MergeInto, Channel: e.Channel, Title: e.MergeInto}
+	if n := w.eventIndex(e.MergeInto); n >= 0 {
+		target = w.events[n]
+	}
+
+	return app.Div().Body(
+		app.If(e.MergeInto != "", func() app.UI {
+			return w.renderMergeProposal(target, e)
+		}),
+		app.Range(pending).Slice(func(n int) app.UI {
+			return w.renderMergeProposal(e, pending[n])
+		}),
+		app.If(e.MergeInto == "" && contains(participants(e), w.citizenID), func() app.UI {
+			return app.Div().Class("p-form p-form--inline").Body(
+				app.Div().Class("p-form__group").Body(
+					app.Label().For("merge-"+e.ID).Text(w.t("duplicate.sameAs")),
+					app.Select().ID("merge-"+e.ID).OnChange(w.onMergeTarget).Body(
+						app.Option().Value("").Text(w.t("duplicate.choose")),
+						app.Range(w.events).Slice(func(n int) app.UI {
+							o := w.events[n]
+							return app.If(o.ID != e.ID && o.MergeInto == "", func() app.UI {
+								return app.Option().Value(o.ID).Text(o.Title + " · " + o.Location).Selected(o.ID == w.mergeTarget)
+							})
+						}),
+					),
+				),
+				app.Button().Class("is-dense").Value(e.ID).Text(w.t("duplicate.propose")).OnClick(w.onProposeMerge),
+			)
+		}),
+	)
+}
+
+// renderMergeProposal shows the pending proposal to merge duplicate into
+// survivor and lets participants of either event agree.
+func (w *witness) renderMergeProposal(survivor, duplicate Event) app.UI {
+	canAgree := !contains(duplicate.MergeVotes, w.citizenID) &&
+		(contains(participants(survivor), w.citizenID) || contains(participants(duplicate), w.citizenID))
+
+	return app.Div().Class("p-notification--caution").Body(
+		app.Div().Class("p-notification__content").Body(
+			app.H5().Class("p-notification__title").Text(w.t("duplicate.heading")),
+			app.P().Class("p-notification__message").Body(
+				app.Text("\""+duplicate.Title+"\" "+w.t("duplicate.mergedInto")+" "),
+				app.A().Href(eventPath(survivor)).Text(survivor.Title),
+				app.Text(". "+strconv.Itoa(len(duplicate.MergeVotes))+" "+w.t("duplicate.agreed")+", "+strconv.Itoa(mergeQuorum)+" "+w.t("duplicate.quorum")+"."),
+			),
+			app.If(canAgree, func() app.UI {
+				return app.Button().Class("is-dense p-button--positive").Value(duplicate.ID).Text(w.t("duplicate.agree")).OnClick(w.onAgreeMerge)
+			}),
+		),
+	)
+}
+
+func (w *witness) onMergeTarget(ctx app.Context, e app.Event) {
+	w.mergeTarget = ctx.JSSrc().Get("value").String()
+}
+
+func (w *witness) onProposeMerge(ctx app.Context, e app.Event) {
+	a, b := w.eventIndex(ctx.JSSrc().Get("value").String()), w.eventIndex(w.mergeTarget)
+	if a < 0 || b < 0 {
+		w.createNotification(ctx, NotificationWarning, w.t("duplicate.merge"), w.t("duplicate.noTarget"))
+		return
+	}
+
+	duplicate, ok := proposeMerge(w.events[a], w.events[b], w.citizenID)
+	if !ok {
+		w.createNotification(ctx, NotificationWarning, w.t("duplicate.merge"), w.t("duplicate.notAllowed"))
+		return
+	}
+	w.mergeTarget = ""
+
+	survivor := w.events[a]
+	if survivor.ID == duplicate.ID {
+		survivor = w.events[b]
+	}
+	duplicate = w.logged(duplicate, opMergeProposal, survivor.ID, time.Now())
+	w.submitMerge(ctx, survivor, duplicate)
+}
+
+func (w *witness) onAgreeMerge(ctx app.Context, e app.Event) {
+	n := w.eventIndex(ctx.JSSrc().Get("value").String())
+	if n < 0 {
+		return
+	}
+	m := w.eventIndex(w.events[n].MergeInto)
+	if m < 0 {
+		return
+	}
+
+	duplicate, ok := agreeMerge(w.events[m], w.events[n], w.citizenID)
+	if !ok {
+		return
+	}
+	duplicate = w.logged(duplicate, opMergeVote, w.events[m].ID, time.Now())
+	w.submitMerge(ctx, w.events[m], duplicate)
+}
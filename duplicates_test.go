@@ -0,0 +1,94 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMergeDuplicates(t *testing.T) {
+	first := Event{ID: "1", Type: eventType, Reporter: "10", Witnesses: []string{"11"}, ConfirmedBy: 1, CreatedAt: 100,
+		Details: []Detail{{Text: "smoke", Author: "10"}}}
+	second := Event{ID: "2", Type: eventType, Reporter: "20", Witnesses: []string{"21", "11"}, ConfirmedBy: 2, CreatedAt: 200,
+		Details: []Detail{{Text: "fire", Author: "20"}}, Tags: []string{"fire"}}
+
+	if _, ok := proposeMerge(first, second, "99"); ok {
+		t.Error("outsider proposed a merge")
+	}
+
+	duplicate, ok := proposeMerge(second, first, "21")
+	if !ok || duplicate.ID != "2" || duplicate.MergeInto != "1" {
+		t.Fatalf("proposal = %+v, %v, want event 2 merged into 1", duplicate, ok)
+	}
+	if _, ok := proposeMerge(first, duplicate, "10"); ok {
+		t.Error("second proposal accepted while one is pending")
+	}
+
+	if mergeAgreed(first, duplicate) {
+		t.Fatal("proposal agreed by its proposer alone")
+	}
+
+	// 11 took part in both reports and counts for each
+	steps := []struct {
+		citizen string
+		agreed  bool
+	}{
+		{"11", false},
+		{"10", true},
+	}
+	for _, s := range steps {
+		var ok bool
+		duplicate, ok = agreeMerge(first, duplicate, s.citizen)
+		if !ok {
+			t.Fatalf("%s could not agree", s.citizen)
+		}
+		if got := mergeAgreed(first, duplicate); got != s.agreed {
+			t.Fatalf("after %s agreed mergeAgreed = %v, want %v", s.citizen, got, s.agreed)
+		}
+	}
+	if _, ok := agreeMerge(first, duplicate, "10"); ok {
+		t.Error("citizen agreed twice")
+	}
+
+	survivor, merged := combineDuplicates(first, duplicate, time.Unix(300, 0))
+	if merged.Type != mergedType || merged.MergeInto != "1" {
+		t.Errorf("duplicate not marked as merged: %+v", merged)
+	}
+	if !reflect.DeepEqual(survivor.Witnesses, []string{"11", "20", "21"}) || survivor.ConfirmedBy != 3 || survivor.NewsAt != 300 {
+		t.Errorf("survivor witnesses %v confirmed by %d news at %d", survivor.Witnesses, survivor.ConfirmedBy, survivor.NewsAt)
+	}
+	wantDetails := []Detail{{Text: "smoke", Author: "10"}, {Text: "fire", Author: "20", From: "2"}}
+	if !reflect.DeepEqual(survivor.Details, wantDetails) {
+		t.Errorf("survivor details %+v, want %+v", survivor.Details, wantDetails)
+	}
+	if !reflect.DeepEqual(survivor.MergedFrom, []string{"2"}) || !reflect.DeepEqual(survivor.Tags, []string{"fire"}) {
+		t.Errorf("survivor merged from %v tags %v", survivor.MergedFrom, survivor.Tags)
+	}
+}
+
+func TestMergedEventLeavesFeed(t *testing.T) {
+	w := &witness{}
+	w.receiveUpdate(Event{ID: "1", Type: eventType, Reporter: "10"})
+	w.receiveUpdate(Event{ID: "2", Type: eventType, Reporter: "20"})
+
+	// votes of citizens who took part in neither report don't count
+	w.receiveUpdate(Event{ID: "2", Type: mergedType, Reporter: "20", MergeInto: "1", MergeVotes: []string{"zzz"}})
+	if w.eventIndex("2") < 0 {
+		t.Fatal("merge voted by outsiders applied")
+	}
+	w.receiveUpdate(Event{ID: "2", Type: mergedType, Reporter: "20", MergeInto: "3", MergeVotes: []string{"10", "20"}})
+	if w.eventIndex("2") < 0 {
+		t.Fatal("merge into an unknown event applied")
+	}
+
+	w.receiveUpdate(Event{ID: "2", Type: mergedType, Reporter: "20", MergeInto: "1", MergeVotes: []string{"10", "20"}})
+	if w.eventIndex("2") >= 0 || w.eventIndex("1") < 0 {
+		t.Errorf("events after merge: %+v", w.events)
+	}
+
+	// a stale version can't bring the duplicate back
+	merged, _ := mergeEvents(Event{ID: "2", Type: mergedType}, Event{ID: "2", Type: archivedType})
+	if merged.Type != mergedType {
+		t.Errorf("merged event became %s", merged.Type)
+	}
+}
@@ -0,0 +1,550 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// fakePeerID returns the n-th of a fixed series of valid libp2p peer IDs:
+// the base58 identity multihash of a protobuf encoded ed25519 public key.
+// Citizen IDs are derived from the last characters of the peer ID, so every
+// node gets a distinct citizen.
+func fakePeerID(n int) string {
+	seed := sha256.Sum256([]byte("fake-peer-" + strconv.Itoa(n)))
+	pub := ed25519.NewKeyFromSeed(seed[:]).Public().(ed25519.PublicKey)
+
+	// identity multihash of length 36 holding {Type: Ed25519, Data: pub}
+	b := append([]byte{0x00, 0x24, 0x08, 0x01, 0x12, 0x20}, pub...)
+
+	num := new(big.Int).SetBytes(b)
+	base := big.NewInt(58)
+	mod := new(big.Int)
+	var out []byte
+	for num.Sign() > 0 {
+		num.DivMod(num, base, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	for _, c := range b {
+		if c != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+	slices.Reverse(out)
+	return string(out)
+}
+
+// fakeNetwork is an in-memory stand-in for the IPFS network as seen through
+// the kubo HTTP API of the stateless-minds fork. Docs stores and blocks are
+// shared by all nodes, as if replication were instant, and pubsub messages
+// reach every subscriber of a topic on any node.
+type fakeNetwork struct {
+	t *testing.T
+
+	mu     sync.Mutex
+	nodes  int
+	seqno  int
+	docs   map[string]map[string]map[string]interface{}
+	blocks map[string][]byte
+	subs   map[string][]chan fakeMessage
+	// keys maps IPNS key names to their IDs and names maps IDs to the
+	// path they were published with
+	keys  map[string]string
+	names map[string]string
+	// drop is closed to end every open subscription stream
+	drop chan struct{}
+}
+
+type fakeMessage struct {
+	from string
+	data []byte
+}
+
+func newFakeNetwork(t *testing.T) *fakeNetwork {
+	return &fakeNetwork{
+		t:      t,
+		docs:   make(map[string]map[string]map[string]interface{}),
+		blocks: make(map[string][]byte),
+		subs:   make(map[string][]chan fakeMessage),
+		keys:   make(map[string]string),
+		names:  make(map[string]string),
+		drop:   make(chan struct{}),
+	}
+}
+
+// dropSubscriptions ends every open subscription stream, as a restarting
+// node would.
+func (n *fakeNetwork) dropSubscriptions() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	close(n.drop)
+	n.drop = make(chan struct{})
+}
+
+// addNode starts the HTTP API of a new node and returns its address.
+func (n *fakeNetwork) addNode() string {
+	n.mu.Lock()
+	peerID := fakePeerID(n.nodes)
+	n.nodes++
+	n.mu.Unlock()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v0/id", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]string{"ID": peerID})
+	})
+	mux.HandleFunc("/api/v0/version", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]string{"Version": "0.30.0"})
+	})
+	mux.HandleFunc("/api/v0/pubsub/sub", n.handleSub)
+	mux.HandleFunc("/api/v0/pubsub/pub", func(w http.ResponseWriter, r *http.Request) {
+		n.handlePub(w, r, peerID)
+	})
+	mux.HandleFunc("/api/v0/orbit/docsput", n.handleDocsPut)
+	mux.HandleFunc("/api/v0/orbit/docsget", n.handleDocsGet)
+	mux.HandleFunc("/api/v0/orbit/docsquery", n.handleDocsQuery)
+	mux.HandleFunc("/api/v0/orbit/docsdel", n.handleDocsDel)
+	mux.HandleFunc("/api/v0/dag/put", n.handleDagPut)
+	mux.HandleFunc("/api/v0/dag/get", n.handleDagGet)
+	mux.HandleFunc("/api/v0/dag/export", n.handleDagExport)
+	mux.HandleFunc("/api/v0/dag/import", n.handleDagImport)
+	mux.HandleFunc("/api/v0/add", n.handleAdd)
+	mux.HandleFunc("/api/v0/key/list", n.handleKeyList)
+	mux.HandleFunc("/api/v0/key/gen", n.handleKeyGen)
+	mux.HandleFunc("/api/v0/name/publish", n.handleNamePublish)
+	// the node doubles as a gateway
+	mux.HandleFunc("/ipns/", n.handleGateway)
+
+	srv := httptest.NewServer(mux)
+	n.t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// publish delivers data to every subscriber of topic.
+func (n *fakeNetwork) publish(topic, from string, data []byte) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, ch := range n.subs[topic] {
+		ch <- fakeMessage{from: from, data: data}
+	}
+}
+
+func (n *fakeNetwork) handleSub(w http.ResponseWriter, r *http.Request) {
+	topic, err := decodeArg(r, 0)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	ch := make(chan fakeMessage, 64)
+	n.mu.Lock()
+	n.subs[topic] = append(n.subs[topic], ch)
+	drop := n.drop
+	n.mu.Unlock()
+
+	defer func() {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		subs := n.subs[topic]
+		for i, c := range subs {
+			if c == ch {
+				n.subs[topic] = append(subs[:i], subs[i+1:]...)
+				break
+			}
+		}
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.(http.Flusher).Flush()
+
+	enc := json.NewEncoder(w)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-drop:
+			return
+		case msg := <-ch:
+			n.mu.Lock()
+			n.seqno++
+			seqno := n.seqno
+			n.mu.Unlock()
+
+			err := enc.Encode(map[string]interface{}{
+				"from":     msg.from,
+				"data":     encodeMultibase(msg.data),
+				"seqno":    encodeMultibase([]byte(strconv.Itoa(seqno))),
+				"topicIDs": []string{encodeMultibase([]byte(topic))},
+			})
+			if err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+		}
+	}
+}
+
+func (n *fakeNetwork) handlePub(w http.ResponseWriter, r *http.Request, peerID string) {
+	topic, err := decodeArg(r, 0)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	data, err := readBody(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	n.publish(topic, peerID, data)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (n *fakeNetwork) handleDocsPut(w http.ResponseWriter, r *http.Request) {
+	db, err := decodeArg(r, 0)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	data, err := readBody(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var doc map[string]interface{}
+	err = json.Unmarshal(data, &doc)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	id, ok := doc["_id"].(string)
+	if !ok {
+		writeError(w, fmt.Errorf("document has no _id"))
+		return
+	}
+
+	n.mu.Lock()
+	if n.docs[db] == nil {
+		n.docs[db] = make(map[string]map[string]interface{})
+	}
+	n.docs[db][id] = doc
+	n.mu.Unlock()
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (n *fakeNetwork) handleDocsGet(w http.ResponseWriter, r *http.Request) {
+	db, err := decodeArg(r, 0)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	key, err := decodeArg(r, 1)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	docs := []interface{}{}
+	if doc, ok := n.docs[db][key]; ok {
+		docs = append(docs, doc)
+	}
+	writeJSON(w, docs)
+}
+
+func (n *fakeNetwork) handleDocsQuery(w http.ResponseWriter, r *http.Request) {
+	db, err := decodeArg(r, 0)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	key, err := decodeArg(r, 1)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	value, err := decodeArg(r, 2)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	docs := []interface{}{}
+	for _, doc := range n.docs[db] {
+		if fmt.Sprint(doc[key]) == value {
+			docs = append(docs, doc)
+		}
+	}
+	writeJSON(w, docs)
+}
+
+func (n *fakeNetwork) handleDocsDel(w http.ResponseWriter, r *http.Request) {
+	db, err := decodeArg(r, 0)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	key, err := decodeArg(r, 1)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	n.mu.Lock()
+	if key == "all" {
+		delete(n.docs, db)
+	} else {
+		delete(n.docs[db], key)
+	}
+	n.mu.Unlock()
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (n *fakeNetwork) handleDagPut(w http.ResponseWriter, r *http.Request) {
+	data, err := readBody(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if !json.Valid(data) {
+		writeError(w, fmt.Errorf("invalid dag-json"))
+		return
+	}
+
+	cid := n.putBlock(data)
+	writeJSON(w, map[string]interface{}{"Cid": map[string]string{"/": cid}})
+}
+
+func (n *fakeNetwork) handleDagGet(w http.ResponseWriter, r *http.Request) {
+	cid := r.URL.Query().Get("arg")
+
+	n.mu.Lock()
+	data, ok := n.blocks[cid]
+	n.mu.Unlock()
+	if !ok {
+		writeError(w, fmt.Errorf("block %s not found", cid))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// fakeCARBlock is a block of the archives the fake node exports. They hold
+// one block per line, the root first, instead of the binary CAR format.
+type fakeCARBlock struct {
+	CID  string `json:"cid"`
+	Data []byte `json:"data"`
+}
+
+func (n *fakeNetwork) handleDagExport(w http.ResponseWriter, r *http.Request) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	enc := json.NewEncoder(w)
+	seen := make(map[string]bool)
+	queue := []string{r.URL.Query().Get("arg")}
+	for len(queue) > 0 {
+		cid := queue[0]
+		queue = queue[1:]
+		data, ok := n.blocks[cid]
+		if seen[cid] || !ok {
+			continue
+		}
+		seen[cid] = true
+		enc.Encode(fakeCARBlock{CID: cid, Data: data})
+
+		var node interface{}
+		if json.Unmarshal(data, &node) == nil {
+			queue = append(queue, dagLinks(node)...)
+		}
+	}
+}
+
+// dagLinks returns the CIDs a dag-json node links to.
+func dagLinks(node interface{}) []string {
+	var links []string
+	switch v := node.(type) {
+	case map[string]interface{}:
+		if cid, ok := v["/"].(string); ok && len(v) == 1 {
+			return []string{cid}
+		}
+		for _, child := range v {
+			links = append(links, dagLinks(child)...)
+		}
+	case []interface{}:
+		for _, child := range v {
+			links = append(links, dagLinks(child)...)
+		}
+	}
+	return links
+}
+
+func (n *fakeNetwork) handleDagImport(w http.ResponseWriter, r *http.Request) {
+	data, err := readBody(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var root string
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	for dec.More() {
+		var b fakeCARBlock
+		if err := dec.Decode(&b); err != nil {
+			writeError(w, err)
+			return
+		}
+		if cid := n.putBlock(b.Data); cid != b.CID {
+			writeError(w, fmt.Errorf("block %s has CID %s", b.CID, cid))
+			return
+		}
+		if root == "" {
+			root = b.CID
+		}
+	}
+	writeJSON(w, map[string]interface{}{"Root": map[string]interface{}{"Cid": map[string]string{"/": root}}})
+}
+
+func (n *fakeNetwork) handleAdd(w http.ResponseWriter, r *http.Request) {
+	data, err := readBody(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	cid := n.putBlock(data)
+	writeJSON(w, map[string]interface{}{"Name": cid, "Hash": cid, "Size": strconv.Itoa(len(data))})
+}
+
+func (n *fakeNetwork) handleKeyList(w http.ResponseWriter, r *http.Request) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	keys := []map[string]string{}
+	for name, id := range n.keys {
+		keys = append(keys, map[string]string{"Name": name, "Id": id})
+	}
+	writeJSON(w, map[string]interface{}{"Keys": keys})
+}
+
+func (n *fakeNetwork) handleKeyGen(w http.ResponseWriter, r *http.Request) {
+	// unlike pubsub and orbit arguments, key names and paths are passed
+	// as is
+	name := r.URL.Query().Get("arg")
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if _, ok := n.keys[name]; ok {
+		writeError(w, fmt.Errorf("key with name %q already exists", name))
+		return
+	}
+	id := fakePeerID(1000 + len(n.keys))
+	n.keys[name] = id
+	writeJSON(w, map[string]string{"Name": name, "Id": id})
+}
+
+func (n *fakeNetwork) handleNamePublish(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Query().Get("arg")
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	id, ok := n.keys[r.URL.Query().Get("key")]
+	if !ok {
+		writeError(w, fmt.Errorf("no key named %q", r.URL.Query().Get("key")))
+		return
+	}
+	n.names[id] = path
+	writeJSON(w, map[string]string{"name": id, "value": path})
+}
+
+// handleGateway serves the block an IPNS name points to.
+func (n *fakeNetwork) handleGateway(w http.ResponseWriter, r *http.Request) {
+	n.mu.Lock()
+	data, ok := n.blocks[strings.TrimPrefix(n.names[strings.TrimPrefix(r.URL.Path, "/ipns/")], "/ipfs/")]
+	n.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Write(data)
+}
+
+// putBlock stores data under a content derived identifier.
+func (n *fakeNetwork) putBlock(data []byte) string {
+	sum := sha256.Sum256(data)
+	cid := "bafyfake" + hex.EncodeToString(sum[:16])
+
+	n.mu.Lock()
+	n.blocks[cid] = data
+	n.mu.Unlock()
+	return cid
+}
+
+// decodeArg returns the n-th argument of a request, which the client
+// encodes as base64url multibase.
+func decodeArg(r *http.Request, n int) (string, error) {
+	args := r.URL.Query()["arg"]
+	if n >= len(args) {
+		return "", fmt.Errorf("missing argument %d", n)
+	}
+
+	arg := args[n]
+	if !strings.HasPrefix(arg, "u") {
+		return "", fmt.Errorf("argument %q is not base64url multibase", arg)
+	}
+	b, err := base64.RawURLEncoding.DecodeString(arg[1:])
+	return string(b), err
+}
+
+func encodeMultibase(b []byte) string {
+	return "u" + base64.RawURLEncoding.EncodeToString(b)
+}
+
+// readBody returns the content of the first file of a multipart request.
+func readBody(r *http.Request) ([]byte, error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+
+	part, err := mr.NextPart()
+	if err != nil {
+		return nil, err
+	}
+	defer part.Close()
+	return io.ReadAll(part)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(map[string]interface{}{"Message": err.Error(), "Code": 0, "Type": "error"})
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+// formatKML is the KML format of feed exports, next to formatGeoJSON and
+// formatCSV.
+const formatKML = "kml"
+
+// feedFormats are the formats the feeds can be exported as, in the order
+// the export buttons show them.
+var feedFormats = []string{formatGeoJSON, formatKML, formatCSV}
+
+// feedMIMETypes are the media types of the export formats.
+var feedMIMETypes = map[string]string{
+	formatGeoJSON: "application/geo+json",
+	formatKML:     "application/vnd.google-earth.kml+xml",
+	formatCSV:     "text/csv",
+}
+
+// feedRecord is an event as exported from a feed, with the latest
+// corrections applied and muted details left out.
+type feedRecord struct {
+	ID          string
+	Title       string
+	Location    string
+	Lat, Lon    *float64
+	State       eventState
+	ConfirmedBy int
+	CreatedAt   int64
+	NewsAt      int64
+	Channel     string
+	Language    string
+	Tags        []string
+	Details     []string
+}
+
+// feedRecords returns the events of v as the feed shows them with the mute
+// list and filters of w: every event left by the filters for the rumors,
+// the confirmed ones for the news.
+func (w *witness) feedRecords(events []Event, v view) []feedRecord {
+	var records []feedRecord
+	for _, e := range events {
+		if e.Type != eventType || !w.inFeed(e) || (v == viewNews && !w.inNews(e)) {
+			continue
+		}
+
+		c := e.corrected()
+		r := feedRecord{
+			ID:          e.ID,
+			Title:       c.Title,
+			Location:    c.Location,
+			State:       e.state(),
+			ConfirmedBy: e.ConfirmedBy,
+			CreatedAt:   e.CreatedAt,
+			NewsAt:      e.NewsAt,
+			Channel:     parseChannel(e.Channel).name(),
+			Language:    e.Language,
+			Tags:        e.Tags,
+		}
+		r.Lat, r.Lon = locationCoordinates(c.Location)
+		for _, d := range e.Details {
+			if !w.mutes.hidesDetail(d) {
+				r.Details = append(r.Details, d.Text)
+			}
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+// feedFormat guesses the export format from the name of the file it is
+// written to.
+func feedFormat(name string) string {
+	switch {
+	case strings.HasSuffix(name, ".kml"):
+		return formatKML
+	case strings.HasSuffix(name, ".csv"):
+		return formatCSV
+	default:
+		return formatGeoJSON
+	}
+}
+
+// writeFeed writes records in format.
+func writeFeed(out io.Writer, format string, records []feedRecord) error {
+	switch format {
+	case formatGeoJSON:
+		return writeFeedGeoJSON(out, records)
+	case formatKML:
+		return writeFeedKML(out, records)
+	case formatCSV:
+		return writeFeedCSV(out, records)
+	default:
+		return fmt.Errorf("unknown export format %q, expected geojson, kml or csv", format)
+	}
+}
+
+// exportTime formats a timestamp of an export, empty when unset.
+func exportTime(unix int64) string {
+	if unix == 0 {
+		return ""
+	}
+	return time.Unix(unix, 0).UTC().Format(time.RFC3339)
+}
+
+// geoJSONPoint is a GeoJSON point geometry.
+type geoJSONPoint struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+// writeFeedGeoJSON writes records as a feature collection. Events without
+// coordinates have no geometry.
+func writeFeedGeoJSON(out io.Writer, records []feedRecord) error {
+	type feature struct {
+		Type       string         `json:"type"`
+		ID         string         `json:"id"`
+		Geometry   *geoJSONPoint  `json:"geometry"`
+		Properties map[string]any `json:"properties"`
+	}
+
+	features := make([]feature, 0, len(records))
+	for _, r := range records {
+		f := feature{Type: "Feature", ID: r.ID, Properties: map[string]any{
+			"title":     r.Title,
+			"location":  r.Location,
+			"state":     r.State,
+			"witnesses": r.ConfirmedBy,
+			"createdAt": exportTime(r.CreatedAt),
+			"newsAt":    exportTime(r.NewsAt),
+			"channel":   r.Channel,
+			"language":  r.Language,
+			"tags":      append([]string{}, r.Tags...),
+			"details":   append([]string{}, r.Details...),
+		}}
+		if r.Lat != nil {
+			// GeoJSON puts the longitude first
+			f.Geometry = &geoJSONPoint{Type: "Point", Coordinates: [2]float64{*r.Lon, *r.Lat}}
+		}
+		features = append(features, f)
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(map[string]any{"type": "FeatureCollection", "features": features})
+}
+
+// kmlDocument is the KML document of an export.
+type kmlDocument struct {
+	XMLName    xml.Name       `xml:"kml"`
+	Namespace  string         `xml:"xmlns,attr"`
+	Name       string         `xml:"Document>name"`
+	Placemarks []kmlPlacemark `xml:"Document>Placemark"`
+}
+
+type kmlPlacemark struct {
+	ID          string     `xml:"id,attr"`
+	Name        string     `xml:"name"`
+	Address     string     `xml:"address"`
+	Description string     `xml:"description,omitempty"`
+	When        string     `xml:"TimeStamp>when"`
+	Data        []kmlData  `xml:"ExtendedData>Data"`
+	Point       *kmlCoords `xml:"Point"`
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+type kmlCoords struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+// writeFeedKML writes records as KML placemarks. Events without
+// coordinates are placed by their address only.
+func writeFeedKML(out io.Writer, records []feedRecord) error {
+	doc := kmlDocument{Namespace: "http://www.opengis.net/kml/2.2", Name: "Cyber Witness"}
+	for _, r := range records {
+		p := kmlPlacemark{
+			// XML IDs can't start with a digit
+			ID:          "event-" + r.ID,
+			Name:        r.Title,
+			Address:     r.Location,
+			Description: strings.Join(r.Details, "\n\n"),
+			When:        exportTime(r.CreatedAt),
+			Data: []kmlData{
+				{Name: "state", Value: string(r.State)},
+				{Name: "witnesses", Value: strconv.Itoa(r.ConfirmedBy)},
+				{Name: "newsAt", Value: exportTime(r.NewsAt)},
+				{Name: "channel", Value: r.Channel},
+				{Name: "language", Value: r.Language},
+				{Name: "tags", Value: strings.Join(r.Tags, ";")},
+			},
+		}
+		if r.Lat != nil {
+			p.Point = &kmlCoords{Coordinates: strconv.FormatFloat(*r.Lon, 'f', -1, 64) + "," + strconv.FormatFloat(*r.Lat, 'f', -1, 64)}
+		}
+		doc.Placemarks = append(doc.Placemarks, p)
+	}
+
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(out)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(out, "\n")
+	return err
+}
+
+// feedCSVHeader names the columns of CSV exports. They use the names bulk
+// imports read, so an export can be edited and imported elsewhere.
+var feedCSVHeader = []string{"id", "title", "location", "latitude", "longitude", "state", "witnesses", "created_at", "news_at", "channel", "language", "tags", "details"}
+
+// writeFeedCSV writes records as a spreadsheet, one event per row. Tags are
+// separated by semicolons and details by blank lines.
+func writeFeedCSV(out io.Writer, records []feedRecord) error {
+	cw := csv.NewWriter(out)
+	if err := cw.Write(feedCSVHeader); err != nil {
+		return err
+	}
+
+	for _, r := range records {
+		var lat, lon string
+		if r.Lat != nil {
+			lat = strconv.FormatFloat(*r.Lat, 'f', -1, 64)
+			lon = strconv.FormatFloat(*r.Lon, 'f', -1, 64)
+		}
+		err := cw.Write([]string{
+			csvText(r.ID),
+			csvText(r.Title),
+			csvText(r.Location),
+			lat,
+			lon,
+			string(r.State),
+			strconv.Itoa(r.ConfirmedBy),
+			exportTime(r.CreatedAt),
+			exportTime(r.NewsAt),
+			csvText(r.Channel),
+			csvText(r.Language),
+			csvText(strings.Join(r.Tags, ";")),
+			csvText(strings.Join(r.Details, "\n\n")),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvText returns a text cell of a CSV export. Spreadsheets run cells that
+// start like a formula, so those are prefixed with an apostrophe to keep
+// anyone who can report an event from running formulas on the reader's
+// machine.
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func (w *witness) onExportFeed(ctx app.Context, e app.Event) {
+	format := ctx.JSSrc().Get("value").String()
+
+	var b strings.Builder
+	err := writeFeed(&b, format, w.feedRecords(w.events, w.view))
+	if err != nil {
+		w.createNotification(ctx, NotificationDanger, w.t(ErrorHeader), w.t("feed.exportFailed"))
+		log.Println(err)
+		return
+	}
+	name := fmt.Sprintf("cyber-witness-%s-%s.%s", w.view, time.Now().Format("2006-01-02"), format)
+	downloadFile(name, feedMIMETypes[format], []byte(b.String()))
+}
+
+// renderFeedExport renders the buttons exporting the events the feed shows.
+func (w *witness) renderFeedExport() app.UI {
+	return app.Div().Class("p-form p-form--inline").Body(
+		app.Div().Class("p-form__group").Body(
+			app.Span().Class("p-form__label").Text(w.t("feed.export")),
+			app.Range(feedFormats).Slice(func(n int) app.UI {
+				return app.Button().Class("p-button--base is-small").Value(feedFormats[n]).Text(strings.ToUpper(feedFormats[n])).OnClick(w.onExportFeed)
+			}),
+		),
+	)
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// feedEvents are a news with coordinates, a muted detail and a correction,
+// a rumor without coordinates and a disputed rumor.
+func feedEvents() []Event {
+	return []Event{
+		{
+			ID: "1", Type: eventType, Title: "Fire", Location: "Main St (42.69770, 23.32190)",
+			ConfirmedBy: 2, Witnesses: []string{"11", "12"}, CreatedAt: 1000, NewsAt: 1100, Language: "en", Tags: []string{"fire", "health"},
+			Details:     []Detail{{Author: "10", Text: "smoke"}, {Author: "66", Text: "spam"}},
+			Corrections: []Correction{{Title: "Fire & smoke", At: 1050}},
+		},
+		{ID: "2", Type: eventType, Title: "Flood", Location: "River Rd", ConfirmedBy: 1, Witnesses: []string{"11"}, CreatedAt: 1200, Channel: "u4pr"},
+		{ID: "3", Type: eventType, Title: "Storm", Location: "Hill", CreatedAt: 1300, Disputes: []string{"20", "21"}},
+		{ID: "4", Type: archivedType, Title: "Old", Location: "Elm St", CreatedAt: 10},
+	}
+}
+
+func TestFeedRecordsFollowTheFilters(t *testing.T) {
+	w := &witness{mutes: muteList{Citizens: []string{"66"}}}
+
+	records := w.feedRecords(feedEvents(), viewRumors)
+	if len(records) != 3 {
+		t.Fatalf("exported %d rumors, want the 3 active events", len(records))
+	}
+	r := records[0]
+	if r.Title != "Fire & smoke" || r.State != stateNews || r.ConfirmedBy != 2 || !reflect.DeepEqual(r.Details, []string{"smoke"}) {
+		t.Errorf("news record = %+v", r)
+	}
+	if r.Lat == nil || *r.Lat != 42.6977 || *r.Lon != 23.3219 {
+		t.Errorf("coordinates = %v, %v", r.Lat, r.Lon)
+	}
+	if records[1].Lat != nil || records[1].Channel != "u4pr" {
+		t.Errorf("rumor record = %+v", records[1])
+	}
+
+	if records := w.feedRecords(feedEvents(), viewNews); len(records) != 1 || records[0].ID != "1" {
+		t.Errorf("news export = %+v", records)
+	}
+	w.stateFilter = stateDisputed
+	if records := w.feedRecords(feedEvents(), viewRumors); len(records) != 1 || records[0].ID != "3" {
+		t.Errorf("export of disputed events = %+v", records)
+	}
+	w.stateFilter, w.tagFilter = "", "health"
+	if records := w.feedRecords(feedEvents(), viewRumors); len(records) != 1 || records[0].ID != "1" {
+		t.Errorf("export of tagged events = %+v", records)
+	}
+}
+
+func TestLocationCoordinates(t *testing.T) {
+	for location, want := range map[string][]float64{
+		"42.6977, 23.3219":             {42.6977, 23.3219},
+		"Main St (-33.86,151.21)":      {-33.86, 151.21},
+		"Main St":                      nil,
+		"Building 12, 3rd floor":       nil,
+		"Out of range (95.0, 23.3219)": nil,
+	} {
+		lat, lon := locationCoordinates(location)
+		var got []float64
+		if lat != nil {
+			got = []float64{*lat, *lon}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("coordinates of %q = %v, want %v", location, got, want)
+		}
+	}
+}
+
+func TestWriteFeed(t *testing.T) {
+	w := &witness{mutes: muteList{Citizens: []string{"66"}}}
+	records := w.feedRecords(feedEvents(), viewRumors)
+
+	var out bytes.Buffer
+	if err := writeFeed(&out, formatGeoJSON, records); err != nil {
+		t.Fatal(err)
+	}
+	var collection struct {
+		Type     string
+		Features []struct {
+			ID         string
+			Geometry   *geoJSONPoint
+			Properties struct {
+				Title     string
+				Witnesses int
+				CreatedAt string
+				State     string
+				Details   []string
+			}
+		}
+	}
+	if err := json.Unmarshal(out.Bytes(), &collection); err != nil {
+		t.Fatal(err)
+	}
+	f := collection.Features[0]
+	if collection.Type != "FeatureCollection" || len(collection.Features) != 3 || f.Geometry.Coordinates != [2]float64{23.3219, 42.6977} {
+		t.Fatalf("GeoJSON = %s", out.String())
+	}
+	if f.Properties.Title != "Fire & smoke" || f.Properties.Witnesses != 2 || f.Properties.CreatedAt != "1970-01-01T00:16:40Z" || f.Properties.State != "news" {
+		t.Errorf("feature properties = %+v", f.Properties)
+	}
+	if collection.Features[1].Geometry != nil {
+		t.Errorf("event without coordinates has geometry %+v", collection.Features[1].Geometry)
+	}
+
+	out.Reset()
+	if err := writeFeed(&out, formatKML, records); err != nil {
+		t.Fatal(err)
+	}
+	var kml kmlDocument
+	if err := xml.Unmarshal(out.Bytes(), &kml); err != nil {
+		t.Fatal(err)
+	}
+	if len(kml.Placemarks) != 3 || kml.Placemarks[0].Name != "Fire & smoke" || kml.Placemarks[0].Point.Coordinates != "23.3219,42.6977" || kml.Placemarks[1].Point != nil {
+		t.Errorf("KML = %s", out.String())
+	}
+
+	out.Reset()
+	if err := writeFeed(&out, formatCSV, records); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 || !reflect.DeepEqual(rows[0], feedCSVHeader) {
+		t.Fatalf("CSV rows = %q", rows)
+	}
+	if want := []string{"1", "Fire & smoke", "Main St (42.69770, 23.32190)", "42.6977", "23.3219", "news", "2", "1970-01-01T00:16:40Z", "1970-01-01T00:18:20Z", "Global", "en", "fire;health", "smoke"}; !reflect.DeepEqual(rows[1], want) {
+		t.Errorf("CSV row = %q, want %q", rows[1], want)
+	}
+
+	// exported spreadsheets read back as reports
+	out.Reset()
+	writeFeed(&out, formatCSV, records)
+	drafts, err := readReportsCSV(strings.NewReader(out.String()))
+	if err != nil || len(drafts) != 3 || drafts[0].Title != "Fire & smoke" {
+		t.Errorf("re-imported = %+v, %v", drafts, err)
+	}
+
+	if err := writeFeed(&out, "shp", records); err == nil {
+		t.Error("unknown format written")
+	}
+}
+
+func TestFeedCSVEscapesFormulas(t *testing.T) {
+	lat, lon := -33.86, 151.2
+	records := []feedRecord{{
+		ID: "1", Title: `=HYPERLINK("http://evil.example","Fire")`, Location: "+1 Main St", Lat: &lat, Lon: &lon,
+		State: stateRumor, Tags: []string{"-fire"}, Details: []string{"@smoke"}, Channel: "Global", Language: "en",
+	}}
+
+	var out bytes.Buffer
+	if err := writeFeedCSV(&out, records); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"1", `'=HYPERLINK("http://evil.example","Fire")`, "'+1 Main St", "-33.86", "151.2", "rumor", "0", "", "", "Global", "en", "'-fire", "'@smoke"}
+	if !reflect.DeepEqual(rows[1], want) {
+		t.Errorf("CSV row = %q, want %q", rows[1], want)
+	}
+
+	for text, want := range map[string]string{"": "", "Fire": "Fire", "a=b": "a=b", "\tx": "'\tx", "\rx": "'\rx", "-": "'-"} {
+		if got := csvText(text); got != want {
+			t.Errorf("csvText(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
@@ -6,6 +6,7 @@ toolchain go1.23.3
 
 require (
 	github.com/NYTimes/gziphandler v1.1.1
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.3.0
 	github.com/foolin/mixer v0.0.8
 	github.com/maxence-charriere/go-app/v10 v10.0.8
 	github.com/mitchellh/mapstructure v1.5.0
@@ -16,7 +17,6 @@ require (
 	github.com/benbjohnson/clock v1.3.5 // indirect
 	github.com/blang/semver/v4 v4.0.0 // indirect
 	github.com/crackcomm/go-gitignore v0.0.0-20241020182519-7843d2ba8fdf // indirect
-	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.3.0 // indirect
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/ipfs/go-cid v0.4.1 // indirect
 	github.com/klauspost/cpuid/v2 v2.2.8 // indirect
@@ -0,0 +1,543 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	shell "github.com/stateless-minds/go-ipfs-api"
+)
+
+// peer is a simulated citizen running the witness component against its own
+// node of a fakeNetwork.
+type peer struct {
+	t *testing.T
+
+	mu       sync.Mutex
+	w        *witness
+	states   map[string]subState
+	connects map[string]int
+}
+
+// newPeer starts a node on n that shows the global channel.
+func newPeer(t *testing.T, n *fakeNetwork) *peer {
+	t.Helper()
+	return newChannelPeer(t, n, channel{})
+}
+
+// newChannelPeer starts a node on n, loads the stored events of channel c
+// like OnMount does and subscribes to the channel's event topics.
+func newChannelPeer(t *testing.T, n *fakeNetwork, c channel) *peer {
+	t.Helper()
+
+	sh := shell.NewShell(n.addNode())
+	citizenID, err := localCitizenID(sh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &peer{
+		t: t,
+		w: &witness{
+			sh:        sh,
+			citizenID: citizenID,
+			key:       key,
+			history:   make(map[string][]revision),
+			noNews:    true,
+			channel:   c,
+		},
+	}
+
+	events, err := queryEvents(sh, c, eventType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range events {
+		p.w.receiveUpdate(e)
+	}
+
+	p.states = make(map[string]subState)
+	p.connects = make(map[string]int)
+	p.w.subs = newSubscriber(sh, func(topic string, s subState) {
+		p.mu.Lock()
+		p.states[topic] = s
+		if s == subConnected {
+			p.connects[topic]++
+		}
+		p.mu.Unlock()
+	})
+	p.w.subs.minBackoff = 10 * time.Millisecond
+	t.Cleanup(p.w.subs.close)
+
+	p.subscribe(c.topic(topicCreateEvent), p.w.receiveEvent)
+	p.subscribe(c.topic(topicUpdateEvent), p.w.receiveUpdate)
+	waitFor(t, "the subscriptions", (*peer).connected, p)
+	return p
+}
+
+// subscribe handles the messages of topic like witness.onEventMessage, with
+// the peer's lock standing in for the UI goroutine.
+func (p *peer) subscribe(topic string, receive func(Event)) {
+	p.w.subs.subscribe(topic, func(data []byte) {
+		e, err := decodeMessage(data)
+		if err != nil || parseChannel(e.Channel) != p.w.channel {
+			return
+		}
+
+		p.mu.Lock()
+		receive(e)
+		p.mu.Unlock()
+	})
+}
+
+// connected reports whether both event topics of the peer's channel are
+// subscribed.
+func (p *peer) connected() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	c := p.w.channel
+	return p.states[c.topic(topicCreateEvent)] == subConnected && p.states[c.topic(topicUpdateEvent)] == subConnected
+}
+
+// report submits a new rumor the way onSubmitEvent does and returns its ID.
+func (p *peer) report(title, details, location string) string {
+	p.t.Helper()
+
+	p.mu.Lock()
+	p.w.eventTitle = title
+	p.w.eventDetails = details
+	p.w.eventLocation = location
+	event, ok := p.w.newReport(time.Now())
+	p.mu.Unlock()
+	if !ok {
+		p.t.Fatalf("%s: report %q refused", p.w.citizenID, title)
+	}
+
+	event, err := p.w.submitReport(event, nil)
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	return event.ID
+}
+
+// confirm confirms a rumor the way confirmRumor does and reports whether
+// the confirmation was accepted.
+func (p *peer) confirm(id string) bool {
+	p.t.Helper()
+
+	p.mu.Lock()
+	event, ok := p.w.confirmation(id, time.Now())
+	p.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	_, err := p.w.putEvent(event, topicUpdateEvent)
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	return true
+}
+
+// addDetails adds details to an event the way onAddDetails does.
+func (p *peer) addDetails(id, text string) {
+	p.t.Helper()
+
+	p.mu.Lock()
+	event, ok := p.w.withDetails(id, Detail{Text: text, Author: p.w.citizenID}, time.Now())
+	p.mu.Unlock()
+	if !ok {
+		p.t.Fatalf("%s: event %s not loaded", p.w.citizenID, id)
+	}
+
+	_, err := p.w.putEvent(event, topicUpdateEvent)
+	if err != nil {
+		p.t.Fatal(err)
+	}
+}
+
+// event returns a copy of the loaded event with the given ID.
+func (p *peer) event(id string) (Event, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := p.w.eventIndex(id)
+	if n < 0 {
+		return Event{}, false
+	}
+	return p.w.events[n], true
+}
+
+// waitFor polls cond until it holds for every peer or fails the test.
+func waitFor(t *testing.T, what string, cond func(p *peer) bool, peers ...*peer) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for _, p := range peers {
+		for !cond(p) {
+			if time.Now().After(deadline) {
+				t.Fatalf("%s: timed out waiting for %s", p.w.citizenID, what)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+func hasEvent(id string) func(p *peer) bool {
+	return func(p *peer) bool {
+		_, ok := p.event(id)
+		return ok
+	}
+}
+
+func TestReportBecomesNews(t *testing.T) {
+	n := newFakeNetwork(t)
+	reporter, alice, bob := newPeer(t, n), newPeer(t, n), newPeer(t, n)
+
+	id := reporter.report("Bridge closed", "Police at both ends", "Old Town")
+	waitFor(t, "the rumor", hasEvent(id), reporter, alice, bob)
+
+	if !alice.confirm(id) {
+		t.Fatal("first confirmation refused")
+	}
+	waitFor(t, "the first confirmation", func(p *peer) bool {
+		e, _ := p.event(id)
+		return e.ConfirmedBy == 1
+	}, reporter, alice, bob)
+
+	if !bob.confirm(id) {
+		t.Fatal("second confirmation refused")
+	}
+	waitFor(t, "the news", func(p *peer) bool {
+		e, _ := p.event(id)
+		return e.ConfirmedBy == 2
+	}, reporter, alice, bob)
+
+	for _, p := range []*peer{reporter, alice, bob} {
+		e, _ := p.event(id)
+		if e.NewsAt == 0 {
+			t.Errorf("%s: NewsAt not set", p.w.citizenID)
+		}
+		if len(e.Witnesses) != 2 || e.Witnesses[0] != alice.w.citizenID || e.Witnesses[1] != bob.w.citizenID {
+			t.Errorf("%s: witnesses = %v", p.w.citizenID, e.Witnesses)
+		}
+		if p.w.noNews {
+			t.Errorf("%s: noNews still set", p.w.citizenID)
+		}
+		if err := verifyEvent(e); err != nil {
+			t.Errorf("%s: %v", p.w.citizenID, err)
+		}
+	}
+
+	if !bob.w.isWitness || reporter.w.isWitness {
+		t.Errorf("isWitness: bob %v, reporter %v", bob.w.isWitness, reporter.w.isWitness)
+	}
+}
+
+func TestConfirmationRules(t *testing.T) {
+	n := newFakeNetwork(t)
+	reporter, alice := newPeer(t, n), newPeer(t, n)
+
+	id := reporter.report("Power outage", "Whole street is dark", "Harbour")
+	waitFor(t, "the rumor", hasEvent(id), reporter, alice)
+
+	if reporter.confirm(id) {
+		t.Error("reporter confirmed their own rumor")
+	}
+	if !alice.confirm(id) {
+		t.Fatal("confirmation refused")
+	}
+	waitFor(t, "the confirmation", func(p *peer) bool {
+		e, _ := p.event(id)
+		return e.ConfirmedBy == 1
+	}, alice)
+
+	if alice.confirm(id) {
+		t.Error("witness confirmed twice")
+	}
+}
+
+func TestAddDetails(t *testing.T) {
+	n := newFakeNetwork(t)
+	reporter, alice, bob := newPeer(t, n), newPeer(t, n), newPeer(t, n)
+
+	id := reporter.report("Flooding", "Water in the underpass", "Riverside")
+	waitFor(t, "the rumor", hasEvent(id), reporter, alice, bob)
+
+	alice.addDetails(id, "Buses are rerouted")
+	waitFor(t, "alice's details", func(p *peer) bool {
+		e, _ := p.event(id)
+		return len(e.Details) == 2
+	}, reporter, alice, bob)
+
+	bob.addDetails(id, "Pumps have arrived")
+	waitFor(t, "bob's details", func(p *peer) bool {
+		e, _ := p.event(id)
+		return len(e.Details) == 3
+	}, reporter, alice, bob)
+
+	want := []Detail{
+		{Text: "Water in the underpass", Author: reporter.w.citizenID},
+		{Text: "Buses are rerouted", Author: alice.w.citizenID},
+		{Text: "Pumps have arrived", Author: bob.w.citizenID},
+	}
+	for _, p := range []*peer{reporter, alice, bob} {
+		e, _ := p.event(id)
+		for i, d := range want {
+			if e.Details[i] != d {
+				t.Errorf("%s: detail %d = %+v, want %+v", p.w.citizenID, i, e.Details[i], d)
+			}
+		}
+	}
+
+	e, _ := reporter.event(id)
+	history, err := eventHistory(reporter.w.sh, e.Revision)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("got %d revisions, want 3", len(history))
+	}
+	if history[0].Author != bob.w.citizenID || history[2].Author != reporter.w.citizenID {
+		t.Errorf("revision authors = %s, %s", history[0].Author, history[2].Author)
+	}
+}
+
+func TestForgedUpdateIsDropped(t *testing.T) {
+	n := newFakeNetwork(t)
+	reporter, alice := newPeer(t, n), newPeer(t, n)
+
+	id := reporter.report("Road works", "Lane closed", "Main Street")
+	waitFor(t, "the rumor", hasEvent(id), reporter, alice)
+
+	forged, _ := alice.event(id)
+	forged.ConfirmedBy = 5
+	b, err := json.Marshal(forged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decodeMessage(b); !errors.Is(err, errBadSignature) {
+		t.Fatalf("decodeMessage: got %v, want %v", err, errBadSignature)
+	}
+	err = alice.w.sh.PubSubPublish(topicUpdateEvent, string(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// messages are delivered in order, so once this report arrives the
+	// forged update has been handled
+	next := alice.report("Market moved", "Stalls on the square", "Centre")
+	waitFor(t, "the next rumor", hasEvent(next), reporter)
+
+	e, _ := reporter.event(id)
+	if e.ConfirmedBy != 0 {
+		t.Errorf("forged update applied: ConfirmedBy = %d", e.ConfirmedBy)
+	}
+}
+
+func TestUnsignedUpdateIsDropped(t *testing.T) {
+	n := newFakeNetwork(t)
+	reporter, alice := newPeer(t, n), newPeer(t, n)
+
+	id := reporter.report("Road works", "Lane closed", "Main Street")
+	waitFor(t, "the rumor", hasEvent(id), reporter, alice)
+
+	// a consistent change with the signature stripped instead of forged
+	stripped, _ := alice.event(id)
+	stripped.Witnesses = []string{"x1", "x2"}
+	stripped.ConfirmedBy = 2
+	stripped = withState(stripped)
+	stripped.Signature = ""
+	stripped.Signer = ""
+	b, err := json.Marshal(stripped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decodeMessage(b); err != nil {
+		t.Fatalf("decodeMessage: %v", err)
+	}
+	err = alice.w.sh.PubSubPublish(topicUpdateEvent, string(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next := alice.report("Market moved", "Stalls on the square", "Centre")
+	waitFor(t, "the next rumor", hasEvent(next), reporter)
+
+	e, _ := reporter.event(id)
+	if len(e.Witnesses) != 0 || verifyEvent(e) != nil {
+		t.Errorf("unsigned update applied: %+v", e)
+	}
+}
+
+func TestNewsNeedsWitnesses(t *testing.T) {
+	n := newFakeNetwork(t)
+	reporter, alice := newPeer(t, n), newPeer(t, n)
+
+	id := reporter.report("Road works", "Lane closed", "Main Street")
+	waitFor(t, "the rumor", hasEvent(id), reporter, alice)
+
+	// validly signed by alice, but claiming confirmations nobody gave
+	e, _ := alice.event(id)
+	forgedNews := e
+	forgedNews.ConfirmedBy = 5
+	forgedNews.State = string(stateNews)
+	forgedCount := e
+	forgedCount.ConfirmedBy = 5
+	forgedReport := Event{ID: "forged", Type: eventType, Title: "Fake", Reporter: alice.w.citizenID, ConfirmedBy: 3, State: string(stateNews)}
+	for _, m := range []struct {
+		topic string
+		event Event
+	}{
+		{topicUpdateEvent, forgedNews},
+		{topicUpdateEvent, forgedCount},
+		{topicCreateEvent, forgedReport},
+	} {
+		signed, err := signEvent(m.event, alice.w.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := json.Marshal(signed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = alice.w.sh.PubSubPublish(m.topic, string(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	next := alice.report("Market moved", "Stalls on the square", "Centre")
+	waitFor(t, "the next rumor", hasEvent(next), reporter)
+
+	e, _ = reporter.event(id)
+	if e.state() != stateRumor || e.ConfirmedBy != 0 {
+		t.Errorf("forged confirmations applied: state %s, confirmed by %d", e.state(), e.ConfirmedBy)
+	}
+	if _, ok := reporter.event("forged"); ok {
+		t.Error("report claiming confirmations accepted")
+	}
+}
+
+func TestArchivedEventStaysRetired(t *testing.T) {
+	n := newFakeNetwork(t)
+	reporter, alice := newPeer(t, n), newPeer(t, n)
+
+	id := reporter.report("Road works", "Lane closed", "Main Street")
+	waitFor(t, "the rumor", hasEvent(id), reporter, alice)
+	stale, _ := alice.event(id)
+
+	// the peers run without a lifecycle, so every rumor has expired
+	archived := stale
+	archived.Type = archivedType
+	archived.ArchivedAt = time.Now().Unix()
+	if _, err := reporter.w.putEvent(archived, topicUpdateEvent); err != nil {
+		t.Fatal(err)
+	}
+	retired := func(p *peer) bool {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		r, ok := p.w.retired[id]
+		return ok && r.Type == archivedType && p.w.eventIndex(id) < 0
+	}
+	waitFor(t, "the archive", retired, alice)
+
+	b, err := json.Marshal(stale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = reporter.w.sh.PubSubPublish(topicUpdateEvent, string(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next := reporter.report("Market moved", "Stalls on the square", "Centre")
+	waitFor(t, "the next rumor", hasEvent(next), alice)
+
+	if !retired(alice) {
+		e, _ := alice.event(id)
+		t.Errorf("stale update brought the archived event back: %+v", e)
+	}
+}
+
+func TestLateJoinerLoadsStore(t *testing.T) {
+	n := newFakeNetwork(t)
+	reporter, alice := newPeer(t, n), newPeer(t, n)
+
+	id := reporter.report("Fire alarm", "Building evacuated", "Library")
+	waitFor(t, "the rumor", hasEvent(id), alice)
+	alice.confirm(id)
+	waitFor(t, "the confirmation", func(p *peer) bool {
+		e, _ := p.event(id)
+		return e.ConfirmedBy == 1
+	}, reporter)
+
+	late := newPeer(t, n)
+	e, ok := late.event(id)
+	if !ok {
+		t.Fatal("late peer did not load the event")
+	}
+	if e.ConfirmedBy != 1 || len(e.Witnesses) != 1 {
+		t.Errorf("late peer loaded a stale event: %+v", e)
+	}
+
+	late.w.eventTitle = "Fire alarm"
+	if _, ok := late.w.newReport(time.Now()); ok {
+		t.Error("duplicate title accepted")
+	}
+}
+
+func TestChannelsAreIsolated(t *testing.T) {
+	n := newFakeNetwork(t)
+	berlin := channel{Region: "u33"}
+	berlinTraffic := channel{Region: "u33", Topic: "traffic"}
+
+	global := newPeer(t, n)
+	alice := newChannelPeer(t, n, berlin)
+	bob := newChannelPeer(t, n, berlin)
+	carol := newChannelPeer(t, n, berlinTraffic)
+
+	id := alice.report("Tram stopped", "Power line down", "Alexanderplatz")
+	waitFor(t, "the rumor", hasEvent(id), alice, bob)
+
+	traffic := carol.report("Tram stopped", "Queue of cars", "Alexanderplatz")
+	waitFor(t, "the traffic rumor", hasEvent(traffic), carol)
+
+	if !bob.confirm(id) {
+		t.Fatal("confirmation refused")
+	}
+	waitFor(t, "the confirmation", func(p *peer) bool {
+		e, _ := p.event(id)
+		return e.ConfirmedBy == 1
+	}, alice)
+
+	for _, p := range []*peer{global, carol} {
+		if _, ok := p.event(id); ok {
+			t.Errorf("%s: received an event of channel %s", p.w.channel.name(), berlin.name())
+		}
+	}
+	if _, ok := bob.event(traffic); ok {
+		t.Errorf("%s: received an event of channel %s", berlin.name(), berlinTraffic.name())
+	}
+
+	stored, err := queryEvents(global.w.sh, berlin, eventType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 || stored[0].ID != id || stored[0].Channel != "u33" {
+		t.Errorf("store of %s holds %+v", berlin.name(), stored)
+	}
+
+	late := newChannelPeer(t, n, berlinTraffic)
+	if _, ok := late.event(traffic); !ok {
+		t.Error("late peer did not load the channel's events")
+	}
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+	shell "github.com/stateless-minds/go-ipfs-api"
+)
+
+// maxHistory bounds how many revisions are walked when showing the history
+// of an event.
+const maxHistory = 50
+
+// link is an IPLD link in its dag-json form.
+type link struct {
+	CID string `json:"/"`
+}
+
+// revision is an immutable IPLD node holding one version of an event. Nodes
+// link to the version they replaced, so the chain starting at
+// Event.Revision is the full history of the event.
+type revision struct {
+	Event     Event  `json:"event"`
+	Previous  *link  `json:"previous,omitempty"`
+	Author    string `json:"author"`
+	Timestamp int64  `json:"timestamp"`
+	CID       string `json:"-"`
+}
+
+// recordRevision stores e as a new revision node linking to the revision it
+// replaces and returns e pointing at the new node.
+func recordRevision(sh *shell.Shell, e Event, author string) (Event, error) {
+	r := revision{
+		Event:     e,
+		Author:    author,
+		Timestamp: time.Now().Unix(),
+	}
+	r.Event.Revision = ""
+	if e.Revision != "" {
+		r.Previous = &link{CID: e.Revision}
+	}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		return e, err
+	}
+
+	cid, err := sh.DagPut(b, "dag-json", "dag-cbor")
+	if err != nil {
+		return e, err
+	}
+
+	e.Revision = cid
+	return e, nil
+}
+
+// getRevision fetches a single revision node by CID.
+func getRevision(sh *shell.Shell, cid string) (revision, error) {
+	r := revision{}
+	err := sh.DagGet(cid, &r)
+	if err != nil {
+		return r, err
+	}
+
+	r.CID = cid
+	r.Event.Revision = cid
+	return r, nil
+}
+
+// eventHistory walks the revision chain from cid, newest first.
+func eventHistory(sh *shell.Shell, cid string) ([]revision, error) {
+	var history []revision
+	for cid != "" && len(history) < maxHistory {
+		r, err := getRevision(sh, cid)
+		if err != nil {
+			return history, err
+		}
+		history = append(history, r)
+
+		cid = ""
+		if r.Previous != nil {
+			cid = r.Previous.CID
+		}
+	}
+	return history, nil
+}
+
+// diffEvents describes what changed between two versions of an event in
+// language lang.
+func diffEvents(lang string, old, new Event) []string {
+	t := func(id string) string { return translate(lang, id) }
+
+	var changes []string
+	if old.Title != new.Title {
+		changes = append(changes, fmt.Sprintf("%s: %q → %q", t("event.title"), old.Title, new.Title))
+	}
+	if old.Location != new.Location {
+		changes = append(changes, fmt.Sprintf("%s: %q → %q", t("event.location"), old.Location, new.Location))
+	}
+
+	var oldDetails, newDetails []string
+	for _, d := range old.Details {
+		oldDetails = append(oldDetails, d.Text)
+	}
+	for _, d := range new.Details {
+		newDetails = append(newDetails, d.Text)
+	}
+	for _, d := range missing(newDetails, oldDetails) {
+		changes = append(changes, fmt.Sprintf("%s: %q", t("history.detailsAdded"), d))
+	}
+	for _, d := range missing(oldDetails, newDetails) {
+		changes = append(changes, fmt.Sprintf("%s: %q", t("history.detailsRemoved"), d))
+	}
+
+	if added := missing(new.Tags, old.Tags); len(added) > 0 {
+		changes = append(changes, t("history.tagsAdded")+": "+strings.Join(added, ", "))
+	}
+	if removed := missing(old.Tags, new.Tags); len(removed) > 0 {
+		changes = append(changes, t("history.tagsRemoved")+": "+strings.Join(removed, ", "))
+	}
+
+	if new.MergeInto != old.MergeInto {
+		changes = append(changes, t("history.duplicateOf")+" "+new.MergeInto)
+	}
+	if added := missing(new.MergeVotes, old.MergeVotes); len(added) > 0 {
+		changes = append(changes, t("history.mergeAgreedBy")+": "+strings.Join(added, ", "))
+	}
+	if added := missing(new.MergedFrom, old.MergedFrom); len(added) > 0 {
+		changes = append(changes, t("history.mergedReports")+": "+strings.Join(added, ", "))
+	}
+
+	if added := missing(new.Witnesses, old.Witnesses); len(added) > 0 {
+		changes = append(changes, t("history.witnessesAdded")+": "+strings.Join(added, ", "))
+	}
+	if removed := missing(old.Witnesses, new.Witnesses); len(removed) > 0 {
+		changes = append(changes, t("history.witnessesRemoved")+": "+strings.Join(removed, ", "))
+	}
+	if added := missing(new.Disputes, old.Disputes); len(added) > 0 {
+		changes = append(changes, t("history.disputedBy")+": "+strings.Join(added, ", "))
+	}
+	for _, c := range new.Corrections {
+		if !hasCorrection(old.Corrections, c) {
+			changes = append(changes, fmt.Sprintf("%s: %q", t("history.correctedBy"), c.Reason))
+		}
+	}
+	if new.Retraction != nil && old.Retraction == nil {
+		changes = append(changes, fmt.Sprintf("%s: %q", t("history.retractedBy"), new.Retraction.Reason))
+	}
+	if old.state() != new.state() {
+		changes = append(changes, fmt.Sprintf("%s: %s → %s", t("feed.state"), t("state."+string(old.state())), t("state."+string(new.state()))))
+	}
+	return changes
+}
+
+// missing returns the entries of a that are not in b.
+func missing(a, b []string) []string {
+	seen := make(map[string]int, len(b))
+	for _, v := range b {
+		seen[v]++
+	}
+
+	var out []string
+	for _, v := range a {
+		if seen[v] > 0 {
+			seen[v]--
+			continue
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+func (w *witness) onShowHistory(ctx app.Context, e app.Event) {
+	id := ctx.JSSrc().Get("value").String()
+	n := w.eventIndex(id)
+	if n < 0 {
+		return
+	}
+
+	if _, ok := w.history[id]; ok {
+		w.closeHistory(id)
+		return
+	}
+
+	cid := w.events[n].Revision
+	if cid == "" {
+		w.createNotification(ctx, NotificationInfo, w.t("event.history"), w.t("history.noRevisions"))
+		return
+	}
+
+	ctx.Async(func() {
+		history, err := eventHistory(w.sh, cid)
+		if err != nil {
+			log.Println(err)
+		}
+
+		ctx.Dispatch(func(ctx app.Context) {
+			if err != nil && len(history) == 0 {
+				w.createNotification(ctx, NotificationDanger, w.t(ErrorHeader), w.t("history.loadFailed"))
+				return
+			}
+			w.history[id] = history
+		})
+	})
+}
+
+// resetHistory closes the history panels of all events.
+func (w *witness) resetHistory() {
+	w.history = make(map[string][]revision)
+	w.revisionCID = make(map[string]string)
+	w.fetchedRevision = make(map[string]*revision)
+}
+
+// closeHistory closes the history panel of the event with the given ID
+// and forgets the revision fetched in it.
+func (w *witness) closeHistory(id string) {
+	delete(w.history, id)
+	delete(w.revisionCID, id)
+	delete(w.fetchedRevision, id)
+}
+
+func (w *witness) onRevisionCID(ctx app.Context, e app.Event) {
+	id := ctx.JSSrc().Get("dataset").Get("event").String()
+	w.revisionCID[id] = strings.TrimSpace(ctx.JSSrc().Get("value").String())
+}
+
+func (w *witness) onFetchRevision(ctx app.Context, e app.Event) {
+	id := ctx.JSSrc().Get("value").String()
+	cid := w.revisionCID[id]
+	if cid == "" {
+		return
+	}
+
+	ctx.Async(func() {
+		r, err := getRevision(w.sh, cid)
+		ctx.Dispatch(func(ctx app.Context) {
+			if err != nil {
+				w.createNotification(ctx, NotificationDanger, w.t(ErrorHeader), w.t("history.fetchFailed")+" "+cid+".")
+				log.Println(err)
+				return
+			}
+			if _, ok := w.history[id]; ok {
+				w.fetchedRevision[id] = &r
+			}
+		})
+	})
+}
+
+func (w *witness) renderHistory(id string) app.UI {
+	history, ok := w.history[id]
+
+	return app.If(ok, func() app.UI {
+		return app.Div().Class("p-card").Body(
+			app.H4().Text(w.t("event.history")),
+			app.Range(history).Slice(func(n int) app.UI {
+				r := history[n]
+				var changes []string
+				if n+1 < len(history) {
+					changes = diffEvents(w.language, history[n+1].Event, r.Event)
+				} else if r.Previous == nil {
+					changes = []string{w.t("history.reported")}
+				}
+
+				return app.Div().Class("row").Body(
+					app.P().Class("p-text--small").Body(
+						app.Text(time.Unix(r.Timestamp, 0).Format(time.RFC822)+" "+w.t("history.by")+" "+r.Author+" · "),
+						app.Code().Text(r.CID),
+					),
+					app.Ul().Class("p-list").Body(
+						app.Range(changes).Slice(func(c int) app.UI {
+							return app.Li().Class("p-list__item").Text(changes[c])
+						}),
+					),
+				)
+			}),
+			app.Div().Class("p-form p-form--inline").Body(
+				app.Div().Class("p-form__group").Body(
+					app.Input().Name("revision-cid").DataSet("event", id).Placeholder(w.t("history.revisionCID")).OnKeyUp(w.onRevisionCID),
+				),
+				app.Button().Class("is-dense").Value(id).Text(w.t("history.fetchRevision")).OnClick(w.onFetchRevision),
+			),
+			app.If(w.fetchedRevision[id] != nil, func() app.UI {
+				r := w.fetchedRevision[id]
+				return app.Div().Class("p-card").Body(
+					app.H5().Text(r.Event.Title),
+					app.P().Text(r.Event.Location),
+					app.Range(r.Event.Details).Slice(func(n int) app.UI {
+						return app.P().Text(r.Event.Details[n].Text)
+					}),
+					app.P().Class("p-text--small").Text(fmt.Sprintf("%s %d · %s", w.t("history.confirmedBy"), r.Event.ConfirmedBy, time.Unix(r.Timestamp, 0).Format(time.RFC822))),
+				)
+			}),
+		)
+	})
+}
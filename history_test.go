@@ -0,0 +1,122 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffEvents(t *testing.T) {
+	base := Event{ID: "1", Type: eventType, Title: "Fire", Location: "Main St", Reporter: "10",
+		Details: []Detail{{Text: "smoke", Author: "10"}}, Tags: []string{"fire"}, Witnesses: []string{"11"}, ConfirmedBy: 1}
+	with := func(change func(e *Event)) Event {
+		e := base
+		change(&e)
+		return e
+	}
+
+	tests := []struct {
+		name string
+		old  Event
+		new  Event
+		want []string
+	}{
+		{"nothing changed", base, base, nil},
+		{
+			name: "title and location",
+			old:  base,
+			new:  with(func(e *Event) { e.Title, e.Location = "Flood", "River Rd" }),
+			want: []string{`Title: "Fire" → "Flood"`, `Location: "Main St" → "River Rd"`},
+		},
+		{
+			name: "details",
+			old:  base,
+			new:  with(func(e *Event) { e.Details = []Detail{{Text: "flames"}, {Text: "sirens"}} }),
+			want: []string{`Details added: "flames"`, `Details added: "sirens"`, `Details removed: "smoke"`},
+		},
+		{
+			name: "tags",
+			old:  base,
+			new:  with(func(e *Event) { e.Tags = []string{"traffic", "road"} }),
+			want: []string{"Tags added: traffic, road", "Tags removed: fire"},
+		},
+		{
+			name: "confirmed into news",
+			old:  base,
+			new:  with(func(e *Event) { e.Witnesses, e.ConfirmedBy = []string{"11", "12"}, 2 }),
+			want: []string{"Witnesses added: 12", "State: Rumor → News"},
+		},
+		{
+			name: "witness removed",
+			old:  with(func(e *Event) { e.Witnesses = []string{"11", "12"} }),
+			new:  base,
+			want: []string{"Witnesses removed: 12", "State: News → Rumor"},
+		},
+		{
+			name: "disputed",
+			old:  base,
+			new:  with(func(e *Event) { e.Disputes = []string{"20", "21"} }),
+			want: []string{"Disputed by: 20, 21", "State: Rumor → Disputed"},
+		},
+		{
+			name: "proposed duplicate",
+			old:  base,
+			new:  with(func(e *Event) { e.MergeInto, e.MergeVotes = "2", []string{"10"} }),
+			want: []string{"Proposed duplicate of 2", "Merge agreed by: 10"},
+		},
+		{
+			name: "merged report",
+			old:  base,
+			new:  with(func(e *Event) { e.MergedFrom = []string{"3"} }),
+			want: []string{"Merged reports: 3"},
+		},
+		{
+			name: "corrected",
+			old:  with(func(e *Event) { e.Corrections = []Correction{{Title: "Fire", Reason: "typo", Signature: "a"}} }),
+			new: with(func(e *Event) {
+				e.Corrections = []Correction{{Title: "Fire", Reason: "typo", Signature: "a"}, {Location: "Main Sq", Reason: "wrong street", Signature: "b"}}
+			}),
+			want: []string{`Corrected by the reporter: "wrong street"`},
+		},
+		{
+			name: "retracted",
+			old:  base,
+			new:  with(func(e *Event) { e.Retraction, e.State = &Retraction{Reason: "mistake"}, string(stateRetracted) }),
+			want: []string{`Retracted by the reporter: "mistake"`, "State: Rumor → Retracted"},
+		},
+		{
+			name: "archived",
+			old:  base,
+			new:  with(func(e *Event) { e.Type = archivedType }),
+			want: []string{"State: Rumor → Archived"},
+		},
+	}
+	for _, tt := range tests {
+		if got := diffEvents(defaultLanguage, tt.old, tt.new); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: diffEvents = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	got := diffEvents("de", base, with(func(e *Event) { e.Title, e.Witnesses = "Brand", []string{"11", "12"} }))
+	want := []string{`Titel: "Fire" → "Brand"`, "Zeugen hinzugefügt: 12", "Status: Gerücht → Nachricht"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffEvents in German = %q, want %q", got, want)
+	}
+}
+
+func TestCloseHistory(t *testing.T) {
+	w := &witness{}
+	w.resetHistory()
+	for _, id := range []string{"1", "2"} {
+		w.history[id] = []revision{{CID: "c" + id}}
+		w.revisionCID[id] = "c" + id
+		w.fetchedRevision[id] = &revision{CID: "c" + id}
+	}
+
+	w.closeHistory("1")
+	if _, ok := w.history["1"]; ok || w.revisionCID["1"] != "" || w.fetchedRevision["1"] != nil {
+		t.Error("closed panel kept its history or fetched revision")
+	}
+	if w.fetchedRevision["2"] == nil || w.fetchedRevision["2"].CID != "c2" || w.revisionCID["2"] != "c2" {
+		t.Errorf("closing one panel changed another, fetched %+v", w.fetchedRevision)
+	}
+}
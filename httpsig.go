@@ -0,0 +1,210 @@
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// HTTP signatures as used by ActivityPub servers: the draft-cavage scheme
+// with rsa-sha256 over the request target, host, date and body digest.
+
+var errBadHTTPSignature = errors.New("HTTP signature is invalid")
+
+// signedHeaders are the headers covered by outgoing signatures.
+const signedHeaders = "(request-target) host date digest"
+
+// maxClockSkew bounds how far the date of a signed request may be from now.
+const maxClockSkew = 12 * time.Hour
+
+var signatureParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// bodyDigest returns the Digest header value of body.
+func bodyDigest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// signingString returns what a signature covering headers signs.
+func signingString(req *http.Request, headers []string) (string, error) {
+	lines := make([]string, 0, len(headers))
+	for _, h := range headers {
+		switch h {
+		case "(request-target)":
+			lines = append(lines, h+": "+strings.ToLower(req.Method)+" "+req.URL.RequestURI())
+		case "host":
+			host := req.Host
+			if host == "" {
+				host = req.URL.Host
+			}
+			lines = append(lines, h+": "+host)
+		default:
+			v := req.Header.Get(h)
+			if v == "" {
+				return "", fmt.Errorf("%w: header %s is missing", errBadHTTPSignature, h)
+			}
+			lines = append(lines, h+": "+v)
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// signRequest adds the Date, Digest and Signature headers to req, whose
+// body is body, signed with the key named keyID.
+func signRequest(req *http.Request, keyID string, key *rsa.PrivateKey, body []byte) error {
+	req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("Digest", bodyDigest(body))
+
+	s, err := signingString(req, strings.Fields(signedHeaders))
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256([]byte(s))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Signature", fmt.Sprintf(`keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		keyID, signedHeaders, base64.StdEncoding.EncodeToString(sig)))
+	return nil
+}
+
+// verifyRequest checks the signature of req, whose body is body, with the
+// key returned by publicKey for the signature's key ID. It returns the key
+// ID.
+func verifyRequest(req *http.Request, body []byte, publicKey func(keyID string) (*rsa.PublicKey, error)) (string, error) {
+	params := map[string]string{}
+	for _, m := range signatureParam.FindAllStringSubmatch(req.Header.Get("Signature"), -1) {
+		params[m[1]] = m[2]
+	}
+	keyID := params["keyId"]
+	if keyID == "" || params["signature"] == "" {
+		return "", fmt.Errorf("%w: no signature", errBadHTTPSignature)
+	}
+
+	headers := strings.Fields(params["headers"])
+	if len(headers) == 0 {
+		headers = []string{"date"}
+	}
+	for _, required := range []string{"(request-target)", "host", "date", "digest"} {
+		if !contains(headers, required) {
+			return "", fmt.Errorf("%w: %s is not signed", errBadHTTPSignature, required)
+		}
+	}
+	if req.Header.Get("Digest") != bodyDigest(body) {
+		return "", fmt.Errorf("%w: digest doesn't match the body", errBadHTTPSignature)
+	}
+	date, err := http.ParseTime(req.Header.Get("Date"))
+	if err != nil || time.Since(date).Abs() > maxClockSkew {
+		return "", fmt.Errorf("%w: date is missing or too far from now", errBadHTTPSignature)
+	}
+
+	s, err := signingString(req, headers)
+	if err != nil {
+		return "", err
+	}
+	sig, err := base64.StdEncoding.DecodeString(params["signature"])
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errBadHTTPSignature, err)
+	}
+
+	pub, err := publicKey(keyID)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(s))
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, sum[:], sig); err != nil {
+		return "", fmt.Errorf("%w: %v", errBadHTTPSignature, err)
+	}
+	return keyID, nil
+}
+
+// encodePublicKey returns the PEM encoding of key as published in actor
+// documents.
+func encodePublicKey(key *rsa.PublicKey) (string, error) {
+	b, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: b})), nil
+}
+
+// decodePublicKey parses a PEM encoded RSA public key.
+func decodePublicKey(s string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil {
+		return nil, errors.New("no PEM encoded public key")
+	}
+
+	k, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		k, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+	pub, ok := k.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not an RSA key")
+	}
+	return pub, nil
+}
+
+// loadFederationKey returns the RSA key the server signs ActivityPub
+// requests with, kept next to the command line signing key and created on
+// first use.
+func loadFederationKey() (*rsa.PrivateKey, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, "cyber-witness", "activitypub.pem")
+
+	b, err := os.ReadFile(path)
+	if err == nil {
+		block, _ := pem.Decode(b)
+		if block == nil {
+			return nil, fmt.Errorf("%s holds no PEM encoded key", path)
+		}
+		k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := k.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%s holds no RSA key", path)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		return nil, err
+	}
+	return key, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600)
+}
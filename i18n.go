@@ -0,0 +1,1130 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+// languageStorageKey is the local storage key of the language the citizen
+// picked for the interface.
+const languageStorageKey = "language"
+
+// defaultLanguage is used when none of the browser languages has a catalog.
+// Its catalog is the reference the others are checked against.
+const defaultLanguage = "en"
+
+// messages maps message IDs to the text shown for them.
+type messages map[string]string
+
+// catalogs holds the interface text of every supported language, keyed by
+// ISO 639-1 code. A message missing from a catalog falls back to English.
+var catalogs = map[string]messages{
+	"en": {
+		"hero.title":         "Cyber Witness - the news as they should be",
+		"hero.intro":         "P2P community of independent reporters and witnesses - an alternative to mass media. Reporters publish events they have personally seen with no interpretation. Until confirmed they show up as rumors. Witnesses confirm rumors they have witnessed and add their own details. Event details aggregate and become more accurate with the input of each new witness. Once a rumor has been confirmed by at least 2 witnesses it becomes news. The more witnesses the greater accuracy of news.",
+		"hero.howItWorks":    "How it works",
+		"hero.muteList":      "Mute list",
+		"hero.backup":        "Backup",
+		"hero.notifications": "Notifications",
+		"hero.language":      "Language",
+		"hero.digest":        "Load news digest",
+
+		"report.heading":  "Have an event to report?",
+		"report.help":     "Check rumors first as it may already exist.",
+		"report.evidence": "Optional Image/Video Evidence",
+		"report.language": "Written in",
+		"report.tags":     "Tags",
+		"report.tagsHint": "Comma separated, e.g. protest, traffic",
+		"report.submit":   "Report event",
+
+		"witness.heading":       "Been a witness of an event?",
+		"witness.confirmRumors": "Confirm rumors",
+		"reader.heading":        "Just want to read the news?",
+		"reader.intro":          "Your personal news feed at your fingertips. All witnessed. No ads, paywalls, censorship or fact checkers.",
+		"reader.readNews":       "Read the news",
+		"reader.archive":        "Archive",
+
+		"feed.rumors":          "Rumors",
+		"feed.news":            "News",
+		"feed.noRumors":        "No recent rumors",
+		"feed.noNews":          "No recent news",
+		"feed.emptyHint":       "Check back later or report an event",
+		"feed.language":        "Language",
+		"feed.allLanguages":    "All languages",
+		"feed.tag":             "Tag",
+		"feed.allTags":         "All tags",
+		"feed.followedTags":    "Followed tags",
+		"feed.followTag":       "Follow tag",
+		"feed.unfollowTag":     "Unfollow tag",
+		"feed.tagsSaveFailed":  "Could not save followed tags.",
+		"feed.state":           "State",
+		"feed.allStates":       "All states",
+		"feed.export":          "Export",
+		"feed.exportFailed":    "Could not export events.",
+		"feed.action":          "Action",
+		"feed.confirmedBy":     "Confirmed By",
+		"modal.close":          "Close active modal",
+		"event.title":          "Title",
+		"event.details":        "Details",
+		"event.location":       "Location",
+		"event.evidence":       "Evidence",
+		"event.confirm":        "Confirm",
+		"event.show":           "Show",
+		"event.hide":           "Hide",
+		"event.history":        "History",
+		"event.link":           "Link",
+		"event.muteReporter":   "Mute reporter",
+		"event.newDetails":     "Add new details: ",
+		"event.addDetails":     "Add details",
+		"event.dispute":        "Dispute",
+		"event.corrected":      "Corrected",
+		"event.watch":          "Follow",
+		"event.unwatch":        "Unfollow",
+		"event.audit":          "Audit log",
+		"event.heading":        "Event",
+		"event.notFound":       "This event could not be found. It may belong to a channel you don't follow or not have reached your node yet.",
+		"event.muted":          "This event is hidden by your mute list.",
+		"event.confirmedBy":    "confirmed by",
+		"event.submitted":      "Event submitted.",
+		"event.createFailed":   "Could not create event. Try again later.",
+		"event.detailsAdded":   "Event details added.",
+		"event.detailsFailed":  "Could not add details. Try again later.",
+		"event.rumorConfirmed": "Rumor confirmed.",
+		"event.confirmFailed":  "Could not confirm rumor. Try again later.",
+		"event.disputed":       "Event disputed.",
+		"event.disputeFailed":  "Could not dispute event. Try again later.",
+
+		"status.syncing":      "Syncing",
+		"status.connected":    "Connected",
+		"status.reconnecting": "Reconnecting",
+		"status.connecting":   "Connecting",
+		"status.offline":      "Offline",
+
+		"state.rumor":      "Rumor",
+		"state.news":       "News",
+		"state.disputed":   "Disputed",
+		"state.disputedBy": "citizens dispute this event",
+		"state.retracted":  "Retracted",
+		"state.merged":     "Merged",
+		"state.archived":   "Archived",
+
+		"sync.inSync":    "in sync",
+		"sync.divergent": "buckets out of sync",
+		"sync.rounds":    "rounds in sync",
+		"sync.recovered": "events recovered",
+		"sync.sent":      "events sent",
+
+		"audit.time":              "Time",
+		"audit.operation":         "Operation",
+		"audit.actor":             "Citizen",
+		"audit.state":             "State",
+		"audit.signature":         "Signature",
+		"audit.valid":             "Valid",
+		"audit.invalid":           "Invalid",
+		"audit.export":            "Export audit log",
+		"audit.exportFailed":      "Could not export audit log.",
+		"audit.notFound":          "The event of this audit log is no longer loaded.",
+		"audit.op.report":         "Reported",
+		"audit.op.confirm":        "Confirmed",
+		"audit.op.detail":         "Added details",
+		"audit.op.dispute":        "Disputed",
+		"audit.op.correction":     "Corrected",
+		"audit.op.retraction":     "Retracted",
+		"audit.op.merge-proposal": "Proposed merge into",
+		"audit.op.merge-vote":     "Agreed to merge into",
+		"audit.op.merge":          "Merged into",
+		"audit.op.merged-from":    "Merged report",
+
+		"digest.from":       "digest from",
+		"digest.instead":    "showing the latest news digest instead.",
+		"digest.header":     "News digest",
+		"digest.loaded":     "news loaded from the digest of",
+		"digest.loadFailed": "Could not load the news digest. Try again later.",
+
+		"daily.title":     "Daily digest",
+		"daily.topRumors": "Top rumors",
+
+		"mute.description":  "Muted citizens, keywords and locations are hidden from your rumors and news. The list is stored on your device only and never changes what others see.",
+		"mute.keyword":      "Keyword",
+		"mute.location":     "Location",
+		"mute.citizen":      "Citizen ID",
+		"mute.mute":         "Mute",
+		"mute.unmute":       "Unmute",
+		"mute.citizens":     "Citizens",
+		"mute.keywords":     "Keywords",
+		"mute.locations":    "Locations",
+		"mute.import":       "Import",
+		"mute.export":       "Export",
+		"mute.muted":        "Muted",
+		"mute.citizenMuted": "Reports and details from this citizen are now hidden.",
+		"mute.imported":     "Mute list imported.",
+		"mute.readFailed":   "Could not read mute list.",
+		"mute.saveFailed":   "Could not save mute list.",
+
+		"archive.status":       "Status",
+		"archive.expiredRumor": "Expired rumor",
+		"archive.empty":        "The archive is empty",
+		"archive.emptyHint":    "Rumors that are never confirmed and old news end up here",
+		"archive.loadFailed":   "Could not load the archive. Try again later.",
+
+		"backup.description":    "Export the events of the channels you follow as JSON Lines or as a CAR archive including revisions and evidence. Imports are checked like live updates and merged with the events you already have.",
+		"backup.lastImport":     "Last import",
+		"backup.added":          "added",
+		"backup.merged":         "merged",
+		"backup.unchanged":      "unchanged",
+		"backup.unsigned":       "unsigned",
+		"backup.rejectedCount":  "rejected",
+		"backup.conflicts":      "conflicts",
+		"backup.rejected":       "Rejected",
+		"backup.conflict":       "Conflict",
+		"backup.import":         "Import",
+		"backup.exportCAR":      "Export CAR",
+		"backup.exportJSONL":    "Export JSON Lines",
+		"backup.importFinished": "Import finished:",
+		"backup.importFailed":   "Could not import events:",
+		"backup.exportFailed":   "Could not export events. Try again later.",
+
+		"channel.label":        "Channel",
+		"channel.manage":       "Channels",
+		"channel.description":  "Follow the regions and topics you care about. A region is a geohash prefix: the shorter it is, the larger the area. Reports go to the channel you are viewing.",
+		"channel.regionHint":   "Region, e.g. u33",
+		"channel.locate":       "Use my location",
+		"channel.topicHint":    "Optional topic, e.g. traffic",
+		"channel.follow":       "Follow",
+		"channel.unfollow":     "Unfollow",
+		"channel.global":       "Global",
+		"channel.loadFailed":   "Could not load events of",
+		"channel.saveFailed":   "Could not save channels.",
+		"channel.badRegion":    "A region is a geohash made of digits and the letters b-z without i, l and o.",
+		"channel.noLocation":   "Your browser does not share its location. Enter a geohash instead.",
+		"channel.locateFailed": "Could not get your location. Enter a geohash instead.",
+
+		"history.reported":         "Reported",
+		"history.by":               "by",
+		"history.confirmedBy":      "Confirmed by",
+		"history.revisionCID":      "Revision CID",
+		"history.fetchRevision":    "Fetch revision",
+		"history.detailsAdded":     "Details added",
+		"history.detailsRemoved":   "Details removed",
+		"history.tagsAdded":        "Tags added",
+		"history.tagsRemoved":      "Tags removed",
+		"history.duplicateOf":      "Proposed duplicate of",
+		"history.mergeAgreedBy":    "Merge agreed by",
+		"history.mergedReports":    "Merged reports",
+		"history.witnessesAdded":   "Witnesses added",
+		"history.witnessesRemoved": "Witnesses removed",
+		"history.disputedBy":       "Disputed by",
+		"history.correctedBy":      "Corrected by the reporter",
+		"history.retractedBy":      "Retracted by the reporter",
+		"history.noRevisions":      "This event was reported before revisions were recorded.",
+		"history.loadFailed":       "Could not load history. Try again later.",
+		"history.fetchFailed":      "Could not fetch revision",
+
+		"notifications.none":     "No notifications yet.",
+		"notifications.clear":    "Clear history",
+		"notifications.dismiss":  "Dismiss notification",
+		"notifications.close":    "Close",
+		"notifications.more":     "more",
+		"notifications.success":  "Success",
+		"notifications.error":    "Error",
+		"notifications.tryLater": "Try again later.",
+
+		"correction.heading":         "Correct or retract your report",
+		"correction.description":     "Description",
+		"correction.reason":          "Reason",
+		"correction.publish":         "Publish correction",
+		"correction.retract":         "Retract report",
+		"correction.corrected":       "Corrected by the reporter",
+		"correction.retracted":       "Retracted by the reporter",
+		"correction.original":        "Originally reported as",
+		"correction.at":              "at",
+		"correction.header":          "Correction",
+		"correction.unchanged":       "Change the title, location or description to publish a correction.",
+		"correction.published":       "Correction published.",
+		"correction.publishFailed":   "Could not publish correction. Try again later.",
+		"correction.retractedReport": "Report retracted.",
+		"correction.retractFailed":   "Could not retract report. Try again later.",
+
+		"duplicate.sameAs":      "Same event as",
+		"duplicate.choose":      "Choose a report",
+		"duplicate.propose":     "Propose merge",
+		"duplicate.heading":     "Proposed duplicate",
+		"duplicate.mergedInto":  "would be merged into",
+		"duplicate.agreed":      "agreed so far",
+		"duplicate.quorum":      "participants of each report are needed",
+		"duplicate.agree":       "Agree",
+		"duplicate.merge":       "Merge",
+		"duplicate.noTarget":    "Choose the report describing the same event.",
+		"duplicate.notAllowed":  "These reports can't be merged. Only their reporters and witnesses can propose a merge, one at a time.",
+		"duplicate.proposed":    "Merge proposal recorded.",
+		"duplicate.merged":      "Reports merged into",
+		"duplicate.mergeFailed": "Could not merge reports. Try again later.",
+
+		"watch.header":        "Followed event",
+		"watch.heading":       "Followed events",
+		"watch.none":          "You don't follow any event of this channel. Events you report or confirm are followed automatically.",
+		"watch.browser":       "Also show browser notifications",
+		"watch.confirmation":  "new confirmation",
+		"watch.confirmations": "new confirmations",
+		"watch.detail":        "new detail",
+		"watch.details":       "new details",
+		"watch.dispute":       "new dispute",
+		"watch.disputes":      "new disputes",
+		"watch.becameNews":    "became news",
+		"watch.isNow":         "is now",
+		"watch.saveFailed":    "Could not save followed events.",
+		"watch.unsupported":   "Your browser does not support notifications.",
+		"watch.denied":        "Browser notifications were not allowed.",
+
+		"howto.heading":     "How to play",
+		"howto.what":        "What is Cyber Witness",
+		"howto.whatText":    "Cyber Witness is a p2p media simulator based on the reporter and witnesses concept.",
+		"howto.problem":     "What's the problem with mass media?",
+		"howto.problemText": "It's centralized, censored, fact-checked, unaccountable and non-trasparent.",
+		"howto.replace":     "How Cyber Witness replaces mass media",
+		"howto.replaceText": "By switching to p2p interactions and the reporter and witnesses model we emulate a transparent environment with a feedback loop.",
+		"howto.features":    "Features",
+		"howto.support":     "Support us",
+
+		"feature.report":          "Report events",
+		"feature.reportText":      "Provide details about the event.",
+		"feature.confirmText":     "Browse reported events, confirm what you have witnessed and provide more details.",
+		"feature.aggregation":     "Event details aggregation",
+		"feature.aggregationText": "The more witnesses the better the accuracy the higher the chance a rumor is real news.",
+		"feature.read":            "Read the real news",
+		"feature.anonymity":       "Anonymity by default",
+		"feature.anonymityText":   "Anonymity guarantees everyone is protected.",
+		"feature.flat":            "Flat interactions",
+		"feature.flatText":        "No centralized control, no fact checkers and no ads.",
+
+		"terms.heading":     "Terms of service",
+		"terms.intro":       "Introduction",
+		"terms.introText":   "Cyber Witness is a p2p media simulator based on the reporter and witnesses concept in the form of a fictional game based on real-time data. By using the application you are implicitly agreeing to share your peer id with the IPFS public network.",
+		"terms.hosting":     "Application Hosting",
+		"terms.hostingText": "Cyber Witness is a decentralized application and is hosted on a public peer to peer network. By using the application you agree to host it on the public IPFS network free of charge for as long as your usage is.",
+		"terms.content":     "User-Generated Content",
+		"terms.contentText": "All published content is user-generated, fictional and creators are not responsible for it.",
+
+		"privacy.heading":     "Privacy policy",
+		"privacy.data":        "Personal data",
+		"privacy.dataText":    "There is no personal information collected within Cyber Witness. We store a small portion of your peer ID encrypted as a non-unique identifier which is used for displaying the ranks interface.",
+		"privacy.cookies":     "Cookies",
+		"privacy.cookiesText": "Cyber Witness does not use cookies.",
+		"privacy.changes":     "Changes to this privacy policy",
+		"privacy.changesText": "This Privacy Policy might be updated from time to time. Thus, it is advised to review this page periodically for any changes. You will be notified of any changes from this page. Changes are effective immediately after they are posted on this page.",
+	},
+	"es": {
+		"hero.title":         "Cyber Witness - las noticias como deberían ser",
+		"hero.intro":         "Comunidad P2P de reporteros y testigos independientes, una alternativa a los medios de masas. Los reporteros publican sucesos que han visto en persona, sin interpretación. Hasta que se confirman aparecen como rumores. Los testigos confirman los rumores que han presenciado y añaden sus propios detalles. Los detalles de un suceso se acumulan y son más precisos con cada nuevo testigo. Cuando al menos 2 testigos confirman un rumor, se convierte en noticia. Cuantos más testigos, más precisas son las noticias.",
+		"hero.howItWorks":    "Cómo funciona",
+		"hero.muteList":      "Lista de silenciados",
+		"hero.backup":        "Copia de seguridad",
+		"hero.notifications": "Notificaciones",
+		"hero.language":      "Idioma",
+		"hero.digest":        "Cargar resumen de noticias",
+
+		"report.heading":  "¿Tienes un suceso que informar?",
+		"report.help":     "Revisa primero los rumores, puede que ya exista.",
+		"report.evidence": "Prueba opcional en imagen o vídeo",
+		"report.language": "Escrito en",
+		"report.tags":     "Etiquetas",
+		"report.tagsHint": "Separadas por comas, p. ej. protest, traffic",
+		"report.submit":   "Informar del suceso",
+
+		"witness.heading":       "¿Has sido testigo de un suceso?",
+		"witness.confirmRumors": "Confirmar rumores",
+		"reader.heading":        "¿Solo quieres leer las noticias?",
+		"reader.intro":          "Tus noticias personales al alcance de la mano. Todas presenciadas. Sin anuncios, muros de pago, censura ni verificadores.",
+		"reader.readNews":       "Leer las noticias",
+		"reader.archive":        "Archivo",
+
+		"feed.rumors":          "Rumores",
+		"feed.news":            "Noticias",
+		"feed.noRumors":        "No hay rumores recientes",
+		"feed.noNews":          "No hay noticias recientes",
+		"feed.emptyHint":       "Vuelve más tarde o informa de un suceso",
+		"feed.language":        "Idioma",
+		"feed.allLanguages":    "Todos los idiomas",
+		"feed.tag":             "Etiqueta",
+		"feed.allTags":         "Todas las etiquetas",
+		"feed.followedTags":    "Etiquetas seguidas",
+		"feed.followTag":       "Seguir etiqueta",
+		"feed.unfollowTag":     "Dejar de seguir",
+		"feed.tagsSaveFailed":  "No se pudieron guardar las etiquetas seguidas.",
+		"feed.state":           "Estado",
+		"feed.allStates":       "Todos los estados",
+		"feed.export":          "Exportar",
+		"feed.exportFailed":    "No se pudieron exportar los sucesos.",
+		"feed.action":          "Acción",
+		"feed.confirmedBy":     "Confirmado por",
+		"modal.close":          "Cerrar la ventana activa",
+		"event.title":          "Título",
+		"event.details":        "Detalles",
+		"event.location":       "Lugar",
+		"event.evidence":       "Prueba",
+		"event.confirm":        "Confirmar",
+		"event.show":           "Mostrar",
+		"event.hide":           "Ocultar",
+		"event.history":        "Historial",
+		"event.link":           "Enlace",
+		"event.muteReporter":   "Silenciar al reportero",
+		"event.newDetails":     "Añadir detalles nuevos: ",
+		"event.addDetails":     "Añadir detalles",
+		"event.dispute":        "Disputar",
+		"event.corrected":      "Corregido",
+		"event.watch":          "Seguir",
+		"event.unwatch":        "Dejar de seguir",
+		"event.audit":          "Registro de auditoría",
+		"event.heading":        "Suceso",
+		"event.notFound":       "No se encontró este suceso. Puede pertenecer a un canal que no sigues o aún no haber llegado a tu nodo.",
+		"event.muted":          "Este suceso está oculto por tu lista de silenciados.",
+		"event.confirmedBy":    "confirmado por",
+		"event.submitted":      "Suceso enviado.",
+		"event.createFailed":   "No se pudo crear el suceso. Inténtalo más tarde.",
+		"event.detailsAdded":   "Detalles del suceso añadidos.",
+		"event.detailsFailed":  "No se pudieron añadir los detalles. Inténtalo más tarde.",
+		"event.rumorConfirmed": "Rumor confirmado.",
+		"event.confirmFailed":  "No se pudo confirmar el rumor. Inténtalo más tarde.",
+		"event.disputed":       "Suceso disputado.",
+		"event.disputeFailed":  "No se pudo disputar el suceso. Inténtalo más tarde.",
+
+		"status.syncing":      "Sincronizando",
+		"status.connected":    "Conectado",
+		"status.reconnecting": "Reconectando",
+		"status.connecting":   "Conectando",
+		"status.offline":      "Sin conexión",
+
+		"state.rumor":      "Rumor",
+		"state.news":       "Noticia",
+		"state.disputed":   "Disputado",
+		"state.disputedBy": "ciudadanos disputan este suceso",
+		"state.retracted":  "Retirado",
+		"state.merged":     "Fusionado",
+		"state.archived":   "Archivado",
+
+		"sync.inSync":    "sincronizado",
+		"sync.divergent": "grupos sin sincronizar",
+		"sync.rounds":    "rondas sincronizadas",
+		"sync.recovered": "sucesos recuperados",
+		"sync.sent":      "sucesos enviados",
+
+		"audit.time":              "Hora",
+		"audit.operation":         "Operación",
+		"audit.actor":             "Ciudadano",
+		"audit.state":             "Estado",
+		"audit.signature":         "Firma",
+		"audit.valid":             "Válida",
+		"audit.invalid":           "Inválida",
+		"audit.export":            "Exportar registro",
+		"audit.exportFailed":      "No se pudo exportar el registro de auditoría.",
+		"audit.notFound":          "El suceso de este registro de auditoría ya no está cargado.",
+		"audit.op.report":         "Reportado",
+		"audit.op.confirm":        "Confirmado",
+		"audit.op.detail":         "Detalles añadidos",
+		"audit.op.dispute":        "Disputado",
+		"audit.op.correction":     "Corregido",
+		"audit.op.retraction":     "Retirado",
+		"audit.op.merge-proposal": "Fusión propuesta con",
+		"audit.op.merge-vote":     "Fusión aceptada con",
+		"audit.op.merge":          "Fusionado con",
+		"audit.op.merged-from":    "Reporte fusionado",
+
+		"digest.from":       "resumen del",
+		"digest.instead":    "se muestra el último resumen de noticias.",
+		"digest.header":     "Resumen de noticias",
+		"digest.loaded":     "noticias cargadas del resumen del",
+		"digest.loadFailed": "No se pudo cargar el resumen de noticias. Inténtalo más tarde.",
+
+		"daily.title":     "Resumen diario",
+		"daily.topRumors": "Rumores principales",
+
+		"mute.description":  "Los ciudadanos, palabras clave y lugares silenciados se ocultan de tus rumores y noticias. La lista solo se guarda en tu dispositivo y nunca cambia lo que ven los demás.",
+		"mute.keyword":      "Palabra clave",
+		"mute.location":     "Lugar",
+		"mute.citizen":      "ID de ciudadano",
+		"mute.mute":         "Silenciar",
+		"mute.unmute":       "Dejar de silenciar",
+		"mute.citizens":     "Ciudadanos",
+		"mute.keywords":     "Palabras clave",
+		"mute.locations":    "Lugares",
+		"mute.import":       "Importar",
+		"mute.export":       "Exportar",
+		"mute.muted":        "Silenciado",
+		"mute.citizenMuted": "Los informes y detalles de este ciudadano ya están ocultos.",
+		"mute.imported":     "Lista de silenciados importada.",
+		"mute.readFailed":   "No se pudo leer la lista de silenciados.",
+		"mute.saveFailed":   "No se pudo guardar la lista de silenciados.",
+
+		"archive.status":       "Estado",
+		"archive.expiredRumor": "Rumor caducado",
+		"archive.empty":        "El archivo está vacío",
+		"archive.emptyHint":    "Aquí terminan los rumores que nunca se confirman y las noticias antiguas",
+		"archive.loadFailed":   "No se pudo cargar el archivo. Inténtalo más tarde.",
+
+		"backup.description":    "Exporta los sucesos de los canales que sigues como JSON Lines o como archivo CAR con revisiones y pruebas. Las importaciones se comprueban como las actualizaciones en directo y se combinan con los sucesos que ya tienes.",
+		"backup.lastImport":     "Última importación",
+		"backup.added":          "añadidos",
+		"backup.merged":         "combinados",
+		"backup.unchanged":      "sin cambios",
+		"backup.unsigned":       "sin firma",
+		"backup.rejectedCount":  "rechazados",
+		"backup.conflicts":      "conflictos",
+		"backup.rejected":       "Rechazado",
+		"backup.conflict":       "Conflicto",
+		"backup.import":         "Importar",
+		"backup.exportCAR":      "Exportar CAR",
+		"backup.exportJSONL":    "Exportar JSON Lines",
+		"backup.importFinished": "Importación terminada:",
+		"backup.importFailed":   "No se pudieron importar los sucesos:",
+		"backup.exportFailed":   "No se pudieron exportar los sucesos. Inténtalo más tarde.",
+
+		"channel.label":        "Canal",
+		"channel.manage":       "Canales",
+		"channel.description":  "Sigue las regiones y temas que te interesan. Una región es un prefijo geohash: cuanto más corto, mayor es la zona. Los informes van al canal que estás viendo.",
+		"channel.regionHint":   "Región, p. ej. u33",
+		"channel.locate":       "Usar mi ubicación",
+		"channel.topicHint":    "Tema opcional, p. ej. tráfico",
+		"channel.follow":       "Seguir",
+		"channel.unfollow":     "Dejar de seguir",
+		"channel.global":       "Global",
+		"channel.loadFailed":   "No se pudieron cargar los sucesos de",
+		"channel.saveFailed":   "No se pudieron guardar los canales.",
+		"channel.badRegion":    "Una región es un geohash formado por dígitos y las letras b-z sin i, l ni o.",
+		"channel.noLocation":   "Tu navegador no comparte su ubicación. Introduce un geohash en su lugar.",
+		"channel.locateFailed": "No se pudo obtener tu ubicación. Introduce un geohash en su lugar.",
+
+		"history.reported":         "Informado",
+		"history.by":               "por",
+		"history.confirmedBy":      "Confirmado por",
+		"history.revisionCID":      "CID de la revisión",
+		"history.fetchRevision":    "Obtener revisión",
+		"history.detailsAdded":     "Detalles añadidos",
+		"history.detailsRemoved":   "Detalles eliminados",
+		"history.tagsAdded":        "Etiquetas añadidas",
+		"history.tagsRemoved":      "Etiquetas eliminadas",
+		"history.duplicateOf":      "Propuesto como duplicado de",
+		"history.mergeAgreedBy":    "Fusión aceptada por",
+		"history.mergedReports":    "Informes fusionados",
+		"history.witnessesAdded":   "Testigos añadidos",
+		"history.witnessesRemoved": "Testigos eliminados",
+		"history.disputedBy":       "Disputado por",
+		"history.correctedBy":      "Corregido por quien informó",
+		"history.retractedBy":      "Retirado por quien informó",
+		"history.noRevisions":      "Este suceso se informó antes de que se registraran revisiones.",
+		"history.loadFailed":       "No se pudo cargar el historial. Inténtalo más tarde.",
+		"history.fetchFailed":      "No se pudo obtener la revisión",
+
+		"notifications.none":     "Todavía no hay notificaciones.",
+		"notifications.clear":    "Borrar historial",
+		"notifications.dismiss":  "Descartar notificación",
+		"notifications.close":    "Cerrar",
+		"notifications.more":     "más",
+		"notifications.success":  "Éxito",
+		"notifications.error":    "Error",
+		"notifications.tryLater": "Inténtalo más tarde.",
+
+		"correction.heading":         "Corrige o retira tu informe",
+		"correction.description":     "Descripción",
+		"correction.reason":          "Motivo",
+		"correction.publish":         "Publicar corrección",
+		"correction.retract":         "Retirar informe",
+		"correction.corrected":       "Corregido por quien informó",
+		"correction.retracted":       "Retirado por quien informó",
+		"correction.original":        "Informado originalmente como",
+		"correction.at":              "en",
+		"correction.header":          "Corrección",
+		"correction.unchanged":       "Cambia el título, el lugar o la descripción para publicar una corrección.",
+		"correction.published":       "Corrección publicada.",
+		"correction.publishFailed":   "No se pudo publicar la corrección. Inténtalo más tarde.",
+		"correction.retractedReport": "Informe retirado.",
+		"correction.retractFailed":   "No se pudo retirar el informe. Inténtalo más tarde.",
+
+		"duplicate.sameAs":      "Mismo suceso que",
+		"duplicate.choose":      "Elige un informe",
+		"duplicate.propose":     "Proponer fusión",
+		"duplicate.heading":     "Posible duplicado",
+		"duplicate.mergedInto":  "se fusionaría con",
+		"duplicate.agreed":      "de acuerdo hasta ahora",
+		"duplicate.quorum":      "participantes de cada informe son necesarios",
+		"duplicate.agree":       "De acuerdo",
+		"duplicate.merge":       "Fusión",
+		"duplicate.noTarget":    "Elige el informe que describe el mismo suceso.",
+		"duplicate.notAllowed":  "Estos informes no se pueden fusionar. Solo quienes informaron y los testigos pueden proponer una fusión, de una en una.",
+		"duplicate.proposed":    "Propuesta de fusión registrada.",
+		"duplicate.merged":      "Informes fusionados en",
+		"duplicate.mergeFailed": "No se pudieron fusionar los informes. Inténtalo más tarde.",
+
+		"watch.header":        "Suceso seguido",
+		"watch.heading":       "Sucesos seguidos",
+		"watch.none":          "No sigues ningún suceso de este canal. Los sucesos que informas o confirmas se siguen automáticamente.",
+		"watch.browser":       "Mostrar también notificaciones del navegador",
+		"watch.confirmation":  "nueva confirmación",
+		"watch.confirmations": "nuevas confirmaciones",
+		"watch.detail":        "nuevo detalle",
+		"watch.details":       "nuevos detalles",
+		"watch.dispute":       "nueva disputa",
+		"watch.disputes":      "nuevas disputas",
+		"watch.becameNews":    "se convirtió en noticia",
+		"watch.isNow":         "ahora está",
+		"watch.saveFailed":    "No se pudieron guardar los sucesos seguidos.",
+		"watch.unsupported":   "Tu navegador no admite notificaciones.",
+		"watch.denied":        "No se permitieron las notificaciones del navegador.",
+
+		"howto.heading":     "Cómo jugar",
+		"howto.what":        "Qué es Cyber Witness",
+		"howto.whatText":    "Cyber Witness es un simulador de medios P2P basado en el concepto de reportero y testigos.",
+		"howto.problem":     "¿Cuál es el problema de los medios de masas?",
+		"howto.problemText": "Son centralizados, censurados, verificados por terceros, no rinden cuentas y no son transparentes.",
+		"howto.replace":     "Cómo sustituye Cyber Witness a los medios de masas",
+		"howto.replaceText": "Al pasar a interacciones P2P y al modelo de reportero y testigos emulamos un entorno transparente con un ciclo de retroalimentación.",
+		"howto.features":    "Funciones",
+		"howto.support":     "Apóyanos",
+
+		"feature.report":          "Informar de sucesos",
+		"feature.reportText":      "Aporta detalles sobre el suceso.",
+		"feature.confirmText":     "Explora los sucesos informados, confirma lo que has presenciado y aporta más detalles.",
+		"feature.aggregation":     "Acumulación de detalles",
+		"feature.aggregationText": "Cuantos más testigos, mayor es la precisión y mayor la probabilidad de que un rumor sea una noticia real.",
+		"feature.read":            "Lee las noticias reales",
+		"feature.anonymity":       "Anonimato por defecto",
+		"feature.anonymityText":   "El anonimato garantiza que todos estén protegidos.",
+		"feature.flat":            "Interacciones horizontales",
+		"feature.flatText":        "Sin control centralizado, sin verificadores y sin anuncios.",
+
+		"terms.heading":     "Condiciones del servicio",
+		"terms.intro":       "Introducción",
+		"terms.introText":   "Cyber Witness es un simulador de medios P2P basado en el concepto de reportero y testigos en forma de juego ficticio basado en datos en tiempo real. Al usar la aplicación aceptas implícitamente compartir tu peer id con la red pública de IPFS.",
+		"terms.hosting":     "Alojamiento de la aplicación",
+		"terms.hostingText": "Cyber Witness es una aplicación descentralizada alojada en una red pública entre pares. Al usar la aplicación aceptas alojarla gratuitamente en la red pública de IPFS mientras la uses.",
+		"terms.content":     "Contenido generado por los usuarios",
+		"terms.contentText": "Todo el contenido publicado lo generan los usuarios, es ficticio y los creadores no son responsables de él.",
+
+		"privacy.heading":     "Política de privacidad",
+		"privacy.data":        "Datos personales",
+		"privacy.dataText":    "Cyber Witness no recoge información personal. Guardamos una pequeña parte de tu peer ID cifrada como identificador no único, que se usa para mostrar la clasificación.",
+		"privacy.cookies":     "Cookies",
+		"privacy.cookiesText": "Cyber Witness no usa cookies.",
+		"privacy.changes":     "Cambios en esta política de privacidad",
+		"privacy.changesText": "Esta política de privacidad puede actualizarse de vez en cuando, por lo que se recomienda revisar esta página periódicamente. Se te avisará de cualquier cambio desde esta página. Los cambios entran en vigor en cuanto se publican en ella.",
+	},
+	"de": {
+		"hero.title":         "Cyber Witness - Nachrichten, wie sie sein sollten",
+		"hero.intro":         "P2P-Gemeinschaft unabhängiger Reporter und Zeugen - eine Alternative zu den Massenmedien. Reporter veröffentlichen Ereignisse, die sie selbst gesehen haben, ohne Deutung. Bis sie bestätigt sind, erscheinen sie als Gerüchte. Zeugen bestätigen Gerüchte, die sie miterlebt haben, und ergänzen eigene Details. Die Details eines Ereignisses sammeln sich und werden mit jedem neuen Zeugen genauer. Sobald mindestens 2 Zeugen ein Gerücht bestätigt haben, wird es zur Nachricht. Je mehr Zeugen, desto genauer die Nachrichten.",
+		"hero.howItWorks":    "So funktioniert es",
+		"hero.muteList":      "Stummschaltliste",
+		"hero.backup":        "Sicherung",
+		"hero.notifications": "Benachrichtigungen",
+		"hero.language":      "Sprache",
+		"hero.digest":        "Nachrichtenzusammenfassung laden",
+
+		"report.heading":  "Möchtest du ein Ereignis melden?",
+		"report.help":     "Sieh zuerst bei den Gerüchten nach, vielleicht gibt es es schon.",
+		"report.evidence": "Optionaler Bild- oder Videobeweis",
+		"report.language": "Geschrieben auf",
+		"report.tags":     "Schlagwörter",
+		"report.tagsHint": "Durch Kommas getrennt, z. B. protest, traffic",
+		"report.submit":   "Ereignis melden",
+
+		"witness.heading":       "Warst du Zeuge eines Ereignisses?",
+		"witness.confirmRumors": "Gerüchte bestätigen",
+		"reader.heading":        "Möchtest du nur die Nachrichten lesen?",
+		"reader.intro":          "Deine persönlichen Nachrichten immer griffbereit. Alle bezeugt. Keine Werbung, Bezahlschranken, Zensur oder Faktenprüfer.",
+		"reader.readNews":       "Nachrichten lesen",
+		"reader.archive":        "Archiv",
+
+		"feed.rumors":          "Gerüchte",
+		"feed.news":            "Nachrichten",
+		"feed.noRumors":        "Keine aktuellen Gerüchte",
+		"feed.noNews":          "Keine aktuellen Nachrichten",
+		"feed.emptyHint":       "Schau später wieder vorbei oder melde ein Ereignis",
+		"feed.language":        "Sprache",
+		"feed.allLanguages":    "Alle Sprachen",
+		"feed.tag":             "Schlagwort",
+		"feed.allTags":         "Alle Schlagwörter",
+		"feed.followedTags":    "Gefolgte Schlagwörter",
+		"feed.followTag":       "Schlagwort folgen",
+		"feed.unfollowTag":     "Nicht mehr folgen",
+		"feed.tagsSaveFailed":  "Gefolgte Schlagwörter konnten nicht gespeichert werden.",
+		"feed.state":           "Status",
+		"feed.allStates":       "Alle Status",
+		"feed.export":          "Exportieren",
+		"feed.exportFailed":    "Ereignisse konnten nicht exportiert werden.",
+		"feed.action":          "Aktion",
+		"feed.confirmedBy":     "Bestätigt von",
+		"modal.close":          "Aktives Fenster schließen",
+		"event.title":          "Titel",
+		"event.details":        "Details",
+		"event.location":       "Ort",
+		"event.evidence":       "Beweis",
+		"event.confirm":        "Bestätigen",
+		"event.show":           "Anzeigen",
+		"event.hide":           "Ausblenden",
+		"event.history":        "Verlauf",
+		"event.link":           "Link",
+		"event.muteReporter":   "Reporter stummschalten",
+		"event.newDetails":     "Neue Details hinzufügen: ",
+		"event.addDetails":     "Details hinzufügen",
+		"event.dispute":        "Anfechten",
+		"event.corrected":      "Korrigiert",
+		"event.watch":          "Folgen",
+		"event.unwatch":        "Nicht mehr folgen",
+		"event.audit":          "Prüfprotokoll",
+		"event.heading":        "Ereignis",
+		"event.notFound":       "Dieses Ereignis wurde nicht gefunden. Es gehört vielleicht zu einem Kanal, dem du nicht folgst, oder hat deinen Knoten noch nicht erreicht.",
+		"event.muted":          "Dieses Ereignis ist durch deine Stummschaltliste ausgeblendet.",
+		"event.confirmedBy":    "bestätigt von",
+		"event.submitted":      "Ereignis gemeldet.",
+		"event.createFailed":   "Ereignis konnte nicht erstellt werden. Versuche es später erneut.",
+		"event.detailsAdded":   "Details zum Ereignis hinzugefügt.",
+		"event.detailsFailed":  "Details konnten nicht hinzugefügt werden. Versuche es später erneut.",
+		"event.rumorConfirmed": "Gerücht bestätigt.",
+		"event.confirmFailed":  "Gerücht konnte nicht bestätigt werden. Versuche es später erneut.",
+		"event.disputed":       "Ereignis angefochten.",
+		"event.disputeFailed":  "Ereignis konnte nicht angefochten werden. Versuche es später erneut.",
+
+		"status.syncing":      "Wird synchronisiert",
+		"status.connected":    "Verbunden",
+		"status.reconnecting": "Wird neu verbunden",
+		"status.connecting":   "Wird verbunden",
+		"status.offline":      "Offline",
+
+		"state.rumor":      "Gerücht",
+		"state.news":       "Nachricht",
+		"state.disputed":   "Umstritten",
+		"state.disputedBy": "Bürger fechten dieses Ereignis an",
+		"state.retracted":  "Zurückgezogen",
+		"state.merged":     "Zusammengeführt",
+		"state.archived":   "Archiviert",
+
+		"sync.inSync":    "synchron",
+		"sync.divergent": "Gruppen nicht synchron",
+		"sync.rounds":    "Runden synchron",
+		"sync.recovered": "Ereignisse wiederhergestellt",
+		"sync.sent":      "Ereignisse gesendet",
+
+		"audit.time":              "Zeit",
+		"audit.operation":         "Vorgang",
+		"audit.actor":             "Bürger",
+		"audit.state":             "Status",
+		"audit.signature":         "Signatur",
+		"audit.valid":             "Gültig",
+		"audit.invalid":           "Ungültig",
+		"audit.export":            "Protokoll exportieren",
+		"audit.exportFailed":      "Prüfprotokoll konnte nicht exportiert werden.",
+		"audit.notFound":          "Das Ereignis dieses Prüfprotokolls ist nicht mehr geladen.",
+		"audit.op.report":         "Gemeldet",
+		"audit.op.confirm":        "Bestätigt",
+		"audit.op.detail":         "Details hinzugefügt",
+		"audit.op.dispute":        "Angefochten",
+		"audit.op.correction":     "Korrigiert",
+		"audit.op.retraction":     "Zurückgezogen",
+		"audit.op.merge-proposal": "Zusammenführung vorgeschlagen mit",
+		"audit.op.merge-vote":     "Zusammenführung zugestimmt mit",
+		"audit.op.merge":          "Zusammengeführt mit",
+		"audit.op.merged-from":    "Zusammengeführte Meldung",
+
+		"digest.from":       "Zusammenfassung vom",
+		"digest.instead":    "stattdessen wird die neueste Nachrichtenzusammenfassung angezeigt.",
+		"digest.header":     "Nachrichtenzusammenfassung",
+		"digest.loaded":     "Nachrichten aus der Zusammenfassung geladen vom",
+		"digest.loadFailed": "Nachrichtenzusammenfassung konnte nicht geladen werden. Versuche es später erneut.",
+
+		"daily.title":     "Tageszusammenfassung",
+		"daily.topRumors": "Top-Gerüchte",
+
+		"mute.description":  "Stummgeschaltete Bürger, Stichwörter und Orte werden in deinen Gerüchten und Nachrichten ausgeblendet. Die Liste wird nur auf deinem Gerät gespeichert und ändert nie, was andere sehen.",
+		"mute.keyword":      "Stichwort",
+		"mute.location":     "Ort",
+		"mute.citizen":      "Bürger-ID",
+		"mute.mute":         "Stummschalten",
+		"mute.unmute":       "Stummschaltung aufheben",
+		"mute.citizens":     "Bürger",
+		"mute.keywords":     "Stichwörter",
+		"mute.locations":    "Orte",
+		"mute.import":       "Importieren",
+		"mute.export":       "Exportieren",
+		"mute.muted":        "Stummgeschaltet",
+		"mute.citizenMuted": "Meldungen und Details dieses Bürgers werden jetzt ausgeblendet.",
+		"mute.imported":     "Stummschaltliste importiert.",
+		"mute.readFailed":   "Stummschaltliste konnte nicht gelesen werden.",
+		"mute.saveFailed":   "Stummschaltliste konnte nicht gespeichert werden.",
+
+		"archive.status":       "Status",
+		"archive.expiredRumor": "Abgelaufenes Gerücht",
+		"archive.empty":        "Das Archiv ist leer",
+		"archive.emptyHint":    "Hier landen Gerüchte, die nie bestätigt werden, und alte Nachrichten",
+		"archive.loadFailed":   "Archiv konnte nicht geladen werden. Versuche es später erneut.",
+
+		"backup.description":    "Exportiere die Ereignisse der Kanäle, denen du folgst, als JSON Lines oder als CAR-Archiv mit Revisionen und Beweisen. Importe werden wie Live-Updates geprüft und mit deinen vorhandenen Ereignissen zusammengeführt.",
+		"backup.lastImport":     "Letzter Import",
+		"backup.added":          "hinzugefügt",
+		"backup.merged":         "zusammengeführt",
+		"backup.unchanged":      "unverändert",
+		"backup.unsigned":       "unsigniert",
+		"backup.rejectedCount":  "abgelehnt",
+		"backup.conflicts":      "Konflikte",
+		"backup.rejected":       "Abgelehnt",
+		"backup.conflict":       "Konflikt",
+		"backup.import":         "Importieren",
+		"backup.exportCAR":      "CAR exportieren",
+		"backup.exportJSONL":    "JSON Lines exportieren",
+		"backup.importFinished": "Import abgeschlossen:",
+		"backup.importFailed":   "Ereignisse konnten nicht importiert werden:",
+		"backup.exportFailed":   "Ereignisse konnten nicht exportiert werden. Versuche es später erneut.",
+
+		"channel.label":        "Kanal",
+		"channel.manage":       "Kanäle",
+		"channel.description":  "Folge den Regionen und Themen, die dich interessieren. Eine Region ist ein Geohash-Präfix: Je kürzer, desto größer das Gebiet. Meldungen gehen an den Kanal, den du gerade ansiehst.",
+		"channel.regionHint":   "Region, z. B. u33",
+		"channel.locate":       "Meinen Standort verwenden",
+		"channel.topicHint":    "Optionales Thema, z. B. Verkehr",
+		"channel.follow":       "Folgen",
+		"channel.unfollow":     "Nicht mehr folgen",
+		"channel.global":       "Global",
+		"channel.loadFailed":   "Fehler beim Laden der Ereignisse von",
+		"channel.saveFailed":   "Kanäle konnten nicht gespeichert werden.",
+		"channel.badRegion":    "Eine Region ist ein Geohash aus Ziffern und den Buchstaben b-z ohne i, l und o.",
+		"channel.noLocation":   "Dein Browser teilt seinen Standort nicht. Gib stattdessen einen Geohash ein.",
+		"channel.locateFailed": "Dein Standort konnte nicht ermittelt werden. Gib stattdessen einen Geohash ein.",
+
+		"history.reported":         "Gemeldet",
+		"history.by":               "von",
+		"history.confirmedBy":      "Bestätigt von",
+		"history.revisionCID":      "Revisions-CID",
+		"history.fetchRevision":    "Revision abrufen",
+		"history.detailsAdded":     "Details hinzugefügt",
+		"history.detailsRemoved":   "Details entfernt",
+		"history.tagsAdded":        "Tags hinzugefügt",
+		"history.tagsRemoved":      "Tags entfernt",
+		"history.duplicateOf":      "Vorgeschlagenes Duplikat von",
+		"history.mergeAgreedBy":    "Zusammenführung zugestimmt von",
+		"history.mergedReports":    "Zusammengeführte Meldungen",
+		"history.witnessesAdded":   "Zeugen hinzugefügt",
+		"history.witnessesRemoved": "Zeugen entfernt",
+		"history.disputedBy":       "Angezweifelt von",
+		"history.correctedBy":      "Vom Melder korrigiert",
+		"history.retractedBy":      "Vom Melder zurückgezogen",
+		"history.noRevisions":      "Dieses Ereignis wurde gemeldet, bevor Revisionen aufgezeichnet wurden.",
+		"history.loadFailed":       "Verlauf konnte nicht geladen werden. Versuche es später erneut.",
+		"history.fetchFailed":      "Fehler beim Abrufen der Revision",
+
+		"notifications.none":     "Noch keine Benachrichtigungen.",
+		"notifications.clear":    "Verlauf löschen",
+		"notifications.dismiss":  "Benachrichtigung schließen",
+		"notifications.close":    "Schließen",
+		"notifications.more":     "weitere",
+		"notifications.success":  "Erfolg",
+		"notifications.error":    "Fehler",
+		"notifications.tryLater": "Versuche es später erneut.",
+
+		"correction.heading":         "Meldung korrigieren oder zurückziehen",
+		"correction.description":     "Beschreibung",
+		"correction.reason":          "Grund",
+		"correction.publish":         "Korrektur veröffentlichen",
+		"correction.retract":         "Meldung zurückziehen",
+		"correction.corrected":       "Vom Melder korrigiert",
+		"correction.retracted":       "Vom Melder zurückgezogen",
+		"correction.original":        "Ursprünglich gemeldet als",
+		"correction.at":              "bei",
+		"correction.header":          "Korrektur",
+		"correction.unchanged":       "Ändere Titel, Ort oder Beschreibung, um eine Korrektur zu veröffentlichen.",
+		"correction.published":       "Korrektur veröffentlicht.",
+		"correction.publishFailed":   "Korrektur konnte nicht veröffentlicht werden. Versuche es später erneut.",
+		"correction.retractedReport": "Meldung zurückgezogen.",
+		"correction.retractFailed":   "Meldung konnte nicht zurückgezogen werden. Versuche es später erneut.",
+
+		"duplicate.sameAs":      "Gleiches Ereignis wie",
+		"duplicate.choose":      "Meldung auswählen",
+		"duplicate.propose":     "Zusammenführen vorschlagen",
+		"duplicate.heading":     "Vorgeschlagenes Duplikat",
+		"duplicate.mergedInto":  "würde zusammengeführt mit",
+		"duplicate.agreed":      "bisher zugestimmt",
+		"duplicate.quorum":      "Beteiligte jeder Meldung werden benötigt",
+		"duplicate.agree":       "Zustimmen",
+		"duplicate.merge":       "Zusammenführen",
+		"duplicate.noTarget":    "Wähle die Meldung, die dasselbe Ereignis beschreibt.",
+		"duplicate.notAllowed":  "Diese Meldungen können nicht zusammengeführt werden. Nur ihre Melder und Zeugen können eine Zusammenführung vorschlagen, eine nach der anderen.",
+		"duplicate.proposed":    "Vorschlag zur Zusammenführung gespeichert.",
+		"duplicate.merged":      "Meldungen zusammengeführt in",
+		"duplicate.mergeFailed": "Meldungen konnten nicht zusammengeführt werden. Versuche es später erneut.",
+
+		"watch.header":        "Verfolgtes Ereignis",
+		"watch.heading":       "Verfolgte Ereignisse",
+		"watch.none":          "Du folgst keinem Ereignis dieses Kanals. Ereignisse, die du meldest oder bestätigst, werden automatisch verfolgt.",
+		"watch.browser":       "Auch Browser-Benachrichtigungen anzeigen",
+		"watch.confirmation":  "neue Bestätigung",
+		"watch.confirmations": "neue Bestätigungen",
+		"watch.detail":        "neues Detail",
+		"watch.details":       "neue Details",
+		"watch.dispute":       "neuer Einwand",
+		"watch.disputes":      "neue Einwände",
+		"watch.becameNews":    "wurde zur Nachricht",
+		"watch.isNow":         "ist jetzt",
+		"watch.saveFailed":    "Verfolgte Ereignisse konnten nicht gespeichert werden.",
+		"watch.unsupported":   "Dein Browser unterstützt keine Benachrichtigungen.",
+		"watch.denied":        "Browser-Benachrichtigungen wurden nicht erlaubt.",
+
+		"howto.heading":     "Spielanleitung",
+		"howto.what":        "Was ist Cyber Witness",
+		"howto.whatText":    "Cyber Witness ist ein P2P-Mediensimulator nach dem Prinzip von Reporter und Zeugen.",
+		"howto.problem":     "Was ist das Problem mit den Massenmedien?",
+		"howto.problemText": "Sie sind zentralisiert, zensiert, faktengeprüft, niemandem verantwortlich und intransparent.",
+		"howto.replace":     "Wie Cyber Witness die Massenmedien ersetzt",
+		"howto.replaceText": "Durch P2P-Interaktionen und das Modell von Reporter und Zeugen bilden wir eine transparente Umgebung mit Rückkopplung nach.",
+		"howto.features":    "Funktionen",
+		"howto.support":     "Unterstütze uns",
+
+		"feature.report":          "Ereignisse melden",
+		"feature.reportText":      "Beschreibe das Ereignis im Detail.",
+		"feature.confirmText":     "Durchsuche gemeldete Ereignisse, bestätige, was du miterlebt hast, und ergänze weitere Details.",
+		"feature.aggregation":     "Gesammelte Details",
+		"feature.aggregationText": "Je mehr Zeugen, desto höher die Genauigkeit und desto wahrscheinlicher ist ein Gerücht eine echte Nachricht.",
+		"feature.read":            "Lies die echten Nachrichten",
+		"feature.anonymity":       "Standardmäßig anonym",
+		"feature.anonymityText":   "Anonymität stellt sicher, dass alle geschützt sind.",
+		"feature.flat":            "Gleichberechtigte Interaktion",
+		"feature.flatText":        "Keine zentrale Kontrolle, keine Faktenprüfer und keine Werbung.",
+
+		"terms.heading":     "Nutzungsbedingungen",
+		"terms.intro":       "Einleitung",
+		"terms.introText":   "Cyber Witness ist ein P2P-Mediensimulator nach dem Prinzip von Reporter und Zeugen in Form eines fiktiven Spiels auf Grundlage von Echtzeitdaten. Mit der Nutzung der Anwendung stimmst du stillschweigend zu, deine Peer-ID mit dem öffentlichen IPFS-Netzwerk zu teilen.",
+		"terms.hosting":     "Hosting der Anwendung",
+		"terms.hostingText": "Cyber Witness ist eine dezentrale Anwendung und wird in einem öffentlichen Peer-to-Peer-Netzwerk gehostet. Mit der Nutzung der Anwendung stimmst du zu, sie für die Dauer deiner Nutzung kostenlos im öffentlichen IPFS-Netzwerk zu hosten.",
+		"terms.content":     "Von Nutzern erstellte Inhalte",
+		"terms.contentText": "Alle veröffentlichten Inhalte werden von Nutzern erstellt, sind fiktiv und die Entwickler sind nicht für sie verantwortlich.",
+
+		"privacy.heading":     "Datenschutzerklärung",
+		"privacy.data":        "Personenbezogene Daten",
+		"privacy.dataText":    "Cyber Witness erhebt keine personenbezogenen Daten. Wir speichern einen kleinen Teil deiner Peer-ID verschlüsselt als nicht eindeutige Kennung, die für die Anzeige der Ränge verwendet wird.",
+		"privacy.cookies":     "Cookies",
+		"privacy.cookiesText": "Cyber Witness verwendet keine Cookies.",
+		"privacy.changes":     "Änderungen dieser Datenschutzerklärung",
+		"privacy.changesText": "Diese Datenschutzerklärung kann von Zeit zu Zeit aktualisiert werden. Es empfiehlt sich daher, diese Seite regelmäßig auf Änderungen zu prüfen. Über Änderungen wirst du auf dieser Seite informiert. Sie gelten ab ihrer Veröffentlichung auf dieser Seite.",
+	},
+}
+
+// languageNames are the languages a report can be tagged with, each in its
+// own language. It covers more than the interface catalogs since citizens
+// write in languages the interface isn't translated to.
+var languageNames = map[string]string{
+	"ar": "العربية",
+	"de": "Deutsch",
+	"en": "English",
+	"es": "Español",
+	"fr": "Français",
+	"hi": "हिन्दी",
+	"it": "Italiano",
+	"ja": "日本語",
+	"pl": "Polski",
+	"pt": "Português",
+	"ru": "Русский",
+	"tr": "Türkçe",
+	"uk": "Українська",
+	"zh": "中文",
+}
+
+// translate returns the text of message id in lang, falling back to English
+// and then to the ID itself.
+func translate(lang, id string) string {
+	if msg, ok := catalogs[lang][id]; ok {
+		return msg
+	}
+	if msg, ok := catalogs[defaultLanguage][id]; ok {
+		return msg
+	}
+	return id
+}
+
+// t returns the text of message id in the interface language.
+func (w *witness) t(id string) string {
+	return translate(w.language, id)
+}
+
+// baseLanguage reduces a BCP 47 tag such as "pt-BR" to its language.
+func baseLanguage(tag string) string {
+	lang, _, _ := strings.Cut(strings.TrimSpace(tag), "-")
+	return strings.ToLower(lang)
+}
+
+// matchLanguage returns the first of the preferred tags with a catalog.
+func matchLanguage(tags []string) string {
+	for _, tag := range tags {
+		if _, ok := catalogs[baseLanguage(tag)]; ok {
+			return baseLanguage(tag)
+		}
+	}
+	return defaultLanguage
+}
+
+// languageName returns the name of a language tag, or the tag itself for
+// languages without a known name.
+func languageName(lang string) string {
+	if name, ok := languageNames[lang]; ok {
+		return name
+	}
+	return strings.ToUpper(lang)
+}
+
+// sortedLanguages returns the keys of m ordered by code.
+func sortedLanguages[V any](m map[string]V) []string {
+	langs := make([]string, 0, len(m))
+	for lang := range m {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// browserLanguages returns the languages preferred by the browser, most
+// preferred first.
+func browserLanguages() []string {
+	navigator := app.Window().Get("navigator")
+	list := navigator.Get("languages")
+	if !list.Truthy() {
+		if lang := navigator.Get("language"); lang.Truthy() {
+			return []string{lang.String()}
+		}
+		return nil
+	}
+
+	tags := make([]string, list.Length())
+	for i := range tags {
+		tags[i] = list.Index(i).String()
+	}
+	return tags
+}
+
+// loadLanguage restores the interface language picked by the citizen or
+// detects it from the browser on first use.
+func (w *witness) loadLanguage(ctx app.Context) {
+	err := ctx.LocalStorage().Get(languageStorageKey, &w.language)
+	if err != nil {
+		log.Println(err)
+	}
+	if _, ok := catalogs[w.language]; !ok {
+		w.language = matchLanguage(browserLanguages())
+	}
+	w.applyLanguage()
+}
+
+func (w *witness) setLanguage(ctx app.Context, lang string) {
+	if _, ok := catalogs[lang]; !ok {
+		return
+	}
+
+	w.language = lang
+	w.applyLanguage()
+	err := ctx.LocalStorage().Set(languageStorageKey, lang)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// applyLanguage tells the browser which language the page is in, for screen
+// readers and hyphenation.
+func (w *witness) applyLanguage() {
+	app.Window().Get("document").Get("documentElement").Set("lang", w.language)
+}
+
+// reportLanguage is the language new reports and details are tagged with.
+// It defaults to the interface language.
+func (w *witness) reportLanguage() string {
+	if w.eventLanguage != "" {
+		return w.eventLanguage
+	}
+	return w.language
+}
+
+// eventLanguages returns the languages of the loaded events and their
+// details, for the feed filter.
+func (w *witness) eventLanguages() []string {
+	seen := make(map[string]bool)
+	for _, e := range w.events {
+		if e.Language != "" {
+			seen[e.Language] = true
+		}
+	}
+	if w.languageFilter != "" {
+		seen[w.languageFilter] = true
+	}
+	return sortedLanguages(seen)
+}
+
+// hidesLanguage reports whether the language filter leaves e out of the
+// feed. Events published before languages were tagged only show without a
+// filter.
+func (w *witness) hidesLanguage(e Event) bool {
+	return w.languageFilter != "" && e.Language != w.languageFilter
+}
+
+func (w *witness) renderLanguageSwitcher() app.UI {
+	langs := sortedLanguages(catalogs)
+
+	return app.Div().Class("p-form p-form--inline").Body(
+		app.Div().Class("p-form__group").Body(
+			app.Label().For("language").Text(w.t("hero.language")),
+			app.Select().ID("language").OnChange(w.onSwitchLanguage).Body(
+				app.Range(langs).Slice(func(n int) app.UI {
+					return app.Option().Value(langs[n]).Text(languageName(langs[n])).Selected(langs[n] == w.language)
+				}),
+			),
+		),
+	)
+}
+
+// renderReportLanguage renders the choice of the language a report is
+// written in.
+func (w *witness) renderReportLanguage() app.UI {
+	langs := sortedLanguages(languageNames)
+	current := w.reportLanguage()
+
+	return app.Div().Class("p-form__group row").Body(
+		app.Label().For("report-language").Text(w.t("report.language")),
+		app.Select().ID("report-language").OnChange(w.onReportLanguage).Body(
+			app.Range(langs).Slice(func(n int) app.UI {
+				return app.Option().Value(langs[n]).Text(languageName(langs[n])).Selected(langs[n] == current)
+			}),
+		),
+	)
+}
+
+// renderLanguageFilter renders the feed filter. id keeps the select of each
+// feed distinct.
+func (w *witness) renderLanguageFilter(id string) app.UI {
+	langs := w.eventLanguages()
+
+	return app.Div().Class("p-form p-form--inline").Body(
+		app.Div().Class("p-form__group").Body(
+			app.Label().For(id).Text(w.t("feed.language")),
+			app.Select().ID(id).OnChange(w.onFilterLanguage).Body(
+				app.Option().Value("").Text(w.t("feed.allLanguages")).Selected(w.languageFilter == ""),
+				app.Range(langs).Slice(func(n int) app.UI {
+					return app.Option().Value(langs[n]).Text(languageName(langs[n])).Selected(langs[n] == w.languageFilter)
+				}),
+			),
+		),
+	)
+}
+
+// renderLanguage labels text written in lang. Nothing is shown for untagged
+// text.
+func renderLanguage(lang string) app.UI {
+	return app.If(lang != "", func() app.UI {
+		return app.Span().Class("p-status-label").Lang(lang).Title(languageName(lang)).Text(strings.ToUpper(lang))
+	})
+}
+
+func (w *witness) onSwitchLanguage(ctx app.Context, e app.Event) {
+	w.setLanguage(ctx, ctx.JSSrc().Get("value").String())
+}
+
+func (w *witness) onReportLanguage(ctx app.Context, e app.Event) {
+	w.eventLanguage = ctx.JSSrc().Get("value").String()
+}
+
+func (w *witness) onFilterLanguage(ctx app.Context, e app.Event) {
+	w.languageFilter = ctx.JSSrc().Get("value").String()
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestCatalogsMatchEnglish(t *testing.T) {
+	for lang, msgs := range catalogs {
+		for id := range msgs {
+			if _, ok := catalogs[defaultLanguage][id]; !ok {
+				t.Errorf("%s: message %q has no English text", lang, id)
+			}
+		}
+		for id := range catalogs[defaultLanguage] {
+			if _, ok := msgs[id]; !ok {
+				t.Errorf("%s: message %q is not translated", lang, id)
+			}
+		}
+		if _, ok := languageNames[lang]; !ok {
+			t.Errorf("%s: catalog language has no name", lang)
+		}
+	}
+}
+
+func TestMessagesHaveText(t *testing.T) {
+	call := regexp.MustCompile(`\bt\("([^"]+)"\)`)
+	files, _ := filepath.Glob("*.go")
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		b, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range call.FindAllSubmatch(b, -1) {
+			if _, ok := catalogs[defaultLanguage][string(m[1])]; !ok {
+				t.Errorf("%s: message %q has no English text", name, m[1])
+			}
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	if got := translate("es", "feed.rumors"); got != "Rumores" {
+		t.Errorf("translate(es) = %q", got)
+	}
+	if got := translate("xx", "feed.rumors"); got != "Rumors" {
+		t.Errorf("unknown language did not fall back to English: %q", got)
+	}
+	if got := translate("es", "no.such.message"); got != "no.such.message" {
+		t.Errorf("unknown message = %q, want its ID", got)
+	}
+}
+
+func TestMatchLanguage(t *testing.T) {
+	tests := []struct {
+		tags []string
+		want string
+	}{
+		{nil, defaultLanguage},
+		{[]string{"de-AT", "en-US"}, "de"},
+		{[]string{"ja", "ES"}, "es"},
+		{[]string{"ja"}, defaultLanguage},
+	}
+	for _, tt := range tests {
+		if got := matchLanguage(tt.tags); got != tt.want {
+			t.Errorf("matchLanguage(%v) = %s, want %s", tt.tags, got, tt.want)
+		}
+	}
+}
+
+func TestLanguageFilter(t *testing.T) {
+	w := &witness{events: []Event{
+		{ID: "1", Language: "de"},
+		{ID: "2", Language: "es"},
+		{ID: "3"},
+	}}
+
+	if w.hidesLanguage(w.events[2]) {
+		t.Error("untagged event hidden without a filter")
+	}
+
+	w.languageFilter = "es"
+	var shown []string
+	for _, e := range w.events {
+		if !w.hidesLanguage(e) {
+			shown = append(shown, e.ID)
+		}
+	}
+	if len(shown) != 1 || shown[0] != "2" {
+		t.Errorf("filter on es shows %v", shown)
+	}
+
+	w.languageFilter = "fr"
+	if langs := w.eventLanguages(); len(langs) != 3 || langs[0] != "de" || langs[2] != "fr" {
+		t.Errorf("eventLanguages() = %v", langs)
+	}
+}
+
+// Events signed before languages were tagged must keep their signature.
+func TestUntaggedEventEncoding(t *testing.T) {
+	b, err := json.Marshal(Event{ID: "1", Details: []Detail{{Text: "text", Author: "10"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc map[string]any
+	json.Unmarshal(b, &doc)
+	if _, ok := doc["language"]; ok {
+		t.Error("untagged event encodes a language")
+	}
+	if _, ok := doc["details"].([]any)[0].(map[string]any)["language"]; ok {
+		t.Error("untagged detail encodes a language")
+	}
+}
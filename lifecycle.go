@@ -0,0 +1,222 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+// archivedType replaces eventType on documents that left the active views.
+// Archived events stay in the event store but are skipped by the default
+// query on type.
+const archivedType = "archived-event"
+
+// Environment variables holding the lifecycle windows as Go durations, for
+// example "168h".
+const (
+	envRumorTTL = "RUMOR_TTL"
+	envNewsTTL  = "NEWS_TTL"
+)
+
+// archiveCheckInterval is how often a running client looks for expired
+// events.
+const archiveCheckInterval = time.Hour
+
+// lifecycle holds the windows after which active events are archived.
+type lifecycle struct {
+	rumorTTL time.Duration
+	newsTTL  time.Duration
+}
+
+var defaultLifecycle = lifecycle{
+	rumorTTL: 7 * 24 * time.Hour,
+	newsTTL:  30 * 24 * time.Hour,
+}
+
+// lifecycleFromEnv returns the default lifecycle overridden by the windows
+// the server passed to the app.
+func lifecycleFromEnv() lifecycle {
+	l := defaultLifecycle
+	if d, err := time.ParseDuration(app.Getenv(envRumorTTL)); err == nil && d > 0 {
+		l.rumorTTL = d
+	}
+	if d, err := time.ParseDuration(app.Getenv(envNewsTTL)); err == nil && d > 0 {
+		l.newsTTL = d
+	}
+	return l
+}
+
+// expired reports whether e should move to the archive. News expires newsTTL
+// after being confirmed and every other event, disputed and retracted ones
+// included, rumorTTL after being reported. Events without the timestamp
+// their window starts at predate timestamps and never expire, since every
+// client would otherwise archive them at once.
+func (l lifecycle) expired(e Event, now time.Time) bool {
+	if e.state() == stateNews {
+		return e.NewsAt > 0 && now.Sub(time.Unix(e.NewsAt, 0)) > l.newsTTL
+	}
+	return e.CreatedAt > 0 && now.Sub(time.Unix(e.CreatedAt, 0)) > l.rumorTTL
+}
+
+// checkExpired archives expired events every archiveCheckInterval.
+func (w *witness) checkExpired(ctx app.Context) {
+	w.archiveExpired(ctx)
+	ctx.After(archiveCheckInterval, w.checkExpired)
+}
+
+// archiveExpired moves expired events out of the active views. Every client
+// runs it, archiving the same event twice only rewrites the same document.
+func (w *witness) archiveExpired(ctx app.Context) {
+	now := time.Now()
+
+	var active []Event
+	var expired []Event
+	for _, e := range w.events {
+		if w.lifecycle.expired(e, now) {
+			e.Type = archivedType
+			e.ArchivedAt = now.Unix()
+			expired = append(expired, e)
+			w.retire(e)
+		} else {
+			active = append(active, e)
+		}
+	}
+	w.events = active
+	w.updateNoNews()
+
+	if len(expired) == 0 {
+		return
+	}
+	w.scheduleCacheSave(ctx)
+
+	ctx.Async(func() {
+		for _, e := range expired {
+			_, err := w.putEvent(e, topicUpdateEvent)
+			if err != nil {
+				log.Println(err)
+			}
+		}
+	})
+}
+
+// archiveDay groups archived events by the day they were last active.
+type archiveDay struct {
+	Date   string
+	Events []Event
+}
+
+// lastActive returns when an event stopped changing state: the day it became
+// news or, for rumors, the day it was reported.
+func (e Event) lastActive() time.Time {
+	if e.NewsAt > 0 {
+		return time.Unix(e.NewsAt, 0)
+	}
+	return time.Unix(e.CreatedAt, 0)
+}
+
+// archiveDays groups events by day, newest day first.
+func archiveDays(events []Event) []archiveDay {
+	sorted := make([]Event, len(events))
+	copy(sorted, events)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].lastActive().After(sorted[j].lastActive())
+	})
+
+	var days []archiveDay
+	for _, e := range sorted {
+		date := e.lastActive().Format("2006-01-02")
+		if len(days) == 0 || days[len(days)-1].Date != date {
+			days = append(days, archiveDay{Date: date})
+		}
+		days[len(days)-1].Events = append(days[len(days)-1].Events, e)
+	}
+	return days
+}
+
+func (w *witness) loadArchive(ctx app.Context) {
+	c := w.channel
+
+	ctx.Async(func() {
+		archive, err := queryEvents(w.sh, c, archivedType)
+		if err != nil {
+			ctx.Dispatch(func(ctx app.Context) {
+				w.createNotification(ctx, NotificationDanger, w.t(ErrorHeader), w.t("archive.loadFailed"))
+			})
+			log.Println(err)
+			return
+		}
+
+		ctx.Dispatch(func(ctx app.Context) {
+			if c == w.channel {
+				w.archive = archive
+			}
+		})
+	})
+}
+
+func (w *witness) renderArchiveModal() app.UI {
+	days := archiveDays(w.archive)
+
+	return app.Div().Class("p-modal").ID("archive-modal").Style("display", "none").Body(
+		app.Section().Class("p-modal__dialog").Role("dialog").Aria("modal", true).Aria("labelledby", "modal-title").Aria("describedby", "modal-description").Body(
+			app.Header().Class("p-modal__header").Body(
+				app.H2().Class("p-modal__title").ID("modal-title").Text(w.t("reader.archive")),
+				app.Button().Class("p-modal__close").Aria("label", w.t("modal.close")).Aria("controls", "modal").OnClick(w.closeArchiveModal),
+			),
+			app.Table().Aria("label", "archive-table").Body(
+				app.THead().Body(
+					app.Tr().Body(
+						app.Th().Text(w.t("event.title")),
+						app.Th().Text(w.t("event.location")),
+						app.Th().Text(w.t("archive.status")),
+						app.Th().Text(w.t("feed.confirmedBy")),
+					),
+				),
+				app.If(len(days) > 0, func() app.UI {
+					return app.Range(days).Slice(func(d int) app.UI {
+						return app.TBody().Body(
+							app.Tr().Body(
+								app.Th().ColSpan(4).Text(days[d].Date),
+							),
+							app.Range(days[d].Events).Slice(func(i int) app.UI {
+								e := days[d].Events[i]
+								return app.If(!w.mutes.hidesEvent(e), func() app.UI {
+									return app.Tr().Body(
+										app.Td().Class("has-overflow").Text(e.Title),
+										app.Td().Class("has-overflow").Text(e.Location),
+										app.If(e.ConfirmedBy > 1, func() app.UI {
+											return app.Td().Text(w.t("state.news"))
+										}).Else(func() app.UI {
+											return app.Td().Text(w.t("archive.expiredRumor"))
+										}),
+										app.Td().Text(e.ConfirmedBy),
+									)
+								})
+							}),
+						)
+					})
+				}).Else(func() app.UI {
+					return app.Caption().Class("p-strip").Body(
+						app.Div().Class("row").Body(
+							app.Div().Class("u-align--left col-8 col-medium-4 col-small-3").Body(
+								app.P().Class("p-heading--4 u-no-margin--bottom").Text(w.t("archive.empty")),
+								app.P().Text(w.t("archive.emptyHint")),
+							),
+						),
+					)
+				}),
+			),
+		),
+	)
+}
+
+func (w *witness) openArchiveDialog(ctx app.Context, e app.Event) {
+	w.loadArchive(ctx)
+	app.Window().GetElementByID("archive-modal").Set("style", "display:flex")
+}
+
+func (w *witness) closeArchiveModal(ctx app.Context, e app.Event) {
+	app.Window().GetElementByID("archive-modal").Set("style", "display:none")
+}
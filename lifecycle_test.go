@@ -0,0 +1,74 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExpired(t *testing.T) {
+	l := lifecycle{rumorTTL: 7 * 24 * time.Hour, newsTTL: 30 * 24 * time.Hour}
+	now := time.Unix(100*24*3600, 0)
+	day := int64(24 * 3600)
+	confirmed := []string{"11", "12"}
+
+	tests := []struct {
+		name  string
+		event Event
+		want  bool
+	}{
+		{"fresh rumor", Event{CreatedAt: now.Unix() - day}, false},
+		{"old rumor", Event{CreatedAt: now.Unix() - 8*day}, true},
+		{"rumor without timestamp", Event{}, false},
+		{"old report, fresh news", Event{CreatedAt: now.Unix() - 40*day, NewsAt: now.Unix() - day, ConfirmedBy: 2, Witnesses: confirmed}, false},
+		{"old news", Event{CreatedAt: now.Unix() - 40*day, NewsAt: now.Unix() - 31*day, ConfirmedBy: 2, Witnesses: confirmed}, true},
+		{"news without confirmation date", Event{CreatedAt: now.Unix() - 40*day, ConfirmedBy: 2, Witnesses: confirmed}, false},
+		{"disputed news", Event{CreatedAt: now.Unix() - 8*day, NewsAt: now.Unix() - day, ConfirmedBy: 2, Witnesses: confirmed, Disputes: []string{"20", "21"}}, true},
+	}
+	for _, tt := range tests {
+		if got := l.expired(tt.event, now); got != tt.want {
+			t.Errorf("%s: expired = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestArchiveDays(t *testing.T) {
+	noon := func(date string) int64 {
+		d, err := time.ParseInLocation("2006-01-02 15:04", date+" 12:00", time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d.Unix()
+	}
+
+	days := archiveDays([]Event{
+		{ID: "1", CreatedAt: noon("2024-05-01")},
+		{ID: "2", CreatedAt: noon("2024-04-20"), NewsAt: noon("2024-05-03")},
+		{ID: "3", CreatedAt: noon("2024-05-01") + 60},
+		{ID: "4", CreatedAt: noon("2024-04-30")},
+	})
+
+	want := []struct {
+		date string
+		ids  []string
+	}{
+		{"2024-05-03", []string{"2"}},
+		{"2024-05-01", []string{"3", "1"}},
+		{"2024-04-30", []string{"4"}},
+	}
+	if len(days) != len(want) {
+		t.Fatalf("archive has %d days, want %d: %+v", len(days), len(want), days)
+	}
+	for n, d := range days {
+		var ids []string
+		for _, e := range d.Events {
+			ids = append(ids, e.ID)
+		}
+		if d.Date != want[n].date || !reflect.DeepEqual(ids, want[n].ids) {
+			t.Errorf("day %d = %s %v, want %s %v", n, d.Date, ids, want[n].date, want[n].ids)
+		}
+	}
+	if days := archiveDays(nil); len(days) != 0 {
+		t.Errorf("empty archive has days %+v", days)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// mergeEvents combines two versions of the same event. Witnesses, details
+// and evidence only ever grow, so the union of both is kept. Fields that
+// identify the event keep their local value and are reported as conflicts
+// when the incoming version disagrees.
+func mergeEvents(local, incoming Event) (Event, []string) {
+	var conflicts []string
+	conflict := func(field, a, b string) {
+		if a != b {
+			conflicts = append(conflicts, fmt.Sprintf("%s: %q kept, %q ignored", field, a, b))
+		}
+	}
+
+	merged := local
+	conflict("title", local.Title, incoming.Title)
+	conflict("location", local.Location, incoming.Location)
+	conflict("reporter", local.Reporter, incoming.Reporter)
+	// the reporter key is only ever set by the report itself, adopting a
+	// later one would let anyone retract or correct older reports
+	if local.ReporterKey != "" && incoming.ReporterKey != "" {
+		conflict("reporterKey", local.ReporterKey, incoming.ReporterKey)
+	}
+	if local.MergeInto == "" {
+		merged.MergeInto = incoming.MergeInto
+	} else if incoming.MergeInto != "" {
+		conflict("mergeInto", local.MergeInto, incoming.MergeInto)
+	}
+
+	for _, d := range incoming.Details {
+		if !containsDetail(merged.Details, d) {
+			merged.Details = append(merged.Details[:len(merged.Details):len(merged.Details)], d)
+		}
+	}
+
+	merged.Witnesses = union(local.Witnesses, incoming.Witnesses)
+	merged.Evidence = union(local.Evidence, incoming.Evidence)
+	merged.Tags = limitTags(union(local.Tags, incoming.Tags))
+	merged.MergeVotes = union(local.MergeVotes, incoming.MergeVotes)
+	merged.MergedFrom = union(local.MergedFrom, incoming.MergedFrom)
+	merged.Disputes = union(local.Disputes, incoming.Disputes)
+	merged.Corrections = unionCorrections(local.Corrections, incoming.Corrections)
+	merged.Audit = unionAudit(local.Audit, incoming.Audit)
+	if merged.Retraction == nil {
+		merged.Retraction = incoming.Retraction
+	}
+	merged.ConfirmedBy = len(merged.Witnesses)
+
+	merged.CreatedAt = earliest(local.CreatedAt, incoming.CreatedAt)
+	merged.NewsAt = earliest(local.NewsAt, incoming.NewsAt)
+	if incoming.Type == archivedType && local.Type != mergedType {
+		merged.Type = archivedType
+	}
+	if incoming.Type == mergedType {
+		merged.Type = mergedType
+	}
+	merged.ArchivedAt = max(local.ArchivedAt, incoming.ArchivedAt)
+	if incoming.State == string(stateRetracted) {
+		merged.State = incoming.State
+	}
+	// events stored before states existed keep their payload and signature
+	if merged.State != "" || incoming.State != "" {
+		merged = withState(merged)
+	}
+
+	// the merged version carries the signature of whichever side it matches
+	// and none when it combines both
+	switch {
+	case samePayload(merged, incoming):
+		merged.Revision = incoming.Revision
+		merged.Signer = incoming.Signer
+		merged.Signature = incoming.Signature
+	case samePayload(merged, local):
+	default:
+		if incoming.Revision != "" {
+			merged.Revision = incoming.Revision
+		}
+		merged.Signer = ""
+		merged.Signature = ""
+	}
+
+	return merged, conflicts
+}
+
+func containsDetail(details []Detail, d Detail) bool {
+	for _, v := range details {
+		if v == d {
+			return true
+		}
+	}
+	return false
+}
+
+// union returns a followed by the entries of b it doesn't contain. a is
+// returned as is when b adds nothing.
+func union(a, b []string) []string {
+	out := a
+	for _, v := range b {
+		if !contains(out, v) {
+			out = append(out[:len(out):len(out)], v)
+		}
+	}
+	return out
+}
+
+func contains(list []string, v string) bool {
+	for _, l := range list {
+		if l == v {
+			return true
+		}
+	}
+	return false
+}
+
+// earliest returns the smallest non-zero timestamp.
+func earliest(a, b int64) int64 {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+// samePayload reports whether a and b carry the same signed content.
+func samePayload(a, b Event) bool {
+	a.Signer, b.Signer = "", ""
+	pa, err := signingPayload(a)
+	if err != nil {
+		return false
+	}
+	pb, err := signingPayload(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(pa, pb)
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+// muteStorageKey is the local storage key of the citizen's mute list.
+const muteStorageKey = "mute-list"
+
+const (
+	muteCitizen  = "citizen"
+	muteKeyword  = "keyword"
+	muteLocation = "location"
+)
+
+// muteList is a personal filter kept in local storage. It only hides content
+// from the citizen who owns it and is never published to the network, so it
+// has no effect on what anyone else sees.
+type muteList struct {
+	Citizens  []string `json:"citizens"`
+	Keywords  []string `json:"keywords"`
+	Locations []string `json:"locations"`
+}
+
+// hidesEvent reports whether an event should be left out of the rumors and
+// news tables.
+func (m muteList) hidesEvent(e Event) bool {
+	for _, c := range m.Citizens {
+		if c == e.Reporter {
+			return true
+		}
+	}
+
+	for _, l := range m.Locations {
+		if containsFold(e.Location, l) {
+			return true
+		}
+	}
+
+	for _, k := range m.Keywords {
+		if containsFold(e.Title, k) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hidesDetail reports whether a single detail of an otherwise visible event
+// should be left out.
+func (m muteList) hidesDetail(d Detail) bool {
+	for _, c := range m.Citizens {
+		if c == d.Author {
+			return true
+		}
+	}
+
+	for _, k := range m.Keywords {
+		if containsFold(d.Text, k) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *muteList) entries(kind string) *[]string {
+	switch kind {
+	case muteCitizen:
+		return &m.Citizens
+	case muteKeyword:
+		return &m.Keywords
+	case muteLocation:
+		return &m.Locations
+	default:
+		return nil
+	}
+}
+
+// add appends value to the list of the given kind and reports whether the
+// list changed.
+func (m *muteList) add(kind, value string) bool {
+	value = strings.TrimSpace(value)
+	list := m.entries(kind)
+	if list == nil || value == "" {
+		return false
+	}
+
+	for _, v := range *list {
+		if strings.EqualFold(v, value) {
+			return false
+		}
+	}
+
+	*list = append(*list, value)
+	return true
+}
+
+func (m *muteList) remove(kind, value string) {
+	list := m.entries(kind)
+	if list == nil {
+		return
+	}
+
+	for n, v := range *list {
+		if v == value {
+			*list = append((*list)[:n], (*list)[n+1:]...)
+			return
+		}
+	}
+}
+
+// merge adds every entry of o that is not already muted.
+func (m *muteList) merge(o muteList) {
+	for _, v := range o.Citizens {
+		m.add(muteCitizen, v)
+	}
+	for _, v := range o.Keywords {
+		m.add(muteKeyword, v)
+	}
+	for _, v := range o.Locations {
+		m.add(muteLocation, v)
+	}
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+func (w *witness) loadMuteList(ctx app.Context) {
+	w.muteKind = muteKeyword
+	err := ctx.LocalStorage().Get(muteStorageKey, &w.mutes)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (w *witness) saveMuteList(ctx app.Context) {
+	err := ctx.LocalStorage().Set(muteStorageKey, w.mutes)
+	if err != nil {
+		w.createNotification(ctx, NotificationDanger, w.t(ErrorHeader), w.t("mute.saveFailed"))
+		log.Println(err)
+	}
+}
+
+func (w *witness) renderMuteModal() app.UI {
+	return app.Div().Class("p-modal").ID("mute-modal").Style("display", "none").Body(
+		app.Section().Class("p-modal__dialog").Role("dialog").Aria("modal", true).Aria("labelledby", "modal-title").Aria("describedby", "modal-description").Body(
+			app.Header().Class("p-modal__header").Body(
+				app.H2().Class("p-modal__title").ID("modal-title").Text(w.t("hero.muteList")),
+				app.Button().Class("p-modal__close").Aria("label", w.t("modal.close")).Aria("controls", "modal").OnClick(w.closeMuteModal),
+			),
+			app.P().ID("modal-description").Text(w.t("mute.description")),
+			app.Div().Class("p-form p-form--inline").Body(
+				app.Div().Class("p-form__group").Body(
+					app.Select().ID("mute-kind").OnChange(w.onMuteKind).Body(
+						app.Option().Value(muteKeyword).Text(w.t("mute.keyword")).Selected(w.muteKind == muteKeyword),
+						app.Option().Value(muteLocation).Text(w.t("mute.location")).Selected(w.muteKind == muteLocation),
+						app.Option().Value(muteCitizen).Text(w.t("mute.citizen")).Selected(w.muteKind == muteCitizen),
+					),
+				),
+				app.Div().Class("p-form__group").Body(
+					app.Input().ID("mute-value").Name("mute-value").Value(w.muteValue).OnKeyUp(w.onMuteValue),
+				),
+				app.Button().Text(w.t("mute.mute")).OnClick(w.onAddMute),
+			),
+			w.renderMuteEntries(w.t("mute.citizens"), muteCitizen, w.mutes.Citizens),
+			w.renderMuteEntries(w.t("mute.keywords"), muteKeyword, w.mutes.Keywords),
+			w.renderMuteEntries(w.t("mute.locations"), muteLocation, w.mutes.Locations),
+			app.Footer().Class("p-modal__footer").Body(
+				app.Label().Class("p-button").For("mute-import").Text(w.t("mute.import")),
+				app.Input().ID("mute-import").Type("file").Accept("application/json").Style("display", "none").OnChange(w.onImportMuteList),
+				app.Button().Class("p-button--positive").Text(w.t("mute.export")).OnClick(w.onExportMuteList),
+			),
+		),
+	)
+}
+
+func (w *witness) renderMuteEntries(title, kind string, entries []string) app.UI {
+	return app.If(len(entries) > 0, func() app.UI {
+		return app.Div().Body(
+			app.H4().Text(title),
+			app.Range(entries).Slice(func(n int) app.UI {
+				return app.Span().Class("p-chip").Body(
+					app.Span().Class("p-chip__value").Text(entries[n]),
+					app.Button().Class("p-chip__dismiss").Value(kind+":"+entries[n]).Text(w.t("mute.unmute")).OnClick(w.onRemoveMute),
+				)
+			}),
+		)
+	})
+}
+
+func (w *witness) openMuteDialog(ctx app.Context, e app.Event) {
+	app.Window().GetElementByID("mute-modal").Set("style", "display:flex")
+}
+
+func (w *witness) closeMuteModal(ctx app.Context, e app.Event) {
+	app.Window().GetElementByID("mute-modal").Set("style", "display:none")
+}
+
+func (w *witness) onMuteKind(ctx app.Context, e app.Event) {
+	w.muteKind = ctx.JSSrc().Get("value").String()
+}
+
+func (w *witness) onMuteValue(ctx app.Context, e app.Event) {
+	w.muteValue = ctx.JSSrc().Get("value").String()
+}
+
+func (w *witness) onAddMute(ctx app.Context, e app.Event) {
+	if !w.mutes.add(w.muteKind, w.muteValue) {
+		return
+	}
+
+	w.muteValue = ""
+	w.saveMuteList(ctx)
+}
+
+func (w *witness) onRemoveMute(ctx app.Context, e app.Event) {
+	kind, value, found := strings.Cut(ctx.JSSrc().Get("value").String(), ":")
+	if !found {
+		return
+	}
+
+	w.mutes.remove(kind, value)
+	w.saveMuteList(ctx)
+}
+
+func (w *witness) onMuteReporter(ctx app.Context, e app.Event) {
+	reporter := ctx.JSSrc().Get("value").String()
+	if reporter == w.citizenID || !w.mutes.add(muteCitizen, reporter) {
+		return
+	}
+
+	w.saveMuteList(ctx)
+	w.createNotification(ctx, NotificationInfo, w.t("mute.muted"), w.t("mute.citizenMuted"))
+}
+
+func (w *witness) onExportMuteList(ctx app.Context, e app.Event) {
+	b, err := json.MarshalIndent(w.mutes, "", "  ")
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	downloadFile("cyber-witness-mute-list.json", "application/json", b)
+}
+
+func (w *witness) onImportMuteList(ctx app.Context, e app.Event) {
+	readFile(ctx, ctx.JSSrc(), func(b []byte) {
+		var imported muteList
+		err := json.Unmarshal(b, &imported)
+		if err != nil {
+			w.createNotification(ctx, NotificationDanger, w.t(ErrorHeader), w.t("mute.readFailed"))
+			log.Println(err)
+			return
+		}
+
+		w.mutes.merge(imported)
+		w.saveMuteList(ctx)
+		w.createNotification(ctx, NotificationSuccess, w.t(SuccessHeader), w.t("mute.imported"))
+	})
+}
+
+// downloadFile hands data to the browser as a file download.
+func downloadFile(name, mimeType string, data []byte) {
+	buf := app.Window().Get("Uint8Array").New(len(data))
+	app.CopyBytesToJS(buf, data)
+	blob := app.Window().Get("Blob").New([]any{buf}, map[string]any{"type": mimeType})
+	url := app.Window().Get("URL").Call("createObjectURL", blob)
+
+	a := app.Window().Get("document").Call("createElement", "a")
+	a.Set("href", url)
+	a.Set("download", name)
+	a.Call("click")
+
+	app.Window().Get("URL").Call("revokeObjectURL", url)
+}
+
+// readFile reads the first file selected in a file input and calls f with its
+// content on the UI goroutine.
+func readFile(ctx app.Context, input app.Value, f func(b []byte)) {
+	files := input.Get("files")
+	if files.Get("length").Int() == 0 {
+		return
+	}
+
+	files.Index(0).Call("arrayBuffer").Then(func(v app.Value) {
+		buf := app.Window().Get("Uint8Array").New(v)
+		b := make([]byte, buf.Get("length").Int())
+		app.CopyBytesToGo(b, buf)
+		ctx.Dispatch(func(ctx app.Context) {
+			f(b)
+		})
+	})
+	input.Set("value", "")
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMuteListHidesEvent(t *testing.T) {
+	m := muteList{Citizens: []string{"66"}, Keywords: []string{"Spam"}, Locations: []string{"main st"}}
+
+	tests := []struct {
+		name  string
+		event Event
+		want  bool
+	}{
+		{"nothing muted", Event{Title: "Fire", Location: "River Rd", Reporter: "10"}, false},
+		{"muted reporter", Event{Title: "Fire", Location: "River Rd", Reporter: "66"}, true},
+		{"muted witness only", Event{Title: "Fire", Location: "River Rd", Reporter: "10", Witnesses: []string{"66"}}, false},
+		{"keyword in the title", Event{Title: "Cheap SPAM here", Reporter: "10"}, true},
+		{"keyword in a detail only", Event{Title: "Fire", Reporter: "10", Details: []Detail{{Text: "spam"}}}, false},
+		{"part of the location", Event{Title: "Fire", Location: "12 Main St, Springfield", Reporter: "10"}, true},
+		{"location muted as keyword", Event{Title: "Fire", Location: "Spam Alley", Reporter: "10"}, false},
+	}
+	for _, tt := range tests {
+		if got := m.hidesEvent(tt.event); got != tt.want {
+			t.Errorf("%s: hidesEvent = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if (muteList{}).hidesEvent(Event{Title: "Fire"}) {
+		t.Error("empty mute list hides an event")
+	}
+}
+
+func TestMuteListHidesDetail(t *testing.T) {
+	m := muteList{Citizens: []string{"66"}, Keywords: []string{"spam"}, Locations: []string{"main st"}}
+
+	tests := []struct {
+		name   string
+		detail Detail
+		want   bool
+	}{
+		{"nothing muted", Detail{Text: "smoke", Author: "10"}, false},
+		{"muted author", Detail{Text: "smoke", Author: "66"}, true},
+		{"keyword in any case", Detail{Text: "Buy SPAM", Author: "10"}, true},
+		{"locations only hide events", Detail{Text: "seen on Main St", Author: "10"}, false},
+	}
+	for _, tt := range tests {
+		if got := m.hidesDetail(tt.detail); got != tt.want {
+			t.Errorf("%s: hidesDetail = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMuteListAdd(t *testing.T) {
+	m := muteList{Keywords: []string{"spam"}}
+
+	tests := []struct {
+		name  string
+		kind  string
+		value string
+		want  bool
+	}{
+		{"new keyword", muteKeyword, "hoax", true},
+		{"duplicate in another case", muteKeyword, "SPAM", false},
+		{"surrounding spaces", muteKeyword, "  hoax ", false},
+		{"blank", muteKeyword, "   ", false},
+		{"citizen", muteCitizen, "66", true},
+		{"location", muteLocation, " Main St ", true},
+		{"unknown kind", "tag", "spam", false},
+	}
+	for _, tt := range tests {
+		if got := m.add(tt.kind, tt.value); got != tt.want {
+			t.Errorf("%s: add(%q, %q) = %v, want %v", tt.name, tt.kind, tt.value, got, tt.want)
+		}
+	}
+
+	want := muteList{Citizens: []string{"66"}, Keywords: []string{"spam", "hoax"}, Locations: []string{"Main St"}}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("mute list = %+v, want %+v", m, want)
+	}
+}
+
+func TestMuteListMerge(t *testing.T) {
+	tests := []struct {
+		name     string
+		list     muteList
+		imported muteList
+		want     muteList
+	}{
+		{
+			name:     "into an empty list",
+			imported: muteList{Citizens: []string{"66"}, Keywords: []string{"spam"}},
+			want:     muteList{Citizens: []string{"66"}, Keywords: []string{"spam"}},
+		},
+		{
+			name:     "keeps existing entries first",
+			list:     muteList{Keywords: []string{"spam"}, Locations: []string{"Main St"}},
+			imported: muteList{Keywords: []string{"hoax", "Spam"}, Locations: []string{"main st", "River Rd"}},
+			want:     muteList{Keywords: []string{"spam", "hoax"}, Locations: []string{"Main St", "River Rd"}},
+		},
+		{
+			name:     "skips blank and duplicate imports",
+			list:     muteList{Citizens: []string{"66"}},
+			imported: muteList{Citizens: []string{"66", " ", "67", "67"}},
+			want:     muteList{Citizens: []string{"66", "67"}},
+		},
+	}
+	for _, tt := range tests {
+		m := tt.list
+		m.merge(tt.imported)
+		if !reflect.DeepEqual(m, tt.want) {
+			t.Errorf("%s: merged %+v, want %+v", tt.name, m, tt.want)
+		}
+	}
+}
@@ -0,0 +1,576 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	shell "github.com/stateless-minds/go-ipfs-api"
+)
+
+// Nostr event kinds the bridge uses.
+const (
+	// nostrEventKind is the custom kind cyber-witness events are published
+	// as. It is addressable, so relays keep only the latest version of an
+	// event per bridge, named by its "d" tag.
+	nostrEventKind = 30420
+	// nostrReactionKind is the NIP-25 reaction kind.
+	nostrReactionKind = 7
+)
+
+const (
+	// nostrSubscription is the ID of the bridge's relay subscription.
+	nostrSubscription = "cyber-witness"
+	// nostrDialTimeout bounds connecting to the relay.
+	nostrDialTimeout = 10 * time.Second
+	// nostrReconnect is the delay before reconnecting to the relay.
+	nostrReconnect = 5 * time.Second
+	// defaultNostrSince is how far back the bridge mirrors events when it
+	// starts.
+	defaultNostrSince = 24 * time.Hour
+	// nostrCitizenPrefix starts the citizen IDs of Nostr users whose
+	// reactions were imported as confirmations.
+	nostrCitizenPrefix = "nostr:"
+)
+
+var errBadNostrEvent = errors.New("Nostr event is invalid")
+
+// nostrEvent is a NIP-01 event.
+type nostrEvent struct {
+	ID        string     `json:"id"`
+	PubKey    string     `json:"pubkey"`
+	CreatedAt int64      `json:"created_at"`
+	Kind      int        `json:"kind"`
+	Tags      [][]string `json:"tags"`
+	Content   string     `json:"content"`
+	Sig       string     `json:"sig"`
+}
+
+// serialize returns the bytes whose hash is the event ID. NIP-01 wants
+// them without whitespace and with only the JSON escapes that are required.
+func (ev nostrEvent) serialize() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("[0,")
+	writeNostrString(&buf, ev.PubKey)
+	buf.WriteString("," + strconv.FormatInt(ev.CreatedAt, 10) + "," + strconv.Itoa(ev.Kind) + ",[")
+	for n, tag := range ev.Tags {
+		if n > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('[')
+		for i, value := range tag {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeNostrString(&buf, value)
+		}
+		buf.WriteByte(']')
+	}
+	buf.WriteString("],")
+	writeNostrString(&buf, ev.Content)
+	buf.WriteByte(']')
+	return buf.Bytes()
+}
+
+// nostrEscapes are the escapes of NIP-01 serialization. Every other byte,
+// other control characters, U+2028, U+2029 and invalid UTF-8 included, is
+// written as is, unlike encoding/json does.
+var nostrEscapes = map[byte]string{
+	'\n': `\n`,
+	'"':  `\"`,
+	'\\': `\\`,
+	'\r': `\r`,
+	'\t': `\t`,
+	'\b': `\b`,
+	'\f': `\f`,
+}
+
+// writeNostrString writes s as a JSON string the way NIP-01 serializes it.
+func writeNostrString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		if escape, ok := nostrEscapes[s[i]]; ok {
+			buf.WriteString(escape)
+		} else {
+			buf.WriteByte(s[i])
+		}
+	}
+	buf.WriteByte('"')
+}
+
+// hash returns the event ID of ev.
+func (ev nostrEvent) hash() []byte {
+	sum := sha256.Sum256(ev.serialize())
+	return sum[:]
+}
+
+// sign sets the author, ID and signature of ev.
+func (ev *nostrEvent) sign(key *nostrKey) error {
+	ev.PubKey = key.publicKey()
+	if ev.Tags == nil {
+		ev.Tags = [][]string{}
+	}
+	id := ev.hash()
+
+	var aux [32]byte
+	if _, err := rand.Read(aux[:]); err != nil {
+		return err
+	}
+	sig := key.sign(id, aux)
+	ev.ID = hex.EncodeToString(id)
+	ev.Sig = hex.EncodeToString(sig[:])
+	return nil
+}
+
+// verifyNostrEvent checks the ID and signature of ev.
+func verifyNostrEvent(ev nostrEvent) error {
+	id := ev.hash()
+	if hex.EncodeToString(id) != ev.ID {
+		return fmt.Errorf("%w: ID doesn't match the content", errBadNostrEvent)
+	}
+
+	pub, err := hex.DecodeString(ev.PubKey)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errBadNostrEvent, err)
+	}
+	sig, err := hex.DecodeString(ev.Sig)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errBadNostrEvent, err)
+	}
+	if !verifySchnorr(pub, id, sig) {
+		return fmt.Errorf("%w: bad signature", errBadNostrEvent)
+	}
+	return nil
+}
+
+// tag returns the first value of the tag called name.
+func (ev nostrEvent) tag(name string) (string, bool) {
+	for _, t := range ev.Tags {
+		if len(t) > 1 && t[0] == name {
+			return t[1], true
+		}
+	}
+	return "", false
+}
+
+// nostrTags describes e with tags relays can filter on and clients that
+// don't know the kind can show.
+func nostrTags(e Event) [][]string {
+	tags := [][]string{
+		{"d", e.ID},
+		{"title", e.Title},
+		{"location", e.Location},
+		{"state", string(e.state())},
+		{"witnesses", strconv.Itoa(e.ConfirmedBy)},
+	}
+	for _, w := range e.Witnesses {
+		tags = append(tags, []string{"witness", w})
+	}
+
+	c := parseChannel(e.Channel)
+	if c.id() != "" {
+		tags = append(tags, []string{"channel", c.id()})
+	}
+	if c.Region != "" {
+		tags = append(tags, []string{"g", c.Region})
+	}
+	for _, t := range e.Tags {
+		tags = append(tags, []string{"t", t})
+	}
+	if e.Language != "" {
+		tags = append(tags, []string{"L", "ISO-639-1"}, []string{"l", e.Language, "ISO-639-1"})
+	}
+	return append(tags, []string{"alt", fmt.Sprintf("Cyber Witness %s: %s", e.state(), e.Title)})
+}
+
+// newNostrEvent returns the unsigned Nostr event mirroring e. The content is
+// the signed event itself, so other bridges can publish it unchanged.
+func newNostrEvent(e Event, createdAt int64) (nostrEvent, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nostrEvent{}, err
+	}
+	return nostrEvent{CreatedAt: createdAt, Kind: nostrEventKind, Tags: nostrTags(e), Content: string(b)}, nil
+}
+
+// eventFromNostr returns the event a Nostr event of nostrEventKind mirrors.
+// Unsigned events are refused since nothing else vouches for them.
+func eventFromNostr(ev nostrEvent) (Event, error) {
+	var e Event
+	if err := json.Unmarshal([]byte(ev.Content), &e); err != nil {
+		return e, fmt.Errorf("%w: %v", errBadNostrEvent, err)
+	}
+	if d, _ := ev.tag("d"); d != e.ID || e.ID == "" {
+		return e, fmt.Errorf("%w: d tag doesn't name the event", errBadNostrEvent)
+	}
+	return e, verifyEvent(e)
+}
+
+// nostrCitizenID returns the citizen ID confirmations imported from the
+// reactions of a Nostr user are made under.
+func nostrCitizenID(pubkey string) string {
+	return nostrCitizenPrefix + pubkey
+}
+
+// nostrBridge mirrors the events of some channels to a Nostr relay and the
+// events other bridges published there back to the channels.
+type nostrBridge struct {
+	watcher *changeWatcher
+	sh      *shell.Shell
+	relay   string
+	key     *nostrKey
+	// signingKey signs the confirmations imported from reactions
+	signingKey ed25519.PrivateKey
+	// reactors are the Nostr public keys whose "+" reactions are imported
+	// as confirmations. Anyone can create Nostr keys, so reactions of other
+	// keys are ignored.
+	reactors  map[string]bool
+	reconnect time.Duration
+
+	mu   sync.Mutex
+	conn *wsConn
+	// pending are the events waiting to be published to the relay
+	pending map[string]Event
+	// mirrored is the digest of the version of every event last published
+	// to or received from the relay, so nothing is sent back where it came
+	// from
+	mirrored map[string]string
+	// createdAt is the timestamp of the last Nostr event published for
+	// every event, which newer versions must exceed to replace it
+	createdAt map[string]int64
+	// published maps the IDs of the Nostr events published to the events
+	// they mirror, for reactions that only name the former
+	published map[string]string
+
+	done chan struct{}
+	once sync.Once
+	wg   sync.WaitGroup
+}
+
+// newNostrBridge returns a bridge of channels and the relay at the ws:// or
+// wss:// URL relay, signing Nostr events with key.
+func newNostrBridge(sh *shell.Shell, relay string, key *nostrKey, signingKey ed25519.PrivateKey, channels []channel) *nostrBridge {
+	b := &nostrBridge{
+		sh:         sh,
+		relay:      relay,
+		key:        key,
+		signingKey: signingKey,
+		reconnect:  nostrReconnect,
+		pending:    make(map[string]Event),
+		mirrored:   make(map[string]string),
+		createdAt:  make(map[string]int64),
+		published:  make(map[string]string),
+		done:       make(chan struct{}),
+	}
+	b.watcher = newChangeWatcher(channels, func(string, Event) {})
+	b.watcher.onUpdate = b.onUpdate
+	return b
+}
+
+// onUpdate queues a new or changed event for the relay.
+func (b *nostrBridge) onUpdate(e Event) {
+	b.mu.Lock()
+	if b.mirrored[e.ID] == eventDigest(e) {
+		b.mu.Unlock()
+		return
+	}
+	b.pending[e.ID] = e
+	b.mu.Unlock()
+	b.flush()
+}
+
+// flush publishes the pending events while the relay is connected.
+func (b *nostrBridge) flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.conn == nil {
+		return
+	}
+	for id, e := range b.pending {
+		createdAt := max(time.Now().Unix(), b.createdAt[id]+1)
+		ev, err := newNostrEvent(e, createdAt)
+		if err == nil {
+			err = ev.sign(b.key)
+		}
+		if err != nil {
+			log.Println("Could not mirror " + id + " to Nostr: " + err.Error())
+			delete(b.pending, id)
+			continue
+		}
+
+		msg, err := json.Marshal([]any{"EVENT", ev})
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		err = b.conn.writeMessage(msg)
+		if err != nil {
+			// the reader notices the broken connection and reconnects
+			return
+		}
+		delete(b.pending, id)
+		b.mirrored[id] = eventDigest(e)
+		b.createdAt[id] = createdAt
+		b.published[ev.ID] = id
+	}
+}
+
+// start connects the bridge to the relay and keeps it connected until close
+// is called. since is when the events mirrored from the relay start.
+func (b *nostrBridge) start(since time.Time) {
+	b.wg.Add(1)
+	go b.run(since)
+}
+
+func (b *nostrBridge) run(since time.Time) {
+	defer b.wg.Done()
+
+	for {
+		conn, err := dialWebSocket(b.relay, nostrDialTimeout)
+		if err == nil {
+			err = b.serve(conn, since)
+			// pick up what was published while reconnecting
+			since = time.Now().Add(-time.Minute)
+		}
+		select {
+		case <-b.done:
+			return
+		default:
+		}
+
+		log.Println("Nostr relay " + b.relay + ": " + err.Error())
+		select {
+		case <-b.done:
+			return
+		case <-time.After(b.reconnect):
+		}
+	}
+}
+
+// serve subscribes to the relay over conn and handles its messages until
+// the connection breaks.
+func (b *nostrBridge) serve(conn *wsConn, since time.Time) error {
+	filters := []any{map[string]any{"kinds": []int{nostrEventKind}, "since": since.Unix()}}
+	if len(b.reactors) > 0 {
+		var authors []string
+		for pubkey := range b.reactors {
+			authors = append(authors, pubkey)
+		}
+		sort.Strings(authors)
+		filters = append(filters, map[string]any{"kinds": []int{nostrReactionKind}, "authors": authors, "#p": []string{b.key.publicKey()}, "since": since.Unix()})
+	}
+	req, err := json.Marshal(append([]any{"REQ", nostrSubscription}, filters...))
+	if err != nil {
+		return err
+	}
+	if err := conn.writeMessage(req); err != nil {
+		conn.close()
+		return err
+	}
+
+	b.mu.Lock()
+	select {
+	case <-b.done:
+		b.mu.Unlock()
+		conn.close()
+		return nil
+	default:
+	}
+	b.conn = conn
+	b.mu.Unlock()
+	b.flush()
+
+	defer func() {
+		b.mu.Lock()
+		b.conn = nil
+		b.mu.Unlock()
+		conn.close()
+	}()
+	for {
+		msg, err := conn.readMessage()
+		if err != nil {
+			return err
+		}
+		b.handle(msg)
+	}
+}
+
+// handle processes a message from the relay.
+func (b *nostrBridge) handle(msg []byte) {
+	var parts []json.RawMessage
+	var kind string
+	if json.Unmarshal(msg, &parts) != nil || len(parts) == 0 || json.Unmarshal(parts[0], &kind) != nil {
+		log.Println("Dropped Nostr relay message: " + string(msg))
+		return
+	}
+
+	switch kind {
+	case "EVENT":
+		var ev nostrEvent
+		if len(parts) < 3 || json.Unmarshal(parts[2], &ev) != nil {
+			return
+		}
+		if err := verifyNostrEvent(ev); err != nil {
+			log.Println("Dropped Nostr event " + ev.ID + ": " + err.Error())
+			return
+		}
+		if ev.PubKey == b.key.publicKey() {
+			return
+		}
+		switch {
+		case ev.Kind == nostrEventKind:
+			b.importEvent(ev)
+		case ev.Kind == nostrReactionKind && b.reactors[ev.PubKey]:
+			b.importReaction(ev)
+		}
+	case "OK":
+		var id, message string
+		var accepted bool
+		if len(parts) < 4 || json.Unmarshal(parts[1], &id) != nil || json.Unmarshal(parts[2], &accepted) != nil {
+			return
+		}
+		json.Unmarshal(parts[3], &message)
+		if !accepted {
+			log.Println("Nostr relay refused " + id + ": " + message)
+		}
+	case "NOTICE", "CLOSED":
+		log.Println("Nostr relay: " + string(msg))
+	}
+}
+
+// importEvent publishes an event mirrored by another bridge to its channel
+// unless the channel already knows that version.
+func (b *nostrBridge) importEvent(ev nostrEvent) {
+	e, err := eventFromNostr(ev)
+	if err != nil {
+		log.Println("Dropped Nostr event " + ev.ID + ": " + err.Error())
+		return
+	}
+	c := parseChannel(e.Channel)
+	if _, ok := b.watcher.witnesses[c]; !ok {
+		return
+	}
+	digest := eventDigest(e)
+	if current, ok := b.watcher.event(e.ID); ok && eventDigest(current) == digest {
+		return
+	}
+
+	b.mu.Lock()
+	b.mirrored[e.ID] = digest
+	b.mu.Unlock()
+
+	data, err := json.Marshal(e)
+	if err == nil {
+		err = b.sh.OrbitDocsPut(c.dbName(), data)
+	}
+	if err == nil {
+		err = b.sh.PubSubPublish(c.topic(topicUpdateEvent), string(data))
+	}
+	if err != nil {
+		log.Println("Could not publish " + e.ID + " from Nostr: " + err.Error())
+	}
+}
+
+// reactionTarget returns the ID of the event a reaction is about.
+func (b *nostrBridge) reactionTarget(ev nostrEvent) string {
+	if a, ok := ev.tag("a"); ok {
+		kind, rest, _ := strings.Cut(a, ":")
+		_, id, _ := strings.Cut(rest, ":")
+		if kind == strconv.Itoa(nostrEventKind) && id != "" {
+			return id
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, t := range ev.Tags {
+		if len(t) > 1 && t[0] == "e" && b.published[t[1]] != "" {
+			return b.published[t[1]]
+		}
+	}
+	return ""
+}
+
+// importReaction confirms the event a "+" reaction is about on behalf of
+// its author, one of the reactors.
+func (b *nostrBridge) importReaction(ev nostrEvent) {
+	if ev.Content != "+" && ev.Content != "" {
+		return
+	}
+	e, ok := b.watcher.event(b.reactionTarget(ev))
+	if !ok {
+		return
+	}
+
+	citizenID := nostrCitizenID(ev.PubKey)
+	confirmer := &witness{citizenID: citizenID, key: b.signingKey, events: []Event{e}}
+	confirmed, ok := confirmer.confirmation(e.ID, time.Now())
+	if !ok {
+		return
+	}
+	_, err := storeEvent(b.sh, b.signingKey, citizenID, confirmed, topicUpdateEvent)
+	if err != nil {
+		log.Println("Could not import the confirmation of " + e.ID + " from Nostr: " + err.Error())
+	}
+}
+
+// close disconnects from the relay and waits for the bridge to stop.
+func (b *nostrBridge) close() {
+	b.once.Do(func() {
+		close(b.done)
+		b.mu.Lock()
+		if b.conn != nil {
+			b.conn.close()
+		}
+		b.mu.Unlock()
+	})
+	b.wg.Wait()
+}
+
+// loadNostrKey returns the key the bridge signs Nostr events with, kept hex
+// encoded next to the command line signing key and created on first use.
+func loadNostrKey() (*nostrKey, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, "cyber-witness", "nostr.key")
+
+	b, err := os.ReadFile(path)
+	if err == nil {
+		secret, err := hex.DecodeString(strings.TrimSpace(string(b)))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return newNostrKey(secret)
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key, err := generateNostrKey()
+	if err != nil {
+		return nil, err
+	}
+	secret := key.secret.Bytes()
+
+	err = os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		return nil, err
+	}
+	return key, os.WriteFile(path, []byte(hex.EncodeToString(secret[:])+"\n"), 0o600)
+}
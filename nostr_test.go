@@ -0,0 +1,435 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	shell "github.com/stateless-minds/go-ipfs-api"
+)
+
+func TestBIP340Vectors(t *testing.T) {
+	// the test vectors of BIP-340, those without a secret key only test
+	// verification
+	vectors := []struct {
+		n                          int
+		secret, pub, aux, msg, sig string
+		valid                      bool
+	}{
+		{0, "0000000000000000000000000000000000000000000000000000000000000003", "f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9", "0000000000000000000000000000000000000000000000000000000000000000", "0000000000000000000000000000000000000000000000000000000000000000", "e907831f80848d1069a5371b402410364bdf1c5f8307b0084c55f1ce2dca821525f66a4a85ea8b71e482a74f382d2ce5ebeee8fdb2172f477df4900d310536c0", true},
+		{1, "b7e151628aed2a6abf7158809cf4f3c762e7160f38b4da56a784d9045190cfef", "dff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659", "0000000000000000000000000000000000000000000000000000000000000001", "243f6a8885a308d313198a2e03707344a4093822299f31d0082efa98ec4e6c89", "6896bd60eeae296db48a229ff71dfe071bde413e6d43f917dc8dcf8c78de33418906d11ac976abccb20b091292bff4ea897efcb639ea871cfa95f6de339e4b0a", true},
+		{2, "c90fdaa22168c234c4c6628b80dc1cd129024e088a67cc74020bbea63b14e5c9", "dd308afec5777e13121fa72b9cc1b7cc0139715309b086c960e18fd969774eb8", "c87aa53824b4d7ae2eb035a2b5bbbccc080e76cdc6d1692c4b0b62d798e6d906", "7e2d58d8b3bcdf1abadec7829054f90dda9805aab56c77333024b9d0a508b75c", "5831aaeed7b44bb74e5eab94ba9d4294c49bcf2a60728d8b4c200f50dd313c1bab745879a5ad954a72c45a91c3a51d3c7adea98d82f8481e0e1e03674a6f3fb7", true},
+		{3, "0b432b2677937381aef05bb02a66ecd012773062cf3fa2549e44f58ed2401710", "25d1dff95105f5253c4022f628a996ad3a0d95fbf21d468a1b33f8c160d8f517", "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", "7eb0509757e246f19449885651611cb965ecc1a187dd51b64fda1edc9637d5ec97582b9cb13db3933705b32ba982af5af25fd78881ebb32771fc5922efc66ea3", true}, // message not reduced modulo p or n
+		{4, "", "d69c3509bb99e412e68b0fe8544e72837dfa30746d8be2aa65975f29d22dc7b9", "", "4df3c3f68fcc83b27e9d42c90431a72499f17875c81a599b566c9889b9696703", "00000000000000000000003b78ce563f89a0ed9414f5aa28ad0d96d6795f9c6376afb1548af603b3eb45c9f8207dee1060cb71c04e80f593060b07d28308d7f4", true},
+		{5, "", "eefdea4cdb677750a420fee807eacf21eb9898ae79b9768766e4faa04a2d4a34", "", "243f6a8885a308d313198a2e03707344a4093822299f31d0082efa98ec4e6c89", "6cff5c3ba86c69ea4b7376f31a9bcb4f74c1976089b2d9963da2e5543e17776969e89b4c5564d00349106b8497785dd7d1d713a8ae82b32fa79d5f7fc407d39b", false},                                                                                                   // public key not on the curve
+		{6, "", "dff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659", "", "243f6a8885a308d313198a2e03707344a4093822299f31d0082efa98ec4e6c89", "fff97bd5755eeea420453a14355235d382f6472f8568a18b2f057a14602975563cc27944640ac607cd107ae10923d9ef7a73c643e166be5ebeafa34b1ac553e2", false},                                                                                                   // R has an odd y
+		{7, "", "dff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659", "", "243f6a8885a308d313198a2e03707344a4093822299f31d0082efa98ec4e6c89", "1fa62e331edbc21c394792d2ab1100a7b432b013df3f6ff4f99fcb33e0e1515f28890b3edb6e7189b630448b515ce4f8622a954cfe545735aaea5134fccdb2bd", false},                                                                                                   // negated message
+		{8, "", "dff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659", "", "243f6a8885a308d313198a2e03707344a4093822299f31d0082efa98ec4e6c89", "6cff5c3ba86c69ea4b7376f31a9bcb4f74c1976089b2d9963da2e5543e177769961764b3aa9b2ffcb6ef947b6887a226e8d7c93e00c5ed0c1834ff0d0c2e6da6", false},                                                                                                   // negated s
+		{9, "", "dff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659", "", "243f6a8885a308d313198a2e03707344a4093822299f31d0082efa98ec4e6c89", "0000000000000000000000000000000000000000000000000000000000000000123dda8328af9c23a94c1feecfd123ba4fb73476f0d594dcb65c6425bd186051", false},                                                                                                   // R is infinite, x(inf) taken as 0
+		{10, "", "dff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659", "", "243f6a8885a308d313198a2e03707344a4093822299f31d0082efa98ec4e6c89", "00000000000000000000000000000000000000000000000000000000000000017615fbaf5ae28864013c099742deadb4dba87f11ac6754f93780d5a1837cf197", false},                                                                                                  // R is infinite, x(inf) taken as 1
+		{11, "", "dff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659", "", "243f6a8885a308d313198a2e03707344a4093822299f31d0082efa98ec4e6c89", "4a298dacae57395a15d0795ddbfd1dcb564da82b0f269bc70a74f8220429ba1d69e89b4c5564d00349106b8497785dd7d1d713a8ae82b32fa79d5f7fc407d39b", false},                                                                                                  // R.x not on the curve
+		{12, "", "dff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659", "", "243f6a8885a308d313198a2e03707344a4093822299f31d0082efa98ec4e6c89", "fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f69e89b4c5564d00349106b8497785dd7d1d713a8ae82b32fa79d5f7fc407d39b", false},                                                                                                  // R.x equal to the field size
+		{13, "", "dff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659", "", "243f6a8885a308d313198a2e03707344a4093822299f31d0082efa98ec4e6c89", "6cff5c3ba86c69ea4b7376f31a9bcb4f74c1976089b2d9963da2e5543e177769fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", false},                                                                                                  // s equal to the curve order
+		{14, "", "fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc30", "", "243f6a8885a308d313198a2e03707344a4093822299f31d0082efa98ec4e6c89", "6cff5c3ba86c69ea4b7376f31a9bcb4f74c1976089b2d9963da2e5543e17776969e89b4c5564d00349106b8497785dd7d1d713a8ae82b32fa79d5f7fc407d39b", false},                                                                                                  // public key exceeds the field size
+		{15, "0340034003400340034003400340034003400340034003400340034003400340", "778caa53b4393ac467774d09497a87224bf9fab6f6e68b23086497324d6fd117", "0000000000000000000000000000000000000000000000000000000000000000", "", "71535db165ecd9fbbc046e5ffaea61186bb6ad436732fccc25291a55895464cf6069ce26bf03466228f19a3a62db8a649f2d560fac652827d1af0574e427ab63", true},                                   // empty message
+		{16, "0340034003400340034003400340034003400340034003400340034003400340", "778caa53b4393ac467774d09497a87224bf9fab6f6e68b23086497324d6fd117", "0000000000000000000000000000000000000000000000000000000000000000", "11", "08a20a0afef64124649232e0693c583ab1b9934ae63b4c3511f3ae1134c6a303ea3173bfea6683bd101fa5aa5dbc1996fe7cacfc5a577d33ec14564cec2bacbf", true},                                 // message of 1 byte
+		{17, "0340034003400340034003400340034003400340034003400340034003400340", "778caa53b4393ac467774d09497a87224bf9fab6f6e68b23086497324d6fd117", "0000000000000000000000000000000000000000000000000000000000000000", "0102030405060708090a0b0c0d0e0f1011", "5130f39a4059b43bc7cac09a19ece52b5d8699d1a71e3c52da9afdb6b50ac370c4a482b77bf960f8681540e25b6771ece1e5a37fd80e5a51897c5566a97ea5a5", true}, // message of 17 bytes
+		{18, "0340034003400340034003400340034003400340034003400340034003400340", "778caa53b4393ac467774d09497a87224bf9fab6f6e68b23086497324d6fd117", "0000000000000000000000000000000000000000000000000000000000000000", strings.Repeat("99", 100), "403b12b0d8555a344175ea7ec746566303321e5dbfa8be6f091635163eca79a8585ed3e3170807e7c03b720fc54c7b23897fcba0e9d0b4a06894cfd249f22367", true},            // message of 100 bytes
+	}
+	decode := func(s string) []byte {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	for _, v := range vectors {
+		msg := decode(v.msg)
+		if got := verifySchnorr(decode(v.pub), msg, decode(v.sig)); got != v.valid {
+			t.Errorf("vector %d verifies %v, want %v", v.n, got, v.valid)
+		}
+		if v.secret == "" {
+			continue
+		}
+
+		key, err := newNostrKey(decode(v.secret))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key.publicKey() != v.pub {
+			t.Errorf("vector %d: public key = %s, want %s", v.n, key.publicKey(), v.pub)
+		}
+		var aux [32]byte
+		copy(aux[:], decode(v.aux))
+		sig := key.sign(msg, aux)
+		if got := hex.EncodeToString(sig[:]); got != v.sig {
+			t.Errorf("vector %d: signature = %s, want %s", v.n, got, v.sig)
+		}
+		if len(msg) > 0 {
+			msg[0] ^= 1
+			if verifySchnorr(key.pub[:], msg, sig[:]) {
+				t.Errorf("vector %d: signature of another message verifies", v.n)
+			}
+		}
+	}
+
+	if _, err := newNostrKey(make([]byte, 32)); !errors.Is(err, errBadNostrKey) {
+		t.Errorf("zero secret key: %v", err)
+	}
+}
+
+func TestNostrSerialization(t *testing.T) {
+	tests := []struct {
+		name, content, want string
+	}{
+		{"required escapes", "a\nb\"c\\d\re\tf\bg\fh", `a\nb\"c\\d\re\tf\bg\fh`},
+		{"no HTML escapes", "<b> & </b>", "<b> & </b>"},
+		{"line and paragraph separators", "a\u2028b\u2029c", "a\u2028b\u2029c"},
+		{"other control characters", "a\x00b\x1fc\x7f", "a\x00b\x1fc\x7f"},
+		{"invalid UTF-8", "a\xffb\xc3", "a\xffb\xc3"},
+		{"non-ASCII", "Straße 🔥", "Straße 🔥"},
+	}
+	for _, tt := range tests {
+		ev := nostrEvent{PubKey: "ab", CreatedAt: 1700000000, Kind: 1, Tags: [][]string{{"t", tt.content}, {"e"}}, Content: tt.content}
+		want := `[0,"ab",1700000000,1,[["t","` + tt.want + `"],["e"]],"` + tt.want + `"]`
+		if got := string(ev.serialize()); got != want {
+			t.Errorf("%s: serialized as %q, want %q", tt.name, got, want)
+		}
+	}
+
+	if got := string((nostrEvent{PubKey: "ab"}).serialize()); got != `[0,"ab",0,0,[],""]` {
+		t.Errorf("event without tags serialized as %s", got)
+	}
+}
+
+func TestNostrEventMirrorsSignedEvent(t *testing.T) {
+	_, reporterKey, _ := ed25519.GenerateKey(nil)
+	e, err := signEvent(withState(Event{
+		ID: "1", Type: eventType, Title: "Fire <downtown> & smoke", Location: "Main St",
+		Reporter: "11", Witnesses: []string{"12"}, ConfirmedBy: 1, CreatedAt: 100,
+		Channel: "u4pr-fire", Language: "en", Tags: []string{"fire"},
+	}), reporterKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, _ := generateNostrKey()
+	ev, err := newNostrEvent(e, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ev.sign(key); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyNostrEvent(ev); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{"d": "1", "location": "Main St", "witness": "12", "witnesses": "1", "g": "u4pr", "channel": "u4pr-fire", "t": "fire", "state": "rumor"} {
+		if got, _ := ev.tag(name); got != want {
+			t.Errorf("tag %s = %q, want %q", name, got, want)
+		}
+	}
+
+	// the ID hashes the content without HTML escapes, as NIP-01 requires
+	b := ev.serialize()
+	if !strings.Contains(string(b), `<downtown> & smoke`) {
+		t.Errorf("serialized as %s", b)
+	}
+
+	got, err := eventFromNostr(ev)
+	if err != nil || eventDigest(got) != eventDigest(e) {
+		t.Errorf("mirrored event = %+v, %v", got, err)
+	}
+
+	tampered := ev
+	tampered.Tags = append(slices.Clone(ev.Tags), []string{"witness", "13"})
+	if err := verifyNostrEvent(tampered); !errors.Is(err, errBadNostrEvent) {
+		t.Errorf("tampered tags: %v", err)
+	}
+	ev.Tags[0] = []string{"d", "2"}
+	if _, err := eventFromNostr(ev); !errors.Is(err, errBadNostrEvent) {
+		t.Errorf("mismatched d tag: %v", err)
+	}
+
+	unsigned, _ := newNostrEvent(Event{ID: "3", Type: eventType}, 200)
+	if _, err := eventFromNostr(unsigned); !errors.Is(err, errUnsigned) {
+		t.Errorf("unsigned event: %v", err)
+	}
+}
+
+// nostrFilter is the part of a NIP-01 filter the test relay understands.
+type nostrFilter struct {
+	Kinds []int    `json:"kinds"`
+	Since int64    `json:"since"`
+	P     []string `json:"#p"`
+}
+
+func (f nostrFilter) matches(ev nostrEvent) bool {
+	if len(f.Kinds) > 0 && !slices.Contains(f.Kinds, ev.Kind) || ev.CreatedAt < f.Since {
+		return false
+	}
+	if len(f.P) > 0 {
+		for _, t := range ev.Tags {
+			if len(t) > 1 && t[0] == "p" && slices.Contains(f.P, t[1]) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// testRelay is a local Nostr relay keeping the latest version of every
+// addressable event and forwarding new events to matching subscriptions.
+type testRelay struct {
+	t   *testing.T
+	url string
+
+	mu     sync.Mutex
+	events []nostrEvent
+	subs   map[*wsConn]map[string][]nostrFilter
+}
+
+func newTestRelay(t *testing.T) *testRelay {
+	r := &testRelay{t: t, subs: make(map[*wsConn]map[string][]nostrFilter)}
+	srv := httptest.NewServer(http.HandlerFunc(r.serve))
+	t.Cleanup(srv.Close)
+	r.url = "ws" + strings.TrimPrefix(srv.URL, "http")
+	return r
+}
+
+// acceptWebSocket answers the opening handshake of a WebSocket client.
+func acceptWebSocket(w http.ResponseWriter, req *http.Request) (*wsConn, error) {
+	key := req.Header.Get("Sec-WebSocket-Key")
+	if !strings.EqualFold(req.Header.Get("Upgrade"), "websocket") || key == "" {
+		http.Error(w, "not a WebSocket handshake", http.StatusBadRequest)
+		return nil, errBadWebSocket
+	}
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", wsAccept(key))
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, r: rw.Reader}, nil
+}
+
+func (r *testRelay) serve(w http.ResponseWriter, req *http.Request) {
+	conn, err := acceptWebSocket(w, req)
+	if err != nil {
+		return
+	}
+	defer func() {
+		r.mu.Lock()
+		delete(r.subs, conn)
+		r.mu.Unlock()
+		conn.close()
+	}()
+
+	for {
+		msg, err := conn.readMessage()
+		if err != nil {
+			return
+		}
+		var parts []json.RawMessage
+		var kind string
+		if json.Unmarshal(msg, &parts) != nil || len(parts) < 2 || json.Unmarshal(parts[0], &kind) != nil {
+			r.t.Errorf("relay received %s", msg)
+			return
+		}
+
+		switch kind {
+		case "EVENT":
+			var ev nostrEvent
+			json.Unmarshal(parts[1], &ev)
+			err := verifyNostrEvent(ev)
+			reply, _ := json.Marshal([]any{"OK", ev.ID, err == nil, fmt.Sprint(err)})
+			conn.writeMessage(reply)
+			if err == nil {
+				r.store(ev)
+			}
+		case "REQ":
+			var id string
+			json.Unmarshal(parts[1], &id)
+			var filters []nostrFilter
+			for _, p := range parts[2:] {
+				var f nostrFilter
+				json.Unmarshal(p, &f)
+				filters = append(filters, f)
+			}
+
+			r.mu.Lock()
+			if r.subs[conn] == nil {
+				r.subs[conn] = make(map[string][]nostrFilter)
+			}
+			r.subs[conn][id] = filters
+			var stored []nostrEvent
+			for _, ev := range r.events {
+				if slices.ContainsFunc(filters, func(f nostrFilter) bool { return f.matches(ev) }) {
+					stored = append(stored, ev)
+				}
+			}
+			r.mu.Unlock()
+
+			for _, ev := range stored {
+				b, _ := json.Marshal([]any{"EVENT", id, ev})
+				conn.writeMessage(b)
+			}
+			b, _ := json.Marshal([]any{"EOSE", id})
+			conn.writeMessage(b)
+		}
+	}
+}
+
+// store keeps ev, replacing older versions of addressable events, and sends
+// it to the subscriptions it matches.
+func (r *testRelay) store(ev nostrEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d, _ := ev.tag("d")
+	r.events = slices.DeleteFunc(r.events, func(old nostrEvent) bool {
+		od, _ := old.tag("d")
+		return ev.Kind >= 30000 && old.Kind == ev.Kind && old.PubKey == ev.PubKey && od == d
+	})
+	r.events = append(r.events, ev)
+
+	for conn, subs := range r.subs {
+		for id, filters := range subs {
+			if slices.ContainsFunc(filters, func(f nostrFilter) bool { return f.matches(ev) }) {
+				b, _ := json.Marshal([]any{"EVENT", id, ev})
+				conn.writeMessage(b)
+			}
+		}
+	}
+}
+
+// find returns the stored event of author mirroring the event with the
+// given ID.
+func (r *testRelay) find(author, id string) (nostrEvent, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, ev := range r.events {
+		if d, _ := ev.tag("d"); ev.PubKey == author && ev.Kind == nostrEventKind && d == id {
+			return ev, true
+		}
+	}
+	return nostrEvent{}, false
+}
+
+// publish sends ev to the relay like a Nostr client would.
+func (r *testRelay) publish(ev nostrEvent) {
+	r.t.Helper()
+	conn, err := dialWebSocket(r.url, time.Second)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	defer conn.close()
+
+	b, _ := json.Marshal([]any{"EVENT", ev})
+	if err := conn.writeMessage(b); err != nil {
+		r.t.Fatal(err)
+	}
+	reply, err := conn.readMessage()
+	if err != nil || !strings.Contains(string(reply), `"OK"`) {
+		r.t.Fatalf("relay answered %s, %v", reply, err)
+	}
+}
+
+func TestNostrBridgeMirrorsBothWays(t *testing.T) {
+	n := newFakeNetwork(t)
+	reporter, other := newPeer(t, n), newPeer(t, n)
+	relay := newTestRelay(t)
+
+	key, _ := generateNostrKey()
+	_, signingKey, _ := ed25519.GenerateKey(nil)
+	sh := shell.NewShell(n.addNode())
+	b := newNostrBridge(sh, relay.url, key, signingKey, []channel{{}})
+	alice, _ := generateNostrKey()
+	mallory, _ := generateNostrKey()
+	b.reactors = map[string]bool{alice.publicKey(): true}
+	b.reconnect = 10 * time.Millisecond
+
+	var mu sync.Mutex
+	connected := 0
+	subs := newSubscriber(sh, func(topic string, s subState) {
+		mu.Lock()
+		defer mu.Unlock()
+		if s == subConnected {
+			connected++
+		}
+	})
+	t.Cleanup(subs.close)
+	b.watcher.subscribe(subs)
+	b.start(time.Now().Add(-time.Hour))
+	t.Cleanup(b.close)
+	waitUntil(t, "the bridge", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		return connected == 2 && b.conn != nil
+	})
+
+	// reports reach the relay
+	id := reporter.report("Fire", "smoke", "Main St")
+	waitUntil(t, "the mirrored report", func() bool { _, ok := relay.find(key.publicKey(), id); return ok })
+	ev, _ := relay.find(key.publicKey(), id)
+	if location, _ := ev.tag("location"); location != "Main St" {
+		t.Errorf("mirrored report tagged %v", ev.Tags)
+	}
+
+	// events mirrored by other bridges reach the channel
+	_, remoteKey, _ := ed25519.GenerateKey(nil)
+	remote, _ := signEvent(withState(Event{ID: "remote-1", Type: eventType, Title: "Flood", Location: "River Rd", Reporter: "99", CreatedAt: time.Now().Unix()}), remoteKey)
+	otherBridge, _ := generateNostrKey()
+	mirrored, _ := newNostrEvent(remote, time.Now().Unix())
+	mirrored.sign(otherBridge)
+	relay.publish(mirrored)
+	waitFor(t, "the event from Nostr", func(p *peer) bool { e, ok := p.event("remote-1"); return ok && e.Title == "Flood" }, reporter, other)
+
+	// reactions of the reactors to mirrored events confirm them, reactions
+	// of other keys are ignored
+	for _, author := range []*nostrKey{mallory, alice} {
+		reaction := nostrEvent{
+			CreatedAt: time.Now().Unix(),
+			Kind:      nostrReactionKind,
+			Content:   "+",
+			Tags:      [][]string{{"e", ev.ID}, {"p", key.publicKey()}, {"k", strconv.Itoa(nostrEventKind)}},
+		}
+		reaction.sign(author)
+		relay.publish(reaction)
+	}
+	citizen := nostrCitizenID(alice.publicKey())
+	waitFor(t, "the confirmation from Nostr", func(p *peer) bool {
+		e, _ := p.event(id)
+		return slices.Contains(e.Witnesses, citizen)
+	}, reporter, other)
+	if e, _ := reporter.event(id); e.ConfirmedBy != 1 || slices.Contains(e.Witnesses, nostrCitizenID(mallory.publicKey())) {
+		t.Errorf("confirmations from Nostr = %v, want only the reactor", e.Witnesses)
+	}
+	waitUntil(t, "the confirmation on the relay", func() bool {
+		ev, _ := relay.find(key.publicKey(), id)
+		return slices.ContainsFunc(ev.Tags, func(t []string) bool { return len(t) > 1 && t[0] == "witness" && t[1] == citizen })
+	})
+
+	// the bridge doesn't mirror back what came from the relay
+	if _, ok := relay.find(key.publicKey(), "remote-1"); ok {
+		t.Error("event from Nostr mirrored back to the relay")
+	}
+}
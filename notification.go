@@ -0,0 +1,201 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+// notificationStorageKey is the local storage key of the notification
+// history.
+const notificationStorageKey = "notification-history"
+
+const (
+	// maxToasts is how many notifications are shown at once. Further ones
+	// wait in the queue until a shown one goes away.
+	maxToasts = 3
+	// maxNotificationHistory bounds the kept history, oldest entries are
+	// dropped first.
+	maxNotificationHistory = 100
+)
+
+// notificationTimeouts is how long a toast of each status stays up. Errors
+// have no timeout and stay until dismissed.
+var notificationTimeouts = map[NotificationStatus]time.Duration{
+	NotificationSuccess: 5 * time.Second,
+	NotificationInfo:    8 * time.Second,
+	NotificationWarning: 15 * time.Second,
+}
+
+type notification struct {
+	ID      int    `json:"id"`
+	Status  string `json:"status"`
+	Header  string `json:"header"`
+	Message string `json:"message"`
+	Time    int64  `json:"time"`
+
+	scheduled bool
+}
+
+// pushNotification queues a toast and records it in the history.
+func (w *witness) pushNotification(s NotificationStatus, h string, msg string, now time.Time) notification {
+	w.notificationID++
+	n := notification{
+		ID:      w.notificationID,
+		Status:  string(s),
+		Header:  h,
+		Message: msg,
+		Time:    now.Unix(),
+	}
+
+	w.notifications = append(w.notifications, n)
+	w.notificationHistory = append(w.notificationHistory, n)
+	if over := len(w.notificationHistory) - maxNotificationHistory; over > 0 {
+		w.notificationHistory = w.notificationHistory[over:]
+	}
+	return n
+}
+
+// dismissNotification removes a toast from the queue.
+func (w *witness) dismissNotification(id int) {
+	for i, n := range w.notifications {
+		if n.ID == id {
+			w.notifications = append(w.notifications[:i], w.notifications[i+1:]...)
+			return
+		}
+	}
+}
+
+// toasts returns the notifications currently shown.
+func (w *witness) toasts() []notification {
+	if len(w.notifications) > maxToasts {
+		return w.notifications[:maxToasts]
+	}
+	return w.notifications
+}
+
+func (w *witness) createNotification(ctx app.Context, s NotificationStatus, h string, msg string) {
+	w.pushNotification(s, h, msg, time.Now())
+	w.saveNotificationHistory(ctx)
+	w.scheduleToasts(ctx)
+}
+
+// scheduleToasts starts the timeout of every shown toast that has none yet,
+// so queued toasts only start counting once they are visible.
+func (w *witness) scheduleToasts(ctx app.Context) {
+	for i := range w.toasts() {
+		n := &w.notifications[i]
+		timeout, ok := notificationTimeouts[NotificationStatus(n.Status)]
+		if n.scheduled || !ok {
+			continue
+		}
+
+		n.scheduled = true
+		id := n.ID
+		ctx.After(timeout, func(ctx app.Context) {
+			w.dismissNotification(id)
+			w.scheduleToasts(ctx)
+		})
+	}
+}
+
+func (w *witness) loadNotificationHistory(ctx app.Context) {
+	err := ctx.LocalStorage().Get(notificationStorageKey, &w.notificationHistory)
+	if err != nil {
+		log.Println(err)
+	}
+	for _, n := range w.notificationHistory {
+		if n.ID > w.notificationID {
+			w.notificationID = n.ID
+		}
+	}
+}
+
+func (w *witness) saveNotificationHistory(ctx app.Context) {
+	err := ctx.LocalStorage().Set(notificationStorageKey, w.notificationHistory)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (w *witness) renderToasts() app.UI {
+	toasts := w.toasts()
+
+	return app.Div().ID("notifications").Aria("live", "polite").Aria("relevant", "additions").Style("position", "fixed").Style("top", "0").Style("width", "100%").Style("z-index", "999").Body(
+		app.Range(toasts).Slice(func(i int) app.UI {
+			n := toasts[i]
+			role := "status"
+			if n.Status == string(NotificationDanger) {
+				role = "alert"
+			}
+
+			return app.Div().Class("p-notification--" + n.Status).Role(role).Body(
+				app.Div().Class("p-notification__content").Body(
+					app.H5().Class("p-notification__title").Text(n.Header),
+					app.P().Class("p-notification__message").Text(n.Message),
+					app.Button().Class("p-notification__close").Aria("label", w.t("notifications.dismiss")).Value(n.ID).Text(w.t("notifications.close")).OnClick(w.onDismissNotification),
+				),
+			)
+		}),
+		app.If(len(w.notifications) > len(toasts), func() app.UI {
+			return app.P().Class("p-text--small").Text(strconv.Itoa(len(w.notifications)-len(toasts)) + " " + w.t("notifications.more"))
+		}),
+	)
+}
+
+func (w *witness) renderNotificationsModal() app.UI {
+	history := w.notificationHistory
+
+	return app.Div().Class("p-modal").ID("notifications-modal").Style("display", "none").Body(
+		app.Section().Class("p-modal__dialog").Role("dialog").Aria("modal", true).Aria("labelledby", "modal-title").Aria("describedby", "modal-description").Body(
+			app.Header().Class("p-modal__header").Body(
+				app.H2().Class("p-modal__title").ID("modal-title").Text(w.t("hero.notifications")),
+				app.Button().Class("p-modal__close").Aria("label", w.t("modal.close")).Aria("controls", "modal").OnClick(w.closeNotificationsModal),
+			),
+			app.If(len(history) > 0, func() app.UI {
+				return app.Ul().Class("p-list--divided").Body(
+					app.Range(history).Slice(func(i int) app.UI {
+						// newest first
+						n := history[len(history)-1-i]
+						return app.Li().Class("p-list__item").Body(
+							app.Span().Class("p-status-label--"+n.Status).Text(n.Header),
+							app.Text(" "+n.Message+" "),
+							app.Span().Class("p-text--small u-text--muted").Text(time.Unix(n.Time, 0).Format(time.RFC822)),
+						)
+					}),
+				)
+			}).Else(func() app.UI {
+				return app.P().ID("modal-description").Text(w.t("notifications.none"))
+			}),
+			w.renderWatchList(),
+			app.Footer().Class("p-modal__footer").Body(
+				app.Button().Text(w.t("notifications.clear")).Disabled(len(history) == 0).OnClick(w.onClearNotifications),
+			),
+		),
+	)
+}
+
+func (w *witness) openNotificationsDialog(ctx app.Context, e app.Event) {
+	app.Window().GetElementByID("notifications-modal").Set("style", "display:flex")
+}
+
+func (w *witness) closeNotificationsModal(ctx app.Context, e app.Event) {
+	app.Window().GetElementByID("notifications-modal").Set("style", "display:none")
+}
+
+func (w *witness) onDismissNotification(ctx app.Context, e app.Event) {
+	id, err := strconv.Atoi(ctx.JSSrc().Get("value").String())
+	if err != nil {
+		return
+	}
+
+	w.dismissNotification(id)
+	w.scheduleToasts(ctx)
+}
+
+func (w *witness) onClearNotifications(ctx app.Context, e app.Event) {
+	w.notificationHistory = nil
+	w.saveNotificationHistory(ctx)
+}
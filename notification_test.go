@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNotificationQueue(t *testing.T) {
+	w := &witness{}
+	now := time.Now()
+
+	var ids []int
+	for i := 0; i < maxToasts+2; i++ {
+		ids = append(ids, w.pushNotification(NotificationInfo, "Info", "message", now).ID)
+	}
+
+	toasts := w.toasts()
+	if len(toasts) != maxToasts || toasts[0].ID != ids[0] {
+		t.Fatalf("shown %d toasts starting at %d, want %d starting at %d", len(toasts), toasts[0].ID, maxToasts, ids[0])
+	}
+
+	w.dismissNotification(ids[1])
+	toasts = w.toasts()
+	if len(toasts) != maxToasts || toasts[1].ID != ids[2] || toasts[maxToasts-1].ID != ids[maxToasts] {
+		t.Errorf("queue did not move up after dismissal: %+v", toasts)
+	}
+	if len(w.notifications) != len(ids)-1 {
+		t.Errorf("%d queued, want %d", len(w.notifications), len(ids)-1)
+	}
+	if len(w.notificationHistory) != len(ids) {
+		t.Errorf("dismissal changed the history: %d entries", len(w.notificationHistory))
+	}
+}
+
+func TestNotificationHistoryIsBounded(t *testing.T) {
+	w := &witness{}
+	for i := 0; i < maxNotificationHistory+10; i++ {
+		w.pushNotification(NotificationSuccess, SuccessHeader, "done", time.Now())
+	}
+
+	if len(w.notificationHistory) != maxNotificationHistory {
+		t.Fatalf("history holds %d entries, want %d", len(w.notificationHistory), maxNotificationHistory)
+	}
+	if first := w.notificationHistory[0].ID; first != 11 {
+		t.Errorf("oldest kept entry is %d, want 11", first)
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+// Paths of the views that can be linked to. Every path renders the same
+// witness component, navigating between them only changes which view is
+// shown.
+const (
+	routeHome   = "/"
+	routeRumors = "/rumors"
+	routeNews   = "/news"
+	routeReport = "/report"
+	routeEvent  = "/event/"
+
+	eventRoutePattern = "^/event/[^/]+$"
+)
+
+// view is the part of the page selected by the URL.
+type view string
+
+const (
+	viewHome   view = ""
+	viewRumors view = "rumors"
+	viewNews   view = "news"
+	viewReport view = "report"
+	viewEvent  view = "event"
+)
+
+// registerRoutes associates every path of the app with the witness
+// component.
+func registerRoutes() {
+	newWitness := func() app.Composer {
+		return &witness{}
+	}
+
+	for _, path := range []string{routeHome, routeRumors, routeNews, routeReport} {
+		app.Route(path, newWitness)
+	}
+	app.RouteWithRegexp(eventRoutePattern, newWitness)
+}
+
+// parseRoute returns the view selected by path and, for events, the ID of
+// the event.
+func parseRoute(path string) (view, string) {
+	switch {
+	case path == routeRumors:
+		return viewRumors, ""
+	case path == routeNews:
+		return viewNews, ""
+	case path == routeReport:
+		return viewReport, ""
+	case strings.HasPrefix(path, routeEvent):
+		id, err := url.PathUnescape(strings.TrimPrefix(path, routeEvent))
+		if err != nil || id == "" || strings.Contains(id, "/") {
+			return viewHome, ""
+		}
+		return viewEvent, id
+	default:
+		return viewHome, ""
+	}
+}
+
+// globalChannelLink stands for the global channel in event links, whose ID
+// is empty. It can't be a region since geohashes have no l or o.
+const globalChannelLink = "global"
+
+// eventPath returns the link to an event. It carries the channel of the event
+// so the link works for citizens viewing another one.
+func eventPath(e Event) string {
+	id := e.Channel
+	if id == "" {
+		id = globalChannelLink
+	}
+	return routeEvent + url.PathEscape(e.ID) + "?channel=" + url.QueryEscape(id)
+}
+
+// linkedChannel returns the channel named by the channel parameter of an
+// event link and false if there is none or it isn't valid.
+func linkedChannel(id string) (channel, bool) {
+	switch id {
+	case "":
+		return channel{}, false
+	case globalChannelLink:
+		return channel{}, true
+	}
+	c := parseChannel(id)
+	if _, ok := newChannel(c.Region, c.Topic); !ok {
+		return c, false
+	}
+	return c, true
+}
+
+// OnNav shows the view selected by the URL. It runs on load and whenever the
+// citizen navigates, including with the browser's back and forward buttons.
+// Leaving a linked event returns to the channel the citizen chose.
+func (w *witness) OnNav(ctx app.Context) {
+	u := ctx.Page().URL()
+	w.view, w.viewEventID = parseRoute(u.Path)
+	w.linkedEvent = nil
+	w.tagFilter = ""
+	if w.view != viewEvent {
+		w.viewChannel(ctx, w.savedChannel)
+	}
+
+	switch w.view {
+	case viewRumors, viewNews:
+		w.tagFilter = u.Query().Get("tag")
+
+	case viewReport:
+		ctx.Defer(func(ctx app.Context) {
+			ctx.ScrollTo("report")
+			app.Window().GetElementByID("title").Call("focus")
+		})
+
+	case viewEvent:
+		if c, ok := linkedChannel(u.Query().Get("channel")); ok {
+			w.viewChannel(ctx, c)
+		}
+		w.loadLinkedEvent(ctx)
+	}
+}
+
+// loadLinkedEvent fetches the linked event from the store so it can be shown
+// even if it is archived or the active events are still loading.
+func (w *witness) loadLinkedEvent(ctx app.Context) {
+	id := w.viewEventID
+	c := w.channel
+
+	ctx.Async(func() {
+		e, err := getEvent(w.sh, c, id)
+		ctx.Dispatch(func(ctx app.Context) {
+			if w.view != viewEvent || w.viewEventID != id {
+				return
+			}
+			if err != nil {
+				log.Println(err)
+				return
+			}
+			if e.Type == mergedType && e.MergeInto != "" {
+				ctx.Navigate(eventPath(Event{ID: e.MergeInto, Channel: e.Channel}))
+				return
+			}
+			w.linkedEvent = &e
+		})
+	})
+}
+
+// routedEvent returns the event shown by the event view, preferring the
+// live copy over the one fetched for the link.
+func (w *witness) routedEvent() (Event, bool) {
+	if n := w.eventIndex(w.viewEventID); n >= 0 {
+		return w.events[n], true
+	}
+	if w.linkedEvent != nil && w.linkedEvent.ID == w.viewEventID {
+		return *w.linkedEvent, true
+	}
+	return Event{}, false
+}
+
+// modalDisplay returns the display style of the modal of view v.
+func (w *witness) modalDisplay(v view) string {
+	if w.view == v {
+		return "flex"
+	}
+	return "none"
+}
+
+func (w *witness) renderEventModal() app.UI {
+	e, ok := w.routedEvent()
+
+	return app.Div().Class("p-modal").ID("event-modal").Style("display", w.modalDisplay(viewEvent)).Body(
+		app.Section().Class("p-modal__dialog").Role("dialog").Aria("modal", true).Aria("labelledby", "modal-title").Aria("describedby", "modal-description").Body(
+			app.Header().Class("p-modal__header").Body(
+				app.If(ok, func() app.UI {
+					return app.H2().Class("p-modal__title").ID("modal-title").Text(e.corrected().Title)
+				}).Else(func() app.UI {
+					return app.H2().Class("p-modal__title").ID("modal-title").Text(w.t("event.heading"))
+				}),
+				app.Button().Class("p-modal__close").Aria("label", w.t("modal.close")).Aria("controls", "modal").OnClick(w.closeRouteModal),
+			),
+			app.If(!ok, func() app.UI {
+				return app.P().ID("modal-description").Text(w.t("event.notFound"))
+			}).ElseIf(w.mutes.hidesEvent(e), func() app.UI {
+				return app.P().ID("modal-description").Text(w.t("event.muted"))
+			}).Else(func() app.UI {
+				witnessed := e.Reporter == w.citizenID || contains(e.Witnesses, w.citizenID)
+
+				return app.Div().Body(
+					w.renderAmendments(e),
+					app.P().ID("modal-description").Body(
+						w.renderState(e),
+						w.renderCorrectedLabel(e),
+						renderLanguage(e.Language),
+						w.renderTags(eventFeed(e), e.Tags),
+						app.Text(" "+e.corrected().Location+" · "+w.t("event.confirmedBy")+" "+strconv.Itoa(e.ConfirmedBy)),
+					),
+					app.H4().Text(w.t("event.details")),
+					app.Range(e.Details).Slice(func(n int) app.UI {
+						return app.If(!w.mutes.hidesDetail(e.Details[n]), func() app.UI {
+							return app.Div().Class("p-card").Lang(e.Details[n].Language).Body(
+								app.P().Text(e.Details[n].Text),
+							)
+						})
+					}),
+					app.Range(e.Evidence).Slice(func(n int) app.UI {
+						return app.A().Class("p-button--base is-dense").Href(gatewayURL + e.Evidence[n]).Target("_blank").Text(w.t("event.evidence"))
+					}),
+					app.Button().Class("is-dense p-button--base").Value(e.ID).Text(w.t("event.history")).OnClick(w.onShowHistory),
+					w.renderWatchButton(e),
+					app.If(e.Type == eventType && e.ConfirmedBy < 2, func() app.UI {
+						return app.Button().Class("is-dense p-button--positive").Value(e.ID).Text(w.t("event.confirm")).Disabled(witnessed || !amendable(e)).OnClick(w.confirmRumor)
+					}),
+					w.renderDispute(e),
+					w.renderHistory(e.ID),
+					w.renderAuditLog(e),
+					w.renderCorrectionForm(e),
+					app.If(e.Type == eventType, func() app.UI {
+						return w.renderMergeControls(e)
+					}),
+					app.If(e.Type == eventType && !witnessed, func() app.UI {
+						return app.Div().Class("p-form p-form--stacked").Body(
+							app.H4().Text(w.t("event.newDetails")),
+							app.Div().Class("p-form__group row").Body(
+								app.Textarea().Class("is-dense").Name("details").Rows(2).OnKeyUp(w.onEventDetails),
+							),
+							app.Div().Class("p-form__group row").Body(
+								app.Button().Class("u-vertically-centered").Value(e.ID).Text(w.t("event.addDetails")).OnClick(w.onAddDetails),
+							),
+						)
+					}),
+				)
+			}),
+		),
+	)
+}
+
+// eventFeed returns the feed listing e.
+func eventFeed(e Event) view {
+	if e.state() == stateNews {
+		return viewNews
+	}
+	return viewRumors
+}
+
+// closeRouteModal closes the modal of a linked view by going back home.
+func (w *witness) closeRouteModal(ctx app.Context, e app.Event) {
+	ctx.Navigate(routeHome)
+}
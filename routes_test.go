@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestParseRoute(t *testing.T) {
+	tests := []struct {
+		path string
+		view view
+		id   string
+	}{
+		{"/", viewHome, ""},
+		{"/rumors", viewRumors, ""},
+		{"/news", viewNews, ""},
+		{"/report", viewReport, ""},
+		{"/event/5b0e5c1e-0c7b-4a3e-9d7a-2f1c4e8b9a10", viewEvent, "5b0e5c1e-0c7b-4a3e-9d7a-2f1c4e8b9a10"},
+		{"/event/", viewHome, ""},
+		{"/event/a/b", viewHome, ""},
+		{"/unknown", viewHome, ""},
+	}
+	for _, tt := range tests {
+		v, id := parseRoute(tt.path)
+		if v != tt.view || id != tt.id {
+			t.Errorf("parseRoute(%q) = %q, %q, want %q, %q", tt.path, v, id, tt.view, tt.id)
+		}
+	}
+}
+
+func TestEventPath(t *testing.T) {
+	tests := []struct {
+		e    Event
+		want string
+	}{
+		{Event{ID: "abc"}, "/event/abc?channel=global"},
+		{Event{ID: "abc", Channel: "u33-road-works"}, "/event/abc?channel=u33-road-works"},
+	}
+	for _, tt := range tests {
+		got := eventPath(tt.e)
+		if got != tt.want {
+			t.Errorf("eventPath(%+v) = %s, want %s", tt.e, got, tt.want)
+		}
+
+		v, id := parseRoute(got[:len("/event/abc")])
+		if v != viewEvent || id != tt.e.ID {
+			t.Errorf("parseRoute(%s) = %q, %q, want the event", got, v, id)
+		}
+		u, _ := url.Parse(got)
+		if c, ok := linkedChannel(u.Query().Get("channel")); !ok || c != parseChannel(tt.e.Channel) {
+			t.Errorf("channel of %s = %+v, %v, want %q", got, c, ok, tt.e.Channel)
+		}
+	}
+
+	for _, id := range []string{"", "a1", "u33 road"} {
+		if c, ok := linkedChannel(id); ok {
+			t.Errorf("linkedChannel(%q) = %+v, want none", id, c)
+		}
+	}
+}
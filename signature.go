@@ -0,0 +1,178 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+// signingKeyStorageKey is the local storage key of the citizen's signing key
+// seed.
+const signingKeyStorageKey = "signing-key"
+
+var (
+	errUnsigned     = errors.New("event is not signed")
+	errBadSignature = errors.New("event signature is invalid")
+)
+
+// signingPayload returns the bytes covered by an event signature. The
+// revision link is left out since it is only known once the signed event
+// has been stored.
+func signingPayload(e Event) ([]byte, error) {
+	e.Signature = ""
+	e.Revision = ""
+	return json.Marshal(e)
+}
+
+// signEvent signs e with key. Whoever changes an event signs the resulting
+// version.
+func signEvent(e Event, key ed25519.PrivateKey) (Event, error) {
+	e.Signer = signerID(key)
+
+	payload, err := signingPayload(e)
+	if err != nil {
+		return e, err
+	}
+
+	e.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, payload))
+	return e, nil
+}
+
+// signerID returns how events signed with key name their signer.
+func signerID(key ed25519.PrivateKey) string {
+	return base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+}
+
+// verifyEvent checks the signature of e. Events published before signing
+// was introduced return errUnsigned.
+func verifyEvent(e Event) error {
+	if e.Signature == "" {
+		return errUnsigned
+	}
+
+	pub, err := base64.StdEncoding.DecodeString(e.Signer)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return errBadSignature
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(e.Signature)
+	if err != nil {
+		return errBadSignature
+	}
+
+	payload, err := signingPayload(e)
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(ed25519.PublicKey(pub), payload, sig) {
+		return errBadSignature
+	}
+	return nil
+}
+
+// statementPayload returns the bytes covered by the signature of a
+// statement of kind about the event with the given ID. Statements are signed
+// on their own so they stay verifiable whoever republishes the event.
+func statementPayload(id, kind string, v any) ([]byte, error) {
+	return json.Marshal(struct {
+		Event     string `json:"event"`
+		Kind      string `json:"kind"`
+		Statement any    `json:"statement"`
+	}{id, kind, v})
+}
+
+// signStatement signs a statement of kind about the event with the given ID.
+// v must not hold the signature itself.
+func signStatement(key ed25519.PrivateKey, id, kind string, v any) (string, error) {
+	payload, err := statementPayload(id, kind, v)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(key, payload)), nil
+}
+
+// verifyStatement checks that signer, as named by signerID, signed the
+// statement.
+func verifyStatement(signer, signature, id, kind string, v any) error {
+	if signature == "" {
+		return errUnsigned
+	}
+
+	pub, err := base64.StdEncoding.DecodeString(signer)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return errBadSignature
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return errBadSignature
+	}
+
+	payload, err := statementPayload(id, kind, v)
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(ed25519.PublicKey(pub), payload, sig) {
+		return errBadSignature
+	}
+	return nil
+}
+
+// loadSigningKey returns the key kept in local storage, creating it on
+// first use.
+func loadSigningKey(ctx app.Context) ed25519.PrivateKey {
+	var seed []byte
+	err := ctx.LocalStorage().Get(signingKeyStorageKey, &seed)
+	if err == nil && len(seed) == ed25519.SeedSize {
+		return ed25519.NewKeyFromSeed(seed)
+	}
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = ctx.LocalStorage().Set(signingKeyStorageKey, key.Seed())
+	if err != nil {
+		log.Println(err)
+	}
+	return key
+}
+
+// loadSigningKeyFile is the command line counterpart of loadSigningKey and
+// keeps the key seed in the user config directory.
+func loadSigningKeyFile() (ed25519.PrivateKey, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, "cyber-witness", "signing.key")
+
+	seed, err := os.ReadFile(path)
+	if err == nil && len(seed) == ed25519.SeedSize {
+		return ed25519.NewKeyFromSeed(seed), nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		return nil, err
+	}
+	return key, os.WriteFile(path, key.Seed(), 0o600)
+}
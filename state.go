@@ -0,0 +1,293 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+// eventState is where an event is in its lifecycle.
+type eventState string
+
+const (
+	stateRumor     eventState = "rumor"
+	stateNews      eventState = "news"
+	stateDisputed  eventState = "disputed"
+	stateRetracted eventState = "retracted"
+	stateMerged    eventState = "merged"
+	stateArchived  eventState = "archived"
+)
+
+// disputeQuorum is how many citizens must dispute an event before it is
+// shown as disputed.
+const disputeQuorum = 2
+
+var (
+	errInvalidTransition = errors.New("invalid state transition")
+	errStateMismatch     = errors.New("state does not match the event")
+	errNotReporter       = errors.New("only the reporter can retract an event")
+	errNoMergeProposal   = errors.New("event was merged without an agreed proposal")
+	errNotExpired        = errors.New("event was archived before it expired")
+)
+
+// transitions lists the states every state can move to. Who triggers a
+// transition is checked by validTransition:
+//
+//   - rumor → news: witnesses, once two of them confirmed it
+//   - rumor, news → disputed: citizens other than the reporter and its
+//     witnesses, once disputeQuorum of them disputed it and the witnesses
+//     don't outnumber them
+//   - disputed → news: witnesses, once they outnumber the disputes
+//   - rumor, news, disputed → retracted: the reporter only
+//   - rumor, news, disputed → merged: participants of both duplicates, see
+//     mergeAgreed
+//   - any state but merged → archived: any client, once the event expired
+//
+// Merged and archived events don't change state anymore.
+var transitions = map[eventState][]eventState{
+	stateRumor:     {stateNews, stateDisputed, stateRetracted, stateMerged, stateArchived},
+	stateNews:      {stateDisputed, stateRetracted, stateMerged, stateArchived},
+	stateDisputed:  {stateNews, stateRetracted, stateMerged, stateArchived},
+	stateRetracted: {stateArchived},
+}
+
+// states is every state in lifecycle order, for the feed filter.
+var states = []eventState{stateRumor, stateNews, stateDisputed, stateRetracted, stateMerged, stateArchived}
+
+// state returns the lifecycle state of e. The document type records the
+// final states and the reporter records a retraction, the others follow from
+// the witnesses and disputes the event gathered. Since witnesses and
+// disputes only ever grow, every client merging the same updates reaches the
+// same state. ConfirmedBy only counts the witnesses and is never trusted on
+// its own.
+func (e Event) state() eventState {
+	switch {
+	case e.Type == mergedType:
+		return stateMerged
+	case e.Type == archivedType:
+		return stateArchived
+	case e.State == string(stateRetracted):
+		return stateRetracted
+	case len(e.Disputes) >= disputeQuorum && len(e.Disputes) >= len(e.Witnesses):
+		return stateDisputed
+	case len(e.Witnesses) > 1:
+		return stateNews
+	default:
+		return stateRumor
+	}
+}
+
+// withState returns e with its State field set to the state it is in.
+func withState(e Event) Event {
+	e.State = string(e.state())
+	return e
+}
+
+// canReach reports whether an event in state from can end up in state to,
+// directly or through updates the client missed.
+func canReach(from, to eventState) bool {
+	seen := map[eventState]bool{from: true}
+	next := []eventState{from}
+	for len(next) > 0 {
+		s := next[0]
+		next = next[1:]
+		for _, t := range transitions[s] {
+			if t == to {
+				return true
+			}
+			if !seen[t] {
+				seen[t] = true
+				next = append(next, t)
+			}
+		}
+	}
+	return false
+}
+
+// validTransition checks an incoming version of a loaded event. Its recorded
+// state and confirmation count must match its content, and the move from the
+// local state must be allowed and made by someone allowed to make it.
+func validTransition(local, incoming Event) error {
+	if incoming.ConfirmedBy != len(incoming.Witnesses) {
+		return fmt.Errorf("%w: confirmed by %d, %d witnesses", errStateMismatch, incoming.ConfirmedBy, len(incoming.Witnesses))
+	}
+	if incoming.State != "" && incoming.State != string(incoming.state()) {
+		return fmt.Errorf("%w: %s recorded, %s found", errStateMismatch, incoming.State, incoming.state())
+	}
+
+	from, to := local.state(), incoming.state()
+	if from == to {
+		return nil
+	}
+	if !canReach(from, to) {
+		return fmt.Errorf("%w from %s to %s", errInvalidTransition, from, to)
+	}
+
+	switch to {
+	case stateRetracted:
+		if incoming.Retraction == nil || verifyRetraction(local.ReporterKey, incoming.ID, *incoming.Retraction) != nil {
+			return errNotReporter
+		}
+	case stateMerged:
+		if incoming.MergeInto == "" || len(incoming.MergeVotes) == 0 {
+			return errNoMergeProposal
+		}
+	}
+	return nil
+}
+
+// validUpdate runs every check an incoming version of an event passes before
+// it is merged. local is the version the citizen knows, loaded or retired,
+// and known reports whether there is one. Versions of unknown events only
+// have to be consistent since there is nothing they move from. Merges are
+// checked against the surviving event and archives against the lifecycle of
+// the citizen, which validTransition can't see.
+//
+// Unsigned versions are only accepted for events that were never signed,
+// which predate signing: anyone could strip the signature of a forged
+// update otherwise. A local merge of signed versions counts as signed.
+func (w *witness) validUpdate(local Event, known bool, incoming Event) error {
+	signed := local.Signature != "" || len(w.sources[local.ID]) > 0
+	if err := verifyEvent(incoming); errors.Is(err, errBadSignature) || (errors.Is(err, errUnsigned) && signed) {
+		return err
+	}
+	if err := validTransition(local, incoming); err != nil && (known || errors.Is(err, errStateMismatch)) {
+		return err
+	}
+
+	if from, to := local.state(), incoming.state(); known && from != to {
+		switch to {
+		case stateMerged:
+			// votes only count from the participants the citizen knows of
+			duplicate := local
+			duplicate.MergeInto = incoming.MergeInto
+			duplicate.MergeVotes = union(local.MergeVotes, incoming.MergeVotes)
+			survivor, ok := w.knownEvent(incoming.MergeInto)
+			if !ok || survivor.ID == local.ID || !mergeAgreed(survivor, duplicate) {
+				return errNoMergeProposal
+			}
+		case stateArchived:
+			if !w.lifecycle.expired(local, time.Now()) {
+				return errNotExpired
+			}
+		}
+	}
+
+	if err := w.validTags(local, known, incoming); err != nil {
+		return err
+	}
+	if err := validCorrections(local, incoming); err != nil {
+		return err
+	}
+	return validAudit(local, incoming)
+}
+
+// dispute returns the event with the given ID disputed by the citizen. It
+// returns false for the reporter, its witnesses and citizens who already
+// disputed it.
+func (w *witness) dispute(id string, now time.Time) (Event, bool) {
+	n := w.eventIndex(id)
+	if n < 0 {
+		return Event{}, false
+	}
+
+	event := w.events[n]
+	if !canDispute(event, w.citizenID) {
+		return event, false
+	}
+
+	event.Disputes = append(event.Disputes[:len(event.Disputes):len(event.Disputes)], w.citizenID)
+	return w.logged(event, opDispute, "", now), true
+}
+
+// canDispute reports whether citizen may dispute e.
+func canDispute(e Event, citizen string) bool {
+	switch e.state() {
+	case stateRumor, stateNews, stateDisputed:
+	default:
+		return false
+	}
+	return !contains(participants(e), citizen) && !contains(e.Disputes, citizen)
+}
+
+// hidesState reports whether the state filter leaves e out of the feed.
+func (w *witness) hidesState(e Event) bool {
+	return w.stateFilter != "" && e.state() != w.stateFilter
+}
+
+func (w *witness) onDisputeEvent(ctx app.Context, e app.Event) {
+	id := ctx.JSSrc().Get("value").String()
+	event, ok := w.dispute(id, time.Now())
+	if !ok {
+		return
+	}
+
+	ctx.Async(func() {
+		event, err := w.putEvent(event, topicUpdateEvent)
+		if err != nil {
+			ctx.Dispatch(func(ctx app.Context) {
+				w.createNotification(ctx, NotificationDanger, w.t(ErrorHeader), w.t("event.disputeFailed"))
+			})
+			log.Println(err)
+			return
+		}
+
+		ctx.Dispatch(func(ctx app.Context) {
+			w.receiveUpdate(event)
+			w.createNotification(ctx, NotificationSuccess, w.t(SuccessHeader), w.t("event.disputed"))
+		})
+	})
+}
+
+func (w *witness) onFilterState(ctx app.Context, e app.Event) {
+	w.stateFilter = eventState(ctx.JSSrc().Get("value").String())
+}
+
+// renderState labels the state of e.
+func (w *witness) renderState(e Event) app.UI {
+	s := e.state()
+	class := "p-status-label"
+	switch s {
+	case stateNews:
+		class = "p-status-label--positive"
+	case stateRumor:
+		class = "p-status-label--caution"
+	case stateDisputed, stateRetracted:
+		class = "p-status-label--negative"
+	}
+
+	label := app.Span().Class(class).Text(w.t("state." + string(s)))
+	if s == stateDisputed {
+		return label.Title(fmt.Sprintf("%d %s", len(e.Disputes), w.t("state.disputedBy")))
+	}
+	return label
+}
+
+// renderStateFilter renders the state filter of a feed. Only the states an
+// active event can be in are offered.
+func (w *witness) renderStateFilter(id string) app.UI {
+	active := states[:4]
+
+	return app.Div().Class("p-form p-form--inline").Body(
+		app.Div().Class("p-form__group").Body(
+			app.Label().For(id).Text(w.t("feed.state")),
+			app.Select().ID(id).OnChange(w.onFilterState).Body(
+				app.Option().Value("").Text(w.t("feed.allStates")).Selected(w.stateFilter == ""),
+				app.Range(active).Slice(func(n int) app.UI {
+					return app.Option().Value(string(active[n])).Text(w.t("state." + string(active[n]))).Selected(active[n] == w.stateFilter)
+				}),
+			),
+		),
+	)
+}
+
+// renderDispute renders the dispute button of e for citizens allowed to
+// dispute it.
+func (w *witness) renderDispute(e Event) app.UI {
+	return app.If(canDispute(e, w.citizenID), func() app.UI {
+		return app.Button().Class("is-dense p-button--negative").Value(e.ID).Text(w.t("event.dispute")).OnClick(w.onDisputeEvent)
+	})
+}
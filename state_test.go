@@ -0,0 +1,174 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestEventState(t *testing.T) {
+	tests := []struct {
+		name  string
+		event Event
+		want  eventState
+	}{
+		{"new report", Event{Type: eventType}, stateRumor},
+		{"confirmed", Event{Type: eventType, ConfirmedBy: 2, Witnesses: []string{"1", "2"}}, stateNews},
+		{"count without witnesses", Event{Type: eventType, ConfirmedBy: 5}, stateRumor},
+		{"one dispute", Event{Type: eventType, Disputes: []string{"3"}}, stateRumor},
+		{"disputed", Event{Type: eventType, Disputes: []string{"3", "4"}}, stateDisputed},
+		{"witnesses outnumber disputes", Event{Type: eventType, ConfirmedBy: 3, Witnesses: []string{"1", "2", "5"}, Disputes: []string{"3", "4"}}, stateNews},
+		{"retracted", Event{Type: eventType, State: "retracted", Disputes: []string{"3", "4"}}, stateRetracted},
+		{"archived", Event{Type: archivedType, State: "retracted"}, stateArchived},
+		{"merged", Event{Type: mergedType}, stateMerged},
+	}
+	for _, tt := range tests {
+		if got := tt.event.state(); got != tt.want {
+			t.Errorf("%s: state = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCanReach(t *testing.T) {
+	if !canReach(stateRumor, stateNews) || !canReach(stateRetracted, stateArchived) {
+		t.Error("allowed transition refused")
+	}
+	for _, to := range []eventState{stateRumor, stateNews, stateDisputed} {
+		if canReach(stateRetracted, to) {
+			t.Errorf("retracted event reached %s", to)
+		}
+	}
+	if canReach(stateArchived, stateRumor) || canReach(stateMerged, stateNews) {
+		t.Error("final state left")
+	}
+}
+
+func TestValidTransition(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, other, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local := Event{ID: "1", Type: eventType, Reporter: "10", ReporterKey: signerID(key)}
+	retract := func(k ed25519.PrivateKey) Event {
+		r, err := signRetraction(k, local.ID, Retraction{Reason: "wrong street", At: 100})
+		if err != nil {
+			t.Fatal(err)
+		}
+		e := local
+		e.State = string(stateRetracted)
+		e.Retraction = &r
+		return e
+	}
+
+	if err := validTransition(local, retract(key)); err != nil {
+		t.Errorf("retraction by the reporter: %v", err)
+	}
+	if err := validTransition(local, retract(other)); !errors.Is(err, errNotReporter) {
+		t.Errorf("retraction by someone else: %v, want %v", err, errNotReporter)
+	}
+
+	forged := withState(local)
+	forged.State = string(stateNews)
+	if err := validTransition(local, forged); !errors.Is(err, errStateMismatch) {
+		t.Errorf("news without confirmations: %v, want %v", err, errStateMismatch)
+	}
+
+	counted := local
+	counted.ConfirmedBy = 2
+	if err := validTransition(local, counted); !errors.Is(err, errStateMismatch) {
+		t.Errorf("confirmations without witnesses: %v, want %v", err, errStateMismatch)
+	}
+
+	archived := withState(Event{ID: "1", Type: archivedType})
+	if err := validTransition(archived, withState(local)); !errors.Is(err, errInvalidTransition) {
+		t.Errorf("archived event revived: %v, want %v", err, errInvalidTransition)
+	}
+
+	merged := local
+	merged.Type = mergedType
+	if err := validTransition(local, merged); !errors.Is(err, errNoMergeProposal) {
+		t.Errorf("merge without votes: %v, want %v", err, errNoMergeProposal)
+	}
+}
+
+func TestArchiveNeedsExpiry(t *testing.T) {
+	w := &witness{lifecycle: defaultLifecycle}
+	now := time.Now()
+	w.receiveUpdate(Event{ID: "1", Type: eventType, CreatedAt: now.Add(-time.Hour).Unix()})
+	w.receiveUpdate(Event{ID: "2", Type: eventType, CreatedAt: now.Add(-8 * 24 * time.Hour).Unix()})
+
+	for _, id := range []string{"1", "2"} {
+		e := w.events[w.eventIndex(id)]
+		e.Type = archivedType
+		// a made up report date doesn't make the event expire sooner
+		e.CreatedAt = 1
+		w.receiveUpdate(e)
+	}
+	if w.eventIndex("1") < 0 {
+		t.Error("fresh rumor archived")
+	}
+	if w.eventIndex("2") >= 0 {
+		t.Error("expired rumor still active")
+	}
+}
+
+func TestDispute(t *testing.T) {
+	w := &witness{citizenID: "10"}
+	w.receiveUpdate(Event{ID: "1", Type: eventType, Reporter: "10", Witnesses: []string{"11"}, ConfirmedBy: 1})
+
+	if _, ok := w.dispute("1", time.Unix(100, 0)); ok {
+		t.Error("reporter disputed their own event")
+	}
+
+	for _, citizen := range []string{"20", "21"} {
+		w.citizenID = citizen
+		e, ok := w.dispute("1", time.Unix(100, 0))
+		if !ok {
+			t.Fatalf("citizen %s could not dispute", citizen)
+		}
+		w.receiveUpdate(e)
+		if _, ok := w.dispute("1", time.Unix(100, 0)); ok {
+			t.Errorf("citizen %s disputed twice", citizen)
+		}
+	}
+
+	if s := w.events[w.eventIndex("1")].state(); s != stateDisputed {
+		t.Errorf("state after two disputes = %s, want %s", s, stateDisputed)
+	}
+
+	w.citizenID = "20"
+	if _, ok := w.confirmation("1", time.Now()); ok {
+		t.Error("citizen disputing the event confirmed it")
+	}
+}
+
+func TestDisputedNewsLeavesTheNews(t *testing.T) {
+	news := Event{ID: "1", Type: eventType, Witnesses: []string{"11", "12"}, ConfirmedBy: 2}
+	disputed := news
+	disputed.Disputes = []string{"20", "21"}
+	retracted := news
+	retracted.State = string(stateRetracted)
+
+	w := &witness{events: []Event{disputed, retracted}}
+	w.updateNoNews()
+	if !w.noNews {
+		t.Error("disputed and retracted events count as news")
+	}
+	for _, e := range w.events {
+		if w.inNews(e) || eventFeed(e) != viewRumors {
+			t.Errorf("%s event listed in the news", e.state())
+		}
+	}
+
+	w.events = append(w.events, news)
+	w.updateNoNews()
+	if w.noNews || !w.inNews(news) || eventFeed(news) != viewNews {
+		t.Error("confirmed event missing from the news")
+	}
+}
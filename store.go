@@ -0,0 +1,116 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+
+	shell "github.com/stateless-minds/go-ipfs-api"
+)
+
+var errEventNotFound = errors.New("event not found")
+
+// storedTypes are the document types kept in the event store.
+var storedTypes = []string{eventType, archivedType, mergedType}
+
+// queryEvents returns the events of the given document type in the store
+// of channel c.
+func queryEvents(sh *shell.Shell, c channel, typ string) ([]Event, error) {
+	v, err := sh.OrbitDocsQuery(c.dbName(), "type", typ)
+	if err != nil {
+		return nil, err
+	}
+	return decodeEvents(v)
+}
+
+// getEvent returns the event with the given ID from the store of channel c,
+// whatever its type.
+func getEvent(sh *shell.Shell, c channel, id string) (Event, error) {
+	v, err := sh.OrbitDocsGet(c.dbName(), id)
+	if err != nil {
+		return Event{}, err
+	}
+
+	events, err := decodeEvents(v)
+	if err != nil {
+		return Event{}, err
+	}
+	if len(events) == 0 {
+		return Event{}, errEventNotFound
+	}
+	return events[0], nil
+}
+
+// decodeEvents decodes the list of documents returned by the docs store.
+func decodeEvents(v []byte) ([]Event, error) {
+	var vv []interface{}
+	err := json.Unmarshal(v, &vv)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]Event, 0, len(vv))
+	for _, ii := range vv {
+		e, err := decodeEvent(ii)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// loadAllEvents returns both active and archived events of the given
+// channels.
+func loadAllEvents(sh *shell.Shell, channels []channel) ([]Event, error) {
+	var events []Event
+	for _, c := range channels {
+		for _, typ := range storedTypes {
+			ev, err := queryEvents(sh, c, typ)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, ev...)
+		}
+	}
+	sortEvents(events)
+	return events, nil
+}
+
+// storeEvent signs e, records it as a new revision, stores it in the docs
+// store of its channel and announces it on the channel's variant of topic.
+// It returns the event as stored.
+func storeEvent(sh *shell.Shell, key ed25519.PrivateKey, author string, e Event, topic string) (Event, error) {
+	e, err := signEvent(withState(e), key)
+	if err != nil {
+		return e, err
+	}
+
+	e, err = recordRevision(sh, e, author)
+	if err != nil {
+		return e, err
+	}
+
+	return e, publishEvent(sh, e, topic)
+}
+
+// publishEvent stores e as it is in the docs store of its channel and
+// announces it on the channel's variant of topic.
+func publishEvent(sh *shell.Shell, e Event, topic string) error {
+	ev, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	c := parseChannel(e.Channel)
+	err = sh.OrbitDocsPut(c.dbName(), ev)
+	if err != nil {
+		return err
+	}
+
+	return sh.PubSubPublish(c.topic(topic), string(ev))
+}
+
+func (w *witness) putEvent(e Event, topic string) (Event, error) {
+	return storeEvent(w.sh, w.key, w.citizenID, e, topic)
+}
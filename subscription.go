@@ -0,0 +1,256 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+	shell "github.com/stateless-minds/go-ipfs-api"
+)
+
+// Bounds of the delay between reconnection attempts. The delay doubles with
+// every failed attempt.
+const (
+	minBackoff = time.Second
+	maxBackoff = 30 * time.Second
+)
+
+// subState is the connection state of a pubsub subscription.
+type subState string
+
+const (
+	subConnecting   subState = "connecting"
+	subConnected    subState = "connected"
+	subReconnecting subState = "reconnecting"
+)
+
+// subscriber owns one long-lived subscription per topic. Each topic is read
+// by a single goroutine so messages are handled in order, and a broken
+// subscription is reopened with exponential backoff until the topic is
+// unsubscribed.
+type subscriber struct {
+	sh         *shell.Shell
+	onState    func(topic string, s subState)
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	mu     sync.Mutex
+	topics map[string]*topicSubscription
+}
+
+type topicSubscription struct {
+	stop chan struct{}
+	sub  *shell.PubSubSubscription
+}
+
+// newSubscriber returns a subscriber reporting state changes to onState,
+// which may be nil.
+func newSubscriber(sh *shell.Shell, onState func(topic string, s subState)) *subscriber {
+	if onState == nil {
+		onState = func(string, subState) {}
+	}
+	return &subscriber{
+		sh:         sh,
+		onState:    onState,
+		minBackoff: minBackoff,
+		maxBackoff: maxBackoff,
+		topics:     make(map[string]*topicSubscription),
+	}
+}
+
+// subscribe calls handle with the data of every message published on topic
+// until it is unsubscribed. Subscribing to a topic twice has no effect.
+func (s *subscriber) subscribe(topic string, handle func(data []byte)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.topics[topic]; ok {
+		return
+	}
+
+	t := &topicSubscription{stop: make(chan struct{})}
+	s.topics[topic] = t
+	go s.run(topic, t, handle)
+}
+
+// unsubscribe stops reading topic.
+func (s *subscriber) unsubscribe(topic string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.topics[topic]
+	if !ok {
+		return
+	}
+
+	delete(s.topics, topic)
+	close(t.stop)
+	if t.sub != nil {
+		t.sub.Cancel()
+	}
+}
+
+// close unsubscribes from every topic.
+func (s *subscriber) close() {
+	s.mu.Lock()
+	topics := make([]string, 0, len(s.topics))
+	for topic := range s.topics {
+		topics = append(topics, topic)
+	}
+	s.mu.Unlock()
+
+	for _, topic := range topics {
+		s.unsubscribe(topic)
+	}
+}
+
+func (s *subscriber) run(topic string, t *topicSubscription, handle func(data []byte)) {
+	state := subConnecting
+	for attempt := 0; ; attempt++ {
+		s.report(topic, t, state)
+		if attempt > 0 && !s.wait(t, s.backoff(attempt-1)) {
+			return
+		}
+
+		sub, err := s.sh.PubSubSubscribe(topic)
+		if err != nil {
+			log.Println("Could not subscribe to topic " + topic + ": " + err.Error())
+			state = subReconnecting
+			continue
+		}
+
+		s.mu.Lock()
+		select {
+		case <-t.stop:
+			s.mu.Unlock()
+			sub.Cancel()
+			return
+		default:
+			t.sub = sub
+		}
+		s.mu.Unlock()
+
+		attempt = 0
+		s.report(topic, t, subConnected)
+		for {
+			msg, err := sub.Next()
+			if err != nil {
+				break
+			}
+			handle(msg.Data)
+		}
+		sub.Cancel()
+
+		select {
+		case <-t.stop:
+			return
+		default:
+			log.Println("Lost subscription to topic " + topic)
+			state = subReconnecting
+		}
+	}
+}
+
+// report passes a state change of t to onState unless t was stopped.
+func (s *subscriber) report(topic string, t *topicSubscription, state subState) {
+	select {
+	case <-t.stop:
+	default:
+		s.onState(topic, state)
+	}
+}
+
+// backoff returns the delay before the reconnection attempt following n
+// failed ones.
+func (s *subscriber) backoff(n int) time.Duration {
+	d := s.minBackoff
+	for i := 0; i < n && d < s.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > s.maxBackoff {
+		d = s.maxBackoff
+	}
+	return d
+}
+
+// wait sleeps for d and reports false if t was stopped in the meantime.
+func (s *subscriber) wait(t *topicSubscription, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-t.stop:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// subscribeChannel subscribes to the event topics of channel c.
+func (w *witness) subscribeChannel(ctx app.Context, c channel) {
+	w.subs.subscribe(c.topic(topicCreateEvent), func(data []byte) {
+		w.onEventMessage(ctx, c, data, w.receiveEvent)
+	})
+	w.subs.subscribe(c.topic(topicUpdateEvent), func(data []byte) {
+		w.onEventMessage(ctx, c, data, func(e Event) {
+			w.receiveWatchedUpdate(ctx, e)
+		})
+	})
+	w.subscribeSync(ctx, c)
+}
+
+// unsubscribeChannel stops listening to the event topics of channel c.
+func (w *witness) unsubscribeChannel(c channel) {
+	for _, topic := range []string{c.topic(topicCreateEvent), c.topic(topicUpdateEvent), c.topic(topicSync)} {
+		w.subs.unsubscribe(topic)
+		delete(w.subStates, topic)
+	}
+}
+
+// onEventMessage decodes a message of channel c and hands it to receive on
+// the UI goroutine.
+func (w *witness) onEventMessage(ctx app.Context, c channel, data []byte, receive func(Event)) {
+	e, err := decodeMessage(data)
+	if err != nil {
+		log.Println("Dropped message on channel " + c.name() + ": " + err.Error())
+		return
+	}
+	if parseChannel(e.Channel) != c {
+		log.Println("Dropped event " + e.ID + " of another channel")
+		return
+	}
+
+	ctx.Dispatch(func(ctx app.Context) {
+		if c == w.channel {
+			receive(e)
+			w.scheduleCacheSave(ctx)
+		}
+	})
+}
+
+// connectionState sums up the state of the current channel's subscriptions.
+func (w *witness) connectionState() subState {
+	state := subConnected
+	for _, topic := range []string{w.channel.topic(topicCreateEvent), w.channel.topic(topicUpdateEvent)} {
+		switch w.subStates[topic] {
+		case subReconnecting:
+			return subReconnecting
+		case subConnected:
+		default:
+			state = subConnecting
+		}
+	}
+	return state
+}
+
+func (w *witness) renderConnectionState() app.UI {
+	switch w.connectionState() {
+	case subConnected:
+		return app.Span().Class("p-status-label--positive").Text(w.t("status.connected"))
+	case subReconnecting:
+		return app.Span().Class("p-status-label--caution").Text(w.t("status.reconnecting"))
+	default:
+		return app.Span().Class("p-status-label").Text(w.t("status.connecting"))
+	}
+}
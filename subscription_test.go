@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	s := newSubscriber(nil, nil)
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 30 * time.Second, 30 * time.Second}
+	for n, d := range want {
+		if got := s.backoff(n); got != d {
+			t.Errorf("backoff(%d) = %v, want %v", n, got, d)
+		}
+	}
+}
+
+func TestSubscriberReconnects(t *testing.T) {
+	n := newFakeNetwork(t)
+	reporter, alice := newPeer(t, n), newPeer(t, n)
+
+	n.dropSubscriptions()
+	waitFor(t, "the reconnection", func(p *peer) bool {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return p.connects[topicCreateEvent] == 2 && p.connects[topicUpdateEvent] == 2
+	}, reporter, alice)
+
+	id := reporter.report("Street party", "Music on every corner", "Harbour")
+	waitFor(t, "the rumor", hasEvent(id), alice)
+}
+
+func TestSubscriberUnsubscribe(t *testing.T) {
+	n := newFakeNetwork(t)
+	reporter, alice := newPeer(t, n), newPeer(t, n)
+
+	alice.w.subs.close()
+	first := reporter.report("Parade", "Road closed for the parade", "Centre")
+	waitFor(t, "the rumor", hasEvent(first), reporter)
+
+	// subscriptions are reopened after close has stopped the old ones
+	alice.subscribe(topicCreateEvent, alice.w.receiveEvent)
+	waitFor(t, "the new subscription", func(p *peer) bool {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return p.states[topicCreateEvent] == subConnected
+	}, alice)
+
+	second := reporter.report("Concert", "Stage on the square", "Centre")
+	waitFor(t, "the second rumor", hasEvent(second), alice)
+	if _, ok := alice.event(first); ok {
+		t.Error("received a rumor while unsubscribed")
+	}
+}
@@ -0,0 +1,430 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+// topicSync is the base topic peers of a channel compare their events on.
+const topicSync = "sync-events"
+
+const (
+	// syncBuckets is how many buckets event IDs are spread over. Peers
+	// only exchange the entries of the buckets they disagree on.
+	syncBuckets = 16
+	// syncInterval is the delay between two summaries of a peer.
+	syncInterval = time.Minute
+	// syncFirstDelay is the delay before the first summary, leaving time
+	// to load the stored events.
+	syncFirstDelay = 10 * time.Second
+	// maxSyncEvents bounds the events sent in a single message.
+	maxSyncEvents = 20
+)
+
+// Kinds of sync messages. A peer broadcasts a summary, the peers that
+// disagree answer with an offer of their entries in the differing buckets,
+// and both sides then request and send the events the other lacks.
+const (
+	syncSummary = "summary"
+	syncOffer   = "offer"
+	syncRequest = "request"
+	syncEvents  = "events"
+)
+
+// syncEntry identifies one version of an event.
+type syncEntry struct {
+	ID     string `json:"id"`
+	Digest string `json:"digest"`
+}
+
+// syncMessage is published on the sync topic of a channel. From and To are
+// the random session IDs of peers, summaries have no recipient.
+type syncMessage struct {
+	Kind    string `json:"kind"`
+	Channel string `json:"channel,omitempty"`
+	From    string `json:"from"`
+	To      string `json:"to,omitempty"`
+	// Root and Buckets summarise every event of the sender, see summarize.
+	Root    string   `json:"root,omitempty"`
+	Buckets []string `json:"buckets,omitempty"`
+	// Differing are the buckets an offer covers, Entries the sender's
+	// entries in them.
+	Differing []int       `json:"differing,omitempty"`
+	Entries   []syncEntry `json:"entries,omitempty"`
+	IDs       []string    `json:"ids,omitempty"`
+	Events    []Event     `json:"events,omitempty"`
+}
+
+// syncStats measures how far the peer drifted from the others.
+type syncStats struct {
+	// Rounds counts the summaries compared, InSync those that matched.
+	Rounds int
+	InSync int
+	// Divergent is how many buckets differed in the last comparison.
+	Divergent int
+	// Requested counts the events asked from peers, Recovered those that
+	// arrived and changed something, Sent the events given to peers.
+	Requested int
+	Recovered int
+	Sent      int
+	LastSync  int64
+}
+
+// eventDigest hashes what peers agree on once they merged the same updates.
+// Merging keeps the local order of lists and the local revision, so lists
+// are sorted and revisions and signatures left out.
+func eventDigest(e Event) string {
+	sorted := func(list []string) []string {
+		s := slices.Clone(list)
+		sort.Strings(s)
+		return s
+	}
+
+	details := make([]string, 0, len(e.Details))
+	for _, d := range e.Details {
+		details = append(details, d.Author+":"+d.Text)
+	}
+	var corrections, audit []string
+	for _, c := range e.Corrections {
+		corrections = append(corrections, c.Signature)
+	}
+	for _, a := range e.Audit {
+		audit = append(audit, a.Signature)
+	}
+	retracted := e.Retraction != nil
+
+	b, _ := json.Marshal([]any{
+		e.ID, e.Type, e.state(), e.Title, e.Location, e.Reporter, e.ConfirmedBy,
+		sorted(e.Witnesses), sorted(details), sorted(e.Evidence), sorted(e.Tags),
+		sorted(e.Disputes), e.MergeInto, sorted(e.MergeVotes), sorted(e.MergedFrom),
+		sorted(corrections), retracted, sorted(audit),
+	})
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:8])
+}
+
+// syncBucket returns the bucket of the event with the given ID.
+func syncBucket(id string) int {
+	sum := sha256.Sum256([]byte(id))
+	return int(sum[0]) % syncBuckets
+}
+
+// summarize returns the digest of every bucket of events and the root
+// digest of them all.
+func summarize(events []Event) (root string, buckets []string) {
+	lines := make([][]string, syncBuckets)
+	for _, e := range events {
+		b := syncBucket(e.ID)
+		lines[b] = append(lines[b], e.ID+":"+eventDigest(e))
+	}
+
+	all := sha256.New()
+	buckets = make([]string, syncBuckets)
+	for n, l := range lines {
+		sort.Strings(l)
+		h := sha256.New()
+		for _, line := range l {
+			h.Write([]byte(line + "\n"))
+		}
+		buckets[n] = hex.EncodeToString(h.Sum(nil)[:8])
+		all.Write([]byte(buckets[n]))
+	}
+	return hex.EncodeToString(all.Sum(nil)[:8]), buckets
+}
+
+// newSyncID returns a random session ID for the sync protocol. Citizen IDs
+// can't be used since several sessions may share one.
+func newSyncID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
+
+// retire keeps the last version of an event leaving the active views, so
+// sync neither brings it back nor forgets to tell peers it ended.
+func (w *witness) retire(e Event) {
+	if w.retired == nil {
+		w.retired = make(map[string]Event)
+	}
+	if r, ok := w.retired[e.ID]; ok {
+		merged, _ := mergeEvents(r, e)
+		w.keepSources(r, e, merged)
+		e = merged
+	}
+	w.retired[e.ID] = e
+}
+
+// keepSources records the signed versions merged combines when the merge
+// matches neither of them and so carries no signature. Peers only accept
+// signed versions of signed events, so sync passes these on instead.
+func (w *witness) keepSources(local, incoming, merged Event) {
+	if merged.Signature != "" {
+		delete(w.sources, merged.ID)
+		return
+	}
+
+	sources := w.sources[merged.ID]
+	for _, v := range []Event{local, incoming} {
+		if v.Signature != "" && !slices.ContainsFunc(sources, func(s Event) bool { return s.Signature == v.Signature }) {
+			sources = append(sources, v)
+		}
+	}
+	if len(sources) == 0 {
+		return
+	}
+	if w.sources == nil {
+		w.sources = make(map[string][]Event)
+	}
+	w.sources[merged.ID] = sources
+}
+
+// syncedEvents returns the events the peer compares with others: the loaded
+// ones and the ones retired this session.
+func (w *witness) syncedEvents() []Event {
+	events := slices.Clone(w.events)
+	for _, e := range w.retired {
+		if w.eventIndex(e.ID) < 0 {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// syncSummary returns the summary the peer broadcasts.
+func (w *witness) syncSummary() syncMessage {
+	root, buckets := summarize(w.syncedEvents())
+	return syncMessage{Kind: syncSummary, Channel: w.channel.id(), From: w.syncID, Root: root, Buckets: buckets}
+}
+
+// handleSync processes a sync message and returns the replies to publish.
+func (w *witness) handleSync(m syncMessage, now time.Time) []syncMessage {
+	if m.From == w.syncID || (m.To != "" && m.To != w.syncID) {
+		return nil
+	}
+
+	events := w.syncedEvents()
+	switch m.Kind {
+	case syncSummary:
+		if len(m.Buckets) != syncBuckets {
+			return nil
+		}
+		root, buckets := summarize(events)
+		w.syncStats.Rounds++
+		w.syncStats.LastSync = now.Unix()
+		if m.Root == root {
+			w.syncStats.InSync++
+			w.syncStats.Divergent = 0
+			return nil
+		}
+
+		var differing []int
+		for n := range buckets {
+			if buckets[n] != m.Buckets[n] {
+				differing = append(differing, n)
+			}
+		}
+		w.syncStats.Divergent = len(differing)
+
+		offer := w.syncReply(syncOffer, m.From)
+		offer.Differing = differing
+		for _, e := range events {
+			if slices.Contains(differing, syncBucket(e.ID)) {
+				offer.Entries = append(offer.Entries, syncEntry{ID: e.ID, Digest: eventDigest(e)})
+			}
+		}
+		return []syncMessage{offer}
+
+	case syncOffer:
+		offered := make(map[string]string, len(m.Entries))
+		var want []string
+		for _, o := range m.Entries {
+			offered[o.ID] = o.Digest
+		}
+
+		var push []Event
+		for _, e := range events {
+			if !slices.Contains(m.Differing, syncBucket(e.ID)) {
+				continue
+			}
+			if d, ok := offered[e.ID]; !ok || d != eventDigest(e) {
+				push = append(push, e)
+			}
+		}
+		for _, o := range m.Entries {
+			if n := slices.IndexFunc(events, func(e Event) bool { return e.ID == o.ID }); n < 0 || eventDigest(events[n]) != o.Digest {
+				want = append(want, o.ID)
+			}
+		}
+
+		var replies []syncMessage
+		if len(want) > 0 {
+			req := w.syncReply(syncRequest, m.From)
+			req.IDs = want
+			w.syncStats.Requested += len(want)
+			replies = append(replies, req)
+		}
+		return append(replies, w.sendEvents(m.From, push)...)
+
+	case syncRequest:
+		var push []Event
+		for _, e := range events {
+			if slices.Contains(m.IDs, e.ID) {
+				push = append(push, e)
+			}
+		}
+		return w.sendEvents(m.From, push)
+
+	case syncEvents:
+		for _, e := range m.Events {
+			if w.receiveSynced(e) {
+				w.syncStats.Recovered++
+			}
+		}
+	}
+	return nil
+}
+
+// syncReply returns an empty message of kind addressed to peer.
+func (w *witness) syncReply(kind, peer string) syncMessage {
+	return syncMessage{Kind: kind, Channel: w.channel.id(), From: w.syncID, To: peer}
+}
+
+// sendEvents returns the messages carrying events to peer. Events are never
+// signed again on the way, the peer would vouch for changes it didn't make:
+// an event merged locally from concurrent updates is sent as the signed
+// versions it combines, which the receiver merges the same way.
+func (w *witness) sendEvents(peer string, events []Event) []syncMessage {
+	var versions []Event
+	for _, e := range events {
+		if sources := w.sources[e.ID]; e.Signature == "" && len(sources) > 0 {
+			versions = append(versions, sources...)
+		} else {
+			versions = append(versions, e)
+		}
+	}
+	events = versions
+
+	var msgs []syncMessage
+	for len(events) > 0 {
+		n := min(len(events), maxSyncEvents)
+		m := w.syncReply(syncEvents, peer)
+		m.Events = events[:n]
+		msgs = append(msgs, m)
+		w.syncStats.Sent += n
+		events = events[n:]
+	}
+	return msgs
+}
+
+// receiveSynced applies an event sent by a peer like an update and reports
+// whether it changed anything.
+func (w *witness) receiveSynced(e Event) bool {
+	if parseChannel(e.Channel) != w.channel {
+		return false
+	}
+
+	before := w.knownDigest(e.ID)
+	w.receiveUpdate(e)
+	return w.knownDigest(e.ID) != before
+}
+
+// knownEvent returns the event with the given ID, loaded or retired this
+// session, and whether the peer knows it.
+func (w *witness) knownEvent(id string) (Event, bool) {
+	if n := w.eventIndex(id); n >= 0 {
+		return w.events[n], true
+	}
+	r, ok := w.retired[id]
+	return r, ok
+}
+
+// knownDigest returns the digest of the event with the given ID, loaded or
+// retired, or an empty string if the peer doesn't know it.
+func (w *witness) knownDigest(id string) string {
+	if e, ok := w.knownEvent(id); ok {
+		return eventDigest(e)
+	}
+	return ""
+}
+
+// subscribeSync subscribes to the sync topic of channel c.
+func (w *witness) subscribeSync(ctx app.Context, c channel) {
+	w.subs.subscribe(c.topic(topicSync), func(data []byte) {
+		var m syncMessage
+		err := json.Unmarshal(data, &m)
+		if err != nil {
+			log.Println("Dropped sync message on channel " + c.name() + ": " + err.Error())
+			return
+		}
+
+		ctx.Dispatch(func(ctx app.Context) {
+			if parseChannel(m.Channel) != w.channel || c != w.channel {
+				return
+			}
+			before := w.syncStats.Recovered
+			w.publishSync(ctx, w.handleSync(m, time.Now())...)
+			if w.syncStats.Recovered > before {
+				w.scheduleCacheSave(ctx)
+			}
+		})
+	})
+}
+
+// publishSync publishes sync messages on the current channel.
+func (w *witness) publishSync(ctx app.Context, msgs ...syncMessage) {
+	if len(msgs) == 0 {
+		return
+	}
+
+	topic := w.channel.topic(topicSync)
+	ctx.Async(func() {
+		for _, m := range msgs {
+			b, err := json.Marshal(m)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+			err = w.sh.PubSubPublish(topic, string(b))
+			if err != nil {
+				log.Println("Could not publish sync message: " + err.Error())
+				return
+			}
+		}
+	})
+}
+
+// syncRound broadcasts the summary of the peer and schedules the next one.
+func (w *witness) syncRound(ctx app.Context) {
+	if !w.syncing {
+		w.publishSync(ctx, w.syncSummary())
+	}
+	ctx.After(syncInterval, w.syncRound)
+}
+
+// renderSyncStats shows how far the peer was from the others last time it
+// compared.
+func (w *witness) renderSyncStats() app.UI {
+	s := w.syncStats
+	return app.If(s.Rounds > 0, func() app.UI {
+		text := w.t("sync.inSync")
+		class := "p-status-label--positive"
+		if s.Divergent > 0 {
+			text = strconv.Itoa(s.Divergent) + "/" + strconv.Itoa(syncBuckets) + " " + w.t("sync.divergent")
+			class = "p-status-label--caution"
+		}
+		title := strconv.Itoa(s.InSync) + "/" + strconv.Itoa(s.Rounds) + " " + w.t("sync.rounds") + " · " +
+			strconv.Itoa(s.Recovered) + " " + w.t("sync.recovered") + " · " + strconv.Itoa(s.Sent) + " " + w.t("sync.sent")
+		return app.Span().Class(class).Title(title).Text(text)
+	})
+}
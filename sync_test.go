@@ -0,0 +1,191 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// published signs e like putEvent does.
+func published(t *testing.T, w *witness, e Event) Event {
+	t.Helper()
+	e, err := signEvent(withState(e), w.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// reported returns a new rumor reported by w.
+func reported(t *testing.T, w *witness, title string, now time.Time) Event {
+	t.Helper()
+	w.eventTitle = title
+	e, ok := w.newReport(now)
+	if !ok {
+		t.Fatalf("report %q refused", title)
+	}
+	return published(t, w, e)
+}
+
+// exchange delivers sync messages between peers, starting with the summary
+// of the first one, until none is left.
+func exchange(t *testing.T, peers ...*witness) {
+	t.Helper()
+	queue := []syncMessage{peers[0].syncSummary()}
+	for n := 0; len(queue) > 0; n++ {
+		if n > 100 {
+			t.Fatal("sync did not settle")
+		}
+		m := queue[0]
+		queue = queue[1:]
+		for _, p := range peers {
+			queue = append(queue, p.handleSync(m, time.Unix(1000, 0))...)
+		}
+	}
+}
+
+func syncPeer(t *testing.T, citizen string) *witness {
+	w := auditWitness(t, citizen)
+	w.syncID = "session-" + citizen
+	return w
+}
+
+func TestSyncRecoversMissedEvents(t *testing.T) {
+	a, b, c := syncPeer(t, "10"), syncPeer(t, "20"), syncPeer(t, "11")
+
+	shared := reported(t, a, "Fire", time.Unix(100, 0))
+	a.receiveEvent(shared)
+	b.receiveEvent(shared)
+	c.receiveEvent(shared)
+
+	// b was offline while a reported a second event and c confirmed the
+	// first, and a missed the report of b
+	onlyA := reported(t, a, "Flood", time.Unix(200, 0))
+	a.receiveEvent(onlyA)
+	confirmed, _ := c.confirmation(shared.ID, time.Unix(300, 0))
+	a.receiveUpdate(published(t, c, confirmed))
+	onlyB := reported(t, b, "Protest", time.Unix(400, 0))
+	b.receiveEvent(onlyB)
+
+	rootA, _ := summarize(a.syncedEvents())
+	rootB, _ := summarize(b.syncedEvents())
+	if rootA == rootB {
+		t.Fatal("diverged peers have the same summary")
+	}
+
+	exchange(t, a, b)
+
+	rootA, _ = summarize(a.syncedEvents())
+	rootB, _ = summarize(b.syncedEvents())
+	if rootA != rootB {
+		t.Errorf("summaries differ after sync: %+v\n%+v", a.events, b.events)
+	}
+	for _, id := range []string{shared.ID, onlyA.ID, onlyB.ID} {
+		if a.eventIndex(id) < 0 || b.eventIndex(id) < 0 {
+			t.Errorf("event %s missing after sync", id)
+		}
+	}
+	if e := b.events[b.eventIndex(shared.ID)]; !contains(e.Witnesses, "11") {
+		t.Errorf("missed confirmation not recovered: %+v", e)
+	}
+
+	if b.syncStats.Rounds != 1 || b.syncStats.Divergent == 0 {
+		t.Errorf("stats of b = %+v, want one divergent round", b.syncStats)
+	}
+	if a.syncStats.Recovered != 1 || b.syncStats.Recovered != 2 {
+		t.Errorf("recovered %d and %d events, want 1 and 2", a.syncStats.Recovered, b.syncStats.Recovered)
+	}
+
+	exchange(t, a, b)
+	if b.syncStats.InSync != 1 || b.syncStats.Divergent != 0 {
+		t.Errorf("stats of b after second round = %+v, want in sync", b.syncStats)
+	}
+}
+
+func TestSyncDoesNotReviveArchivedEvents(t *testing.T) {
+	a, b := syncPeer(t, "10"), syncPeer(t, "20")
+
+	e := reported(t, a, "Fire", time.Unix(100, 0))
+	a.receiveEvent(e)
+	b.receiveEvent(e)
+
+	archived := e
+	archived.Type = archivedType
+	a.receiveUpdate(published(t, a, archived))
+
+	exchange(t, b, a)
+
+	if a.eventIndex(e.ID) >= 0 || b.eventIndex(e.ID) >= 0 {
+		t.Errorf("archived event active after sync: %+v, %+v", a.events, b.events)
+	}
+}
+
+func TestSyncPassesOnSignedVersions(t *testing.T) {
+	a, b, c, d := syncPeer(t, "10"), syncPeer(t, "20"), syncPeer(t, "11"), syncPeer(t, "12")
+
+	shared := reported(t, a, "Fire", time.Unix(100, 0))
+	for _, p := range []*witness{a, b, c, d} {
+		p.receiveEvent(shared)
+	}
+
+	// c confirmed and d added details at the same time, so a merges two
+	// versions none of them signed together
+	confirmed, _ := c.confirmation(shared.ID, time.Unix(200, 0))
+	detailed, _ := d.withDetails(shared.ID, Detail{Text: "smoke", Author: "12"}, time.Unix(200, 0))
+	a.receiveUpdate(published(t, c, confirmed))
+	a.receiveUpdate(published(t, d, detailed))
+	combined := a.events[a.eventIndex(shared.ID)]
+	if combined.Signature != "" {
+		t.Fatalf("combined version signed by %s", combined.Signer)
+	}
+
+	for _, m := range a.sendEvents(b.syncID, []Event{combined}) {
+		for _, e := range m.Events {
+			if err := verifyEvent(e); err != nil || e.Signer == signerID(a.key) {
+				t.Errorf("sent version signed by %s: %v", e.Signer, err)
+			}
+		}
+	}
+
+	exchange(t, a, b)
+	e := b.events[b.eventIndex(shared.ID)]
+	if !contains(e.Witnesses, "11") || !containsDetail(e.Details, Detail{Text: "smoke", Author: "12"}) {
+		t.Errorf("b did not recover both updates: %+v", e)
+	}
+	if eventDigest(e) != eventDigest(combined) {
+		t.Errorf("b merged %+v, a %+v", e, combined)
+	}
+
+	// the unsigned combination still counts as signed
+	stripped := combined
+	stripped.Witnesses = []string{"11", "x1"}
+	stripped.ConfirmedBy = 2
+	a.receiveUpdate(withState(stripped))
+	if e := a.events[a.eventIndex(shared.ID)]; contains(e.Witnesses, "x1") {
+		t.Errorf("unsigned update of a merged event applied: %+v", e)
+	}
+}
+
+func TestSyncChecksRetiredEvents(t *testing.T) {
+	a, mallory := syncPeer(t, "10"), syncPeer(t, "66")
+
+	e := reported(t, a, "Fire", time.Unix(100, 0))
+	a.receiveEvent(e)
+	archived := e
+	archived.Type = archivedType
+	archived = published(t, a, archived)
+	a.receiveUpdate(archived)
+
+	forged := archived
+	forged.Corrections = []Correction{{Title: "Nothing happened", At: 300}}
+	unsigned := archived
+	unsigned.Tags = []string{"hoax"}
+	unsigned.Signature = ""
+	m := mallory.syncReply(syncEvents, a.syncID)
+	m.Events = []Event{published(t, mallory, forged), unsigned}
+	a.handleSync(m, time.Unix(1000, 0))
+
+	r := a.retired[e.ID]
+	if len(r.Corrections) != 0 || len(r.Tags) != 0 || a.syncStats.Recovered != 0 {
+		t.Errorf("retired event took in invalid versions: %+v", r)
+	}
+}
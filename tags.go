@@ -0,0 +1,286 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+// followedTagsStorageKey is the local storage key of the tags the citizen
+// follows.
+const followedTagsStorageKey = "followed-tags"
+
+const (
+	// maxTags bounds the tags of a single event.
+	maxTags = 8
+	// maxTagLength bounds the length of a tag in characters.
+	maxTagLength = 32
+)
+
+var errBadTags = errors.New("tags are malformed or not added by the reporter")
+
+// followedTagsFilter is the tag filter of the feed made of every followed
+// tag. It can't clash with a real tag since normalised tags have no
+// asterisk.
+const followedTagsFilter = "*"
+
+// suggestedTags is the vocabulary offered on the report form. Reporters are
+// free to use other tags, the suggestions only help readers following a
+// topic find the same tag on every report.
+var suggestedTags = []string{
+	"accident",
+	"crime",
+	"environment",
+	"fire",
+	"health",
+	"infrastructure-outage",
+	"politics",
+	"protest",
+	"traffic",
+	"weather",
+}
+
+// normalizeTag turns a tag typed by a citizen into its canonical form:
+// lower case words joined by dashes, without punctuation. It returns an
+// empty string if nothing is left.
+func normalizeTag(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-'
+	})
+	tag := strings.Trim(strings.Join(words, "-"), "-")
+
+	if r := []rune(tag); len(r) > maxTagLength {
+		tag = strings.TrimRight(string(r[:maxTagLength]), "-")
+	}
+	return tag
+}
+
+// parseTags returns the distinct tags of a comma separated list, at most
+// maxTags of them.
+func parseTags(s string) []string {
+	var tags []string
+	for _, t := range strings.Split(s, ",") {
+		t = normalizeTag(t)
+		if t != "" && !contains(tags, t) && len(tags) < maxTags {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// limitTags drops the tags past maxTags.
+func limitTags(tags []string) []string {
+	if len(tags) > maxTags {
+		return tags[:maxTags:maxTags]
+	}
+	return tags
+}
+
+// validTags checks the tags of incoming: they have to be normalised, distinct
+// and at most maxTags. Tags are chosen by the reporter, so a known event only
+// gains tags in a version signed with the reporter key, apart from the ones a
+// merge brings along from the duplicates it takes in.
+func (w *witness) validTags(local Event, known bool, incoming Event) error {
+	if len(incoming.Tags) > maxTags {
+		return errBadTags
+	}
+	for n, t := range incoming.Tags {
+		if t == "" || normalizeTag(t) != t || contains(incoming.Tags[:n], t) {
+			return errBadTags
+		}
+	}
+	if !known || (local.ReporterKey != "" && incoming.Signer == local.ReporterKey) {
+		return nil
+	}
+
+	var merged []string
+	for _, id := range missing(incoming.MergedFrom, local.MergedFrom) {
+		if d, ok := w.knownEvent(id); ok {
+			merged = union(merged, d.Tags)
+		}
+	}
+	if len(missing(missing(incoming.Tags, local.Tags), merged)) > 0 {
+		return errBadTags
+	}
+	return nil
+}
+
+// hasTag reports whether e is tagged with tag.
+func (e Event) hasTag(tag string) bool {
+	return contains(e.Tags, tag)
+}
+
+// tagPath returns the link to the feed of view v filtered by tag.
+func tagPath(v view, tag string) string {
+	path := routeNews
+	if v == viewRumors {
+		path = routeRumors
+	}
+	if tag == "" {
+		return path
+	}
+	return path + "?tag=" + url.QueryEscape(tag)
+}
+
+// hidesTag reports whether the tag filter leaves e out of the feed.
+func (w *witness) hidesTag(e Event) bool {
+	switch w.tagFilter {
+	case "":
+		return false
+	case followedTagsFilter:
+		for _, t := range w.followedTags {
+			if e.hasTag(t) {
+				return false
+			}
+		}
+		return true
+	default:
+		return !e.hasTag(w.tagFilter)
+	}
+}
+
+// eventTags returns the tags of the loaded events, for the feed filter.
+func (w *witness) eventTags() []string {
+	seen := make(map[string]bool)
+	for _, e := range w.events {
+		for _, t := range e.Tags {
+			seen[t] = true
+		}
+	}
+	if w.tagFilter != "" && w.tagFilter != followedTagsFilter {
+		seen[w.tagFilter] = true
+	}
+
+	tags := make([]string, 0, len(seen))
+	for t := range seen {
+		tags = append(tags, t)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+func (w *witness) loadFollowedTags(ctx app.Context) {
+	err := ctx.LocalStorage().Get(followedTagsStorageKey, &w.followedTags)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (w *witness) saveFollowedTags(ctx app.Context) {
+	err := ctx.LocalStorage().Set(followedTagsStorageKey, w.followedTags)
+	if err != nil {
+		w.createNotification(ctx, NotificationDanger, w.t(ErrorHeader), w.t("feed.tagsSaveFailed"))
+		log.Println(err)
+	}
+}
+
+// renderTags renders the tags of an event as chips opening the tag's feed
+// of view v.
+func (w *witness) renderTags(v view, tags []string) app.UI {
+	return app.Range(tags).Slice(func(n int) app.UI {
+		return app.A().Class("p-chip is-dense").Href(tagPath(v, tags[n])).Body(
+			app.Span().Class("p-chip__value").Text(tags[n]),
+		)
+	})
+}
+
+// renderTagFilter renders the tag filter of the feed of view v, with the
+// button following the selected tag.
+func (w *witness) renderTagFilter(v view) app.UI {
+	tags := w.eventTags()
+	id := string(v) + "-tag"
+	followed := contains(w.followedTags, w.tagFilter)
+
+	return app.Div().Class("p-form p-form--inline").Body(
+		app.Div().Class("p-form__group").Body(
+			app.Label().For(id).Text(w.t("feed.tag")),
+			app.Select().ID(id).DataSet("view", v).OnChange(w.onFilterTag).Body(
+				app.Option().Value("").Text(w.t("feed.allTags")).Selected(w.tagFilter == ""),
+				app.Option().Value(followedTagsFilter).Text(w.t("feed.followedTags")).Selected(w.tagFilter == followedTagsFilter).Disabled(len(w.followedTags) == 0),
+				app.Range(tags).Slice(func(n int) app.UI {
+					return app.Option().Value(tags[n]).Text(tags[n]).Selected(tags[n] == w.tagFilter)
+				}),
+			),
+		),
+		app.If(w.tagFilter != "" && w.tagFilter != followedTagsFilter, func() app.UI {
+			return app.If(followed, func() app.UI {
+				return app.Button().Class("is-dense").Value(w.tagFilter).Text(w.t("feed.unfollowTag")).OnClick(w.onUnfollowTag)
+			}).Else(func() app.UI {
+				return app.Button().Class("is-dense p-button--positive").Value(w.tagFilter).Text(w.t("feed.followTag")).OnClick(w.onFollowTag)
+			})
+		}),
+	)
+}
+
+// renderReportTags renders the tag entry of the report form with the
+// suggested vocabulary.
+func (w *witness) renderReportTags() app.UI {
+	tags := parseTags(w.eventTagInput)
+
+	return app.Div().Class("p-form__group row").Body(
+		app.Label().For("tags").Text(w.t("report.tags")),
+		app.Input().ID("tags").Name("tags").Placeholder(w.t("report.tagsHint")).Value(w.eventTagInput).OnKeyUp(w.onEventTags),
+		app.Div().Body(
+			app.Range(suggestedTags).Slice(func(n int) app.UI {
+				t := suggestedTags[n]
+				return app.Button().Type("button").Class("p-chip is-dense").Aria("pressed", contains(tags, t)).Value(t).OnClick(w.onSuggestTag).Body(
+					app.Span().Class("p-chip__value").Text(t),
+				)
+			}),
+		),
+	)
+}
+
+func (w *witness) onEventTags(ctx app.Context, e app.Event) {
+	w.eventTagInput = ctx.JSSrc().Get("value").String()
+}
+
+// onSuggestTag adds a suggested tag to the report, or removes it if it is
+// already there.
+func (w *witness) onSuggestTag(ctx app.Context, e app.Event) {
+	tag := ctx.JSSrc().Get("value").String()
+	tags := parseTags(w.eventTagInput)
+
+	if contains(tags, tag) {
+		for n, t := range tags {
+			if t == tag {
+				tags = append(tags[:n], tags[n+1:]...)
+				break
+			}
+		}
+	} else {
+		tags = append(tags, tag)
+	}
+	w.eventTagInput = strings.Join(tags, ", ")
+}
+
+func (w *witness) onFilterTag(ctx app.Context, e app.Event) {
+	v := view(ctx.JSSrc().Get("dataset").Get("view").String())
+	ctx.Navigate(tagPath(v, ctx.JSSrc().Get("value").String()))
+}
+
+func (w *witness) onFollowTag(ctx app.Context, e app.Event) {
+	tag := ctx.JSSrc().Get("value").String()
+	if contains(w.followedTags, tag) {
+		return
+	}
+
+	w.followedTags = append(w.followedTags, tag)
+	w.saveFollowedTags(ctx)
+}
+
+func (w *witness) onUnfollowTag(ctx app.Context, e app.Event) {
+	tag := ctx.JSSrc().Get("value").String()
+	for n, t := range w.followedTags {
+		if t == tag {
+			w.followedTags = append(w.followedTags[:n], w.followedTags[n+1:]...)
+			break
+		}
+	}
+	w.saveFollowedTags(ctx)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Protest", "protest"},
+		{"  Infrastructure   outage ", "infrastructure-outage"},
+		{"#power-cut!", "power-cut"},
+		{"Überschwemmung", "überschwemmung"},
+		{"--", ""},
+		{strings.Repeat("a", maxTagLength+5), strings.Repeat("a", maxTagLength)},
+	}
+	for _, tt := range tests {
+		if got := normalizeTag(tt.in); got != tt.want {
+			t.Errorf("normalizeTag(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	got := parseTags("Protest, traffic,, protest ,Road works")
+	if want := []string{"protest", "traffic", "road-works"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseTags = %v, want %v", got, want)
+	}
+
+	var many []string
+	for i := 0; i < maxTags+3; i++ {
+		many = append(many, "t"+strconv.Itoa(i))
+	}
+	if got := parseTags(strings.Join(many, ",")); len(got) != maxTags {
+		t.Errorf("parsed %d tags, want at most %d", len(got), maxTags)
+	}
+}
+
+func TestTagFilter(t *testing.T) {
+	w := &witness{events: []Event{
+		{ID: "1", Tags: []string{"protest"}},
+		{ID: "2", Tags: []string{"weather", "traffic"}},
+		{ID: "3"},
+	}}
+	shown := func() []string {
+		var ids []string
+		for _, e := range w.events {
+			if !w.hidesTag(e) {
+				ids = append(ids, e.ID)
+			}
+		}
+		return ids
+	}
+
+	if got := shown(); len(got) != 3 {
+		t.Errorf("no filter shows %v", got)
+	}
+
+	w.tagFilter = "traffic"
+	if got := shown(); !reflect.DeepEqual(got, []string{"2"}) {
+		t.Errorf("traffic feed shows %v", got)
+	}
+
+	w.tagFilter = followedTagsFilter
+	w.followedTags = []string{"protest", "weather"}
+	if got := shown(); !reflect.DeepEqual(got, []string{"1", "2"}) {
+		t.Errorf("followed feed shows %v", got)
+	}
+
+	if got := w.eventTags(); !reflect.DeepEqual(got, []string{"protest", "traffic", "weather"}) {
+		t.Errorf("eventTags() = %v", got)
+	}
+}
+
+func TestTagPath(t *testing.T) {
+	if got := tagPath(viewRumors, "road-works"); got != "/rumors?tag=road-works" {
+		t.Errorf("tagPath(rumors) = %s", got)
+	}
+	if got := tagPath(viewNews, followedTagsFilter); got != "/news?tag=%2A" {
+		t.Errorf("tagPath(news, followed) = %s", got)
+	}
+	if got := tagPath(viewNews, ""); got != routeNews {
+		t.Errorf("tagPath(news, none) = %s", got)
+	}
+}
+
+func TestMergeTags(t *testing.T) {
+	merged, _ := mergeEvents(Event{ID: "1", Tags: []string{"protest"}}, Event{ID: "1", Tags: []string{"traffic", "protest"}})
+	if !reflect.DeepEqual(merged.Tags, []string{"protest", "traffic"}) {
+		t.Errorf("merged tags = %v", merged.Tags)
+	}
+
+	many := parseTags("a, b, c, d, e, f, g, h")
+	merged, _ = mergeEvents(Event{ID: "1", Tags: many}, Event{ID: "1", Tags: []string{"protest"}})
+	if !reflect.DeepEqual(merged.Tags, many) {
+		t.Errorf("merged tags = %v, want the first %d", merged.Tags, maxTags)
+	}
+}
+
+func TestValidTags(t *testing.T) {
+	reporter, other := auditWitness(t, "10"), auditWitness(t, "11")
+	fire := reported(t, reporter, "Fire", time.Unix(100, 0))
+	duplicate := Event{ID: "2", Type: mergedType, Tags: []string{"smoke"}}
+	w := &witness{events: []Event{fire}, retired: map[string]Event{"2": duplicate}}
+	with := func(change func(e *Event)) Event {
+		e := fire
+		change(&e)
+		return e
+	}
+
+	tests := []struct {
+		name     string
+		known    bool
+		incoming Event
+		ok       bool
+	}{
+		{"unchanged", true, fire, true},
+		{"added by the reporter", true, published(t, reporter, with(func(e *Event) { e.Tags = []string{"fire"} })), true},
+		{"added by another citizen", true, published(t, other, with(func(e *Event) { e.Tags = []string{"hoax"} })), false},
+		{"brought along by a merge", true, published(t, other, with(func(e *Event) { e.Tags, e.MergedFrom = []string{"smoke"}, []string{"2"} })), true},
+		{"not on the merged duplicate", true, published(t, other, with(func(e *Event) { e.Tags, e.MergedFrom = []string{"hoax"}, []string{"2"} })), false},
+		{"not normalised", false, with(func(e *Event) { e.Tags = []string{"Road Works"} }), false},
+		{"empty", false, with(func(e *Event) { e.Tags = []string{""} }), false},
+		{"repeated", false, with(func(e *Event) { e.Tags = []string{"fire", "fire"} }), false},
+		{"too many", false, with(func(e *Event) { e.Tags = append(parseTags("a, b, c, d, e, f, g, h"), "i") }), false},
+		{"new event", false, published(t, other, with(func(e *Event) { e.Tags = []string{"hoax"} })), true},
+	}
+	for _, tt := range tests {
+		local := Event{}
+		if tt.known {
+			local = fire
+		}
+		if err := w.validTags(local, tt.known, tt.incoming); (err == nil) != tt.ok {
+			t.Errorf("%s: validTags = %v", tt.name, err)
+		}
+	}
+}
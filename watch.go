@@ -0,0 +1,248 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+// Local storage keys of the events the citizen follows and of whether they
+// also want browser notifications about them.
+const (
+	watchedStorageKey              = "watched-events"
+	browserNotificationsStorageKey = "browser-notifications"
+)
+
+// maxWatched bounds the watch list, the oldest followed events are dropped
+// first.
+const maxWatched = 200
+
+// watchChanges describes the updates of a followed event worth notifying
+// about in language lang.
+func watchChanges(lang string, old, new Event) []string {
+	t := func(id string) string { return translate(lang, id) }
+
+	var changes []string
+	if added := len(missing(new.Witnesses, old.Witnesses)); added == 1 {
+		changes = append(changes, "1 "+t("watch.confirmation"))
+	} else if added > 1 {
+		changes = append(changes, strconv.Itoa(added)+" "+t("watch.confirmations"))
+	}
+
+	var oldDetails, newDetails []string
+	for _, d := range old.Details {
+		oldDetails = append(oldDetails, d.Text)
+	}
+	for _, d := range new.Details {
+		newDetails = append(newDetails, d.Text)
+	}
+	if added := len(missing(newDetails, oldDetails)); added == 1 {
+		changes = append(changes, "1 "+t("watch.detail"))
+	} else if added > 1 {
+		changes = append(changes, strconv.Itoa(added)+" "+t("watch.details"))
+	}
+
+	if added := len(missing(new.Disputes, old.Disputes)); added == 1 {
+		changes = append(changes, "1 "+t("watch.dispute"))
+	} else if added > 1 {
+		changes = append(changes, strconv.Itoa(added)+" "+t("watch.disputes"))
+	}
+
+	if s := new.state(); s != old.state() {
+		switch s {
+		case stateNews:
+			changes = append(changes, t("watch.becameNews"))
+		default:
+			changes = append(changes, t("watch.isNow")+" "+strings.ToLower(t("state."+string(s))))
+		}
+	}
+	return changes
+}
+
+// isWatched reports whether the citizen follows the event with the given ID.
+func (w *witness) isWatched(id string) bool {
+	return contains(w.watched, id)
+}
+
+// watch adds the event with the given ID to the watch list. It returns
+// false if it was already there.
+func (w *witness) watch(id string) bool {
+	if w.isWatched(id) {
+		return false
+	}
+
+	w.watched = append(w.watched, id)
+	if over := len(w.watched) - maxWatched; over > 0 {
+		w.watched = w.watched[over:]
+	}
+	return true
+}
+
+// unwatch removes the event with the given ID from the watch list.
+func (w *witness) unwatch(id string) bool {
+	if !w.isWatched(id) {
+		return false
+	}
+
+	w.watched = removeString(w.watched, id)
+	return true
+}
+
+// receiveWatchedUpdate applies an update like receiveUpdate and notifies the
+// citizen if it changed an event they follow. A followed duplicate is
+// followed on into the report it was merged into.
+func (w *witness) receiveWatchedUpdate(ctx app.Context, e Event) {
+	n := w.eventIndex(e.ID)
+	if n < 0 || !w.isWatched(e.ID) {
+		w.receiveUpdate(e)
+		return
+	}
+
+	old := w.events[n]
+	w.receiveUpdate(e)
+	updated := e
+	if n := w.eventIndex(e.ID); n >= 0 {
+		updated = w.events[n]
+	}
+	if updated.state() == stateMerged && w.watch(updated.MergeInto) {
+		w.saveWatched(ctx)
+	}
+
+	changes := watchChanges(w.language, old, updated)
+	if len(changes) == 0 {
+		return
+	}
+	msg := "\"" + old.corrected().Title + "\": " + strings.Join(changes, ", ") + "."
+	w.createNotification(ctx, NotificationInfo, w.t("watch.header"), msg)
+	if w.browserNotifications {
+		showBrowserNotification(w.t("watch.header"), msg)
+	}
+}
+
+func (w *witness) loadWatched(ctx app.Context) {
+	err := ctx.LocalStorage().Get(watchedStorageKey, &w.watched)
+	if err != nil {
+		log.Println(err)
+	}
+	err = ctx.LocalStorage().Get(browserNotificationsStorageKey, &w.browserNotifications)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (w *witness) saveWatched(ctx app.Context) {
+	err := ctx.LocalStorage().Set(watchedStorageKey, w.watched)
+	if err != nil {
+		w.createNotification(ctx, NotificationDanger, w.t(ErrorHeader), w.t("watch.saveFailed"))
+		log.Println(err)
+	}
+}
+
+// watchEvent follows the event with the given ID, used when the citizen
+// reports or confirms it.
+func (w *witness) watchEvent(ctx app.Context, id string) {
+	if w.watch(id) {
+		w.saveWatched(ctx)
+	}
+}
+
+func (w *witness) onWatchEvent(ctx app.Context, e app.Event) {
+	w.watchEvent(ctx, ctx.JSSrc().Get("value").String())
+}
+
+func (w *witness) onUnwatchEvent(ctx app.Context, e app.Event) {
+	if w.unwatch(ctx.JSSrc().Get("value").String()) {
+		w.saveWatched(ctx)
+	}
+}
+
+// showBrowserNotification shows a system notification if the browser
+// supports them and the citizen allowed them.
+func showBrowserNotification(title, body string) {
+	n := app.Window().Get("Notification")
+	if !n.Truthy() || n.Get("permission").String() != "granted" {
+		return
+	}
+	n.New(title, map[string]any{"body": body})
+}
+
+func (w *witness) onToggleBrowserNotifications(ctx app.Context, e app.Event) {
+	if w.browserNotifications {
+		w.setBrowserNotifications(ctx, false)
+		return
+	}
+
+	n := app.Window().Get("Notification")
+	if !n.Truthy() {
+		w.createNotification(ctx, NotificationWarning, w.t("hero.notifications"), w.t("watch.unsupported"))
+		return
+	}
+
+	var granted app.Func
+	granted = app.FuncOf(func(this app.Value, args []app.Value) any {
+		defer granted.Release()
+		ok := len(args) > 0 && args[0].String() == "granted"
+		ctx.Dispatch(func(ctx app.Context) {
+			if !ok {
+				w.createNotification(ctx, NotificationWarning, w.t("hero.notifications"), w.t("watch.denied"))
+				return
+			}
+			w.setBrowserNotifications(ctx, true)
+		})
+		return nil
+	})
+	n.Call("requestPermission").Call("then", granted)
+}
+
+func (w *witness) setBrowserNotifications(ctx app.Context, on bool) {
+	w.browserNotifications = on
+	err := ctx.LocalStorage().Set(browserNotificationsStorageKey, on)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// renderWatchButton renders the button following or unfollowing e.
+func (w *witness) renderWatchButton(e Event) app.UI {
+	return app.If(w.isWatched(e.ID), func() app.UI {
+		return app.Button().Class("is-dense p-button--base").Value(e.ID).Text(w.t("event.unwatch")).OnClick(w.onUnwatchEvent)
+	}).Else(func() app.UI {
+		return app.Button().Class("is-dense p-button--base").Value(e.ID).Text(w.t("event.watch")).OnClick(w.onWatchEvent)
+	})
+}
+
+// renderWatchList lists the loaded events the citizen follows.
+func (w *witness) renderWatchList() app.UI {
+	var events []Event
+	for _, id := range w.watched {
+		if n := w.eventIndex(id); n >= 0 {
+			events = append(events, w.events[n])
+		}
+	}
+
+	return app.Div().Body(
+		app.H4().Text(w.t("watch.heading")),
+		app.If(len(events) > 0, func() app.UI {
+			return app.Ul().Class("p-list--divided").Body(
+				app.Range(events).Slice(func(n int) app.UI {
+					e := events[n]
+					return app.Li().Class("p-list__item").Body(
+						app.A().Href(eventPath(e)).Text(e.corrected().Title),
+						app.Text(" "),
+						w.renderState(e),
+						app.Text(" "),
+						app.Button().Class("is-dense p-button--base").Value(e.ID).Text(w.t("event.unwatch")).OnClick(w.onUnwatchEvent),
+					)
+				}),
+			)
+		}).Else(func() app.UI {
+			return app.P().Text(w.t("watch.none"))
+		}),
+		app.Div().Class("p-form p-form--inline").Body(
+			app.Input().Type("checkbox").ID("browser-notifications").Checked(w.browserNotifications).OnChange(w.onToggleBrowserNotifications),
+			app.Label().For("browser-notifications").Text(w.t("watch.browser")),
+		),
+	)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestWatchChanges(t *testing.T) {
+	old := Event{ID: "1", Type: eventType, Witnesses: []string{"11"}, ConfirmedBy: 1,
+		Details: []Detail{{Text: "smoke", Author: "10"}}}
+
+	tests := []struct {
+		name   string
+		update func(e Event) Event
+		want   []string
+	}{
+		{"nothing new", func(e Event) Event { return e }, nil},
+		{"confirmed into news", func(e Event) Event {
+			e.Witnesses = []string{"11", "12"}
+			e.ConfirmedBy = 2
+			return e
+		}, []string{"1 new confirmation", "became news"}},
+		{"details", func(e Event) Event {
+			e.Details = []Detail{{Text: "smoke"}, {Text: "fire"}, {Text: "sirens"}}
+			return e
+		}, []string{"2 new details"}},
+		{"disputed", func(e Event) Event {
+			e.Disputes = []string{"20", "21"}
+			return e
+		}, []string{"2 new disputes", "is now disputed"}},
+	}
+	for _, tt := range tests {
+		if got := watchChanges(defaultLanguage, old, tt.update(old)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: changes = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	disputed := old
+	disputed.Disputes = []string{"20", "21"}
+	if got, want := watchChanges("es", old, disputed), []string{"2 nuevas disputas", "ahora está disputado"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("changes in Spanish = %q, want %q", got, want)
+	}
+}
+
+func TestWatchList(t *testing.T) {
+	w := &witness{}
+	if !w.watch("1") || w.watch("1") {
+		t.Error("watching twice")
+	}
+	if !w.isWatched("1") {
+		t.Error("watched event not followed")
+	}
+	if !w.unwatch("1") || w.unwatch("1") || w.isWatched("1") {
+		t.Error("unwatched event still followed")
+	}
+
+	for n := 0; n < maxWatched+5; n++ {
+		w.watch(strconv.Itoa(n))
+	}
+	if len(w.watched) != maxWatched || w.isWatched("0") || !w.isWatched(strconv.Itoa(maxWatched+4)) {
+		t.Errorf("watch list of %d events, want the latest %d", len(w.watched), maxWatched)
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Headers of a webhook request. The signature is the hex HMAC-SHA256 of the
+// body keyed with the shared secret, prefixed with "sha256=".
+const (
+	hookSignatureHeader = "X-Cyber-Witness-Signature"
+	hookKindHeader      = "X-Cyber-Witness-Event"
+	hookDeliveryHeader  = "X-Cyber-Witness-Delivery"
+)
+
+// envWebhookSecret holds the secret webhook payloads are signed with, so it
+// doesn't show up in the process list.
+const envWebhookSecret = "WEBHOOK_SECRET"
+
+const (
+	// defaultHookAttempts is how many times a delivery is tried before it
+	// is given up.
+	defaultHookAttempts = 5
+	// hookBackoff is the delay before the first retry, doubled on every
+	// following one.
+	hookBackoff = time.Second
+	// hookTimeout bounds a single delivery attempt.
+	hookTimeout = 10 * time.Second
+)
+
+// hookPayload is the JSON body POSTed to webhooks.
+type hookPayload struct {
+	Delivery string `json:"delivery"`
+	Kind     string `json:"kind"`
+	At       int64  `json:"at"`
+	Channel  string `json:"channel"`
+	Event    Event  `json:"event"`
+}
+
+// hookDelivery is an entry of the delivery log, one per attempt.
+type hookDelivery struct {
+	Delivery string `json:"delivery"`
+	URL      string `json:"url"`
+	Kind     string `json:"kind"`
+	Event    string `json:"event"`
+	Attempt  int    `json:"attempt"`
+	At       int64  `json:"at"`
+	Status   int    `json:"status,omitempty"`
+	Error    string `json:"error,omitempty"`
+	// Done is set on the last attempt of a delivery, whether it
+	// succeeded or was given up.
+	Done bool `json:"done,omitempty"`
+}
+
+// hookSignature signs a webhook body with secret.
+func hookSignature(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// validHookSignature lets receivers check a webhook request.
+func validHookSignature(secret, body []byte, signature string) bool {
+	return hmac.Equal([]byte(hookSignature(secret, body)), []byte(signature))
+}
+
+// webhooks calls every configured URL about the changes its watcher sees.
+type webhooks struct {
+	watcher  *changeWatcher
+	urls     []string
+	secret   []byte
+	kinds    []string
+	client   *http.Client
+	attempts int
+	backoff  time.Duration
+
+	logMu sync.Mutex
+	log   io.Writer
+
+	wg sync.WaitGroup
+}
+
+// newWebhooks returns webhooks calling urls about the given kinds of
+// changes of the events of channels and logging deliveries to out.
+func newWebhooks(urls []string, secret []byte, kinds []string, channels []channel, out io.Writer) *webhooks {
+	h := &webhooks{
+		urls:     urls,
+		secret:   secret,
+		kinds:    kinds,
+		client:   &http.Client{Timeout: hookTimeout},
+		attempts: defaultHookAttempts,
+		backoff:  hookBackoff,
+		log:      out,
+	}
+	h.watcher = newChangeWatcher(channels, h.onChange)
+	return h
+}
+
+func (h *webhooks) onChange(kind string, e Event) {
+	if contains(h.kinds, kind) {
+		h.notify(kind, e)
+	}
+}
+
+// notify delivers a payload about e to every webhook in the background.
+func (h *webhooks) notify(kind string, e Event) {
+	p := hookPayload{
+		Delivery: newHookDeliveryID(),
+		Kind:     kind,
+		At:       time.Now().Unix(),
+		Channel:  parseChannel(e.Channel).id(),
+		Event:    e,
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	for _, url := range h.urls {
+		h.wg.Add(1)
+		go func(url string) {
+			defer h.wg.Done()
+			h.deliver(url, p, body)
+		}(url)
+	}
+}
+
+// deliver POSTs body to url, retrying with exponential backoff until the
+// webhook accepts it or the attempts run out.
+func (h *webhooks) deliver(url string, p hookPayload, body []byte) {
+	delay := h.backoff
+	for attempt := 1; attempt <= h.attempts; attempt++ {
+		d := hookDelivery{Delivery: p.Delivery, URL: url, Kind: p.Kind, Event: p.Event.ID, Attempt: attempt, At: time.Now().Unix()}
+
+		status, err := h.post(url, p, body)
+		d.Status = status
+		retry := true
+		switch {
+		case err != nil:
+			d.Error = err.Error()
+		case status >= 200 && status < 300:
+			retry = false
+		case status == http.StatusRequestTimeout || status == http.StatusTooManyRequests || status >= 500:
+			d.Error = http.StatusText(status)
+		default:
+			// the webhook refuses the payload, sending it again won't help
+			d.Error = http.StatusText(status)
+			retry = false
+		}
+		d.Done = !retry || attempt == h.attempts
+		h.logDelivery(d)
+		if d.Done {
+			return
+		}
+
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+func (h *webhooks) post(url string, p hookPayload, body []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(hookKindHeader, p.Kind)
+	req.Header.Set(hookDeliveryHeader, p.Delivery)
+	req.Header.Set(hookSignatureHeader, hookSignature(h.secret, body))
+
+	res, err := h.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+	return res.StatusCode, nil
+}
+
+// logDelivery appends d to the delivery log as a JSON line.
+func (h *webhooks) logDelivery(d hookDelivery) {
+	b, err := json.Marshal(d)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	h.logMu.Lock()
+	defer h.logMu.Unlock()
+	_, err = h.log.Write(append(b, '\n'))
+	if err != nil {
+		log.Println("Could not log webhook delivery " + d.Delivery + ": " + err.Error())
+	}
+}
+
+// wait returns once every pending delivery is done.
+func (h *webhooks) wait() {
+	h.wg.Wait()
+}
+
+// parseHookKinds parses a comma separated list of change kinds, with or
+// without their "event." prefix.
+func parseHookKinds(s string) ([]string, error) {
+	var kinds []string
+	for _, k := range strings.Split(s, ",") {
+		k = strings.TrimSpace(k)
+		if k == "" {
+			continue
+		}
+		if !strings.HasPrefix(k, "event.") {
+			k = "event." + k
+		}
+		if !contains(changeKinds, k) {
+			return nil, fmt.Errorf("unknown webhook event %q, expected created, confirmed, disputed or news", k)
+		}
+		kinds = append(kinds, k)
+	}
+	return kinds, nil
+}
+
+func newHookDeliveryID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
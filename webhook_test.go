@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	shell "github.com/stateless-minds/go-ipfs-api"
+)
+
+// hookReceiver is a local webhook endpoint recording the payloads whose
+// signature holds and rejecting the others.
+type hookReceiver struct {
+	t      *testing.T
+	secret []byte
+
+	mu       sync.Mutex
+	fail     int
+	status   int
+	requests int
+	payloads []hookPayload
+}
+
+func newHookReceiver(t *testing.T, secret string) (*hookReceiver, string) {
+	r := &hookReceiver{t: t, secret: []byte(secret), status: http.StatusNoContent}
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return r, srv.URL
+}
+
+func (r *hookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests++
+	if r.fail > 0 {
+		r.fail--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	if !validHookSignature(r.secret, body, req.Header.Get(hookSignatureHeader)) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var p hookPayload
+	if err := json.Unmarshal(body, &p); err != nil {
+		r.t.Error(err)
+	}
+	if req.Header.Get(hookKindHeader) != p.Kind {
+		r.t.Errorf("kind header %q for a payload of kind %s", req.Header.Get(hookKindHeader), p.Kind)
+	}
+	r.payloads = append(r.payloads, p)
+	w.WriteHeader(r.status)
+}
+
+func (r *hookReceiver) kinds() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var kinds []string
+	for _, p := range r.payloads {
+		kinds = append(kinds, p.Kind)
+	}
+	return kinds
+}
+
+func TestWebhookRetriesAndLogsDeliveries(t *testing.T) {
+	receiver, url := newHookReceiver(t, "s3cret")
+	receiver.fail = 2
+
+	var out bytes.Buffer
+	h := newWebhooks([]string{url}, []byte("s3cret"), changeKinds, []channel{{}}, &out)
+	h.backoff = time.Millisecond
+	h.watcher.receive(Event{ID: "1", Type: eventType, Title: "Fire"}, true)
+	h.wait()
+
+	if got := receiver.kinds(); !reflect.DeepEqual(got, []string{changeCreated}) {
+		t.Fatalf("received %q, want one creation", got)
+	}
+
+	var log []hookDelivery
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var d hookDelivery
+		if err := json.Unmarshal([]byte(line), &d); err != nil {
+			t.Fatal(err)
+		}
+		log = append(log, d)
+	}
+	if len(log) != 3 {
+		t.Fatalf("delivery log = %+v, want 3 attempts", log)
+	}
+	if log[0].Status != http.StatusServiceUnavailable || log[0].Done {
+		t.Errorf("first attempt = %+v, want a failure to retry", log[0])
+	}
+	if last := log[2]; last.Attempt != 3 || last.Status != http.StatusNoContent || last.Error != "" || !last.Done {
+		t.Errorf("last attempt = %+v, want a delivery", last)
+	}
+}
+
+func TestWebhookGivesUpOnRejection(t *testing.T) {
+	receiver, url := newHookReceiver(t, "s3cret")
+
+	var out bytes.Buffer
+	h := newWebhooks([]string{url}, []byte("wrong"), []string{changeCreated}, []channel{{}}, &out)
+	h.backoff = time.Millisecond
+	h.watcher.receive(Event{ID: "1", Type: eventType}, true)
+	h.wait()
+
+	receiver.mu.Lock()
+	requests := receiver.requests
+	receiver.mu.Unlock()
+	if requests != 1 || !strings.Contains(out.String(), `"done":true`) {
+		t.Errorf("%d requests, log %s, want a single rejected attempt", requests, out.String())
+	}
+}
+
+func TestWebhooksFollowTheNetwork(t *testing.T) {
+	n := newFakeNetwork(t)
+	reporter, b, c := newPeer(t, n), newPeer(t, n), newPeer(t, n)
+
+	receiver, url := newHookReceiver(t, "s3cret")
+	h := newWebhooks([]string{url}, []byte("s3cret"), changeKinds, []channel{{}}, io.Discard)
+
+	var mu sync.Mutex
+	connected := 0
+	subs := newSubscriber(shell.NewShell(n.addNode()), func(topic string, s subState) {
+		mu.Lock()
+		defer mu.Unlock()
+		if s == subConnected {
+			connected++
+		}
+	})
+	t.Cleanup(subs.close)
+	h.watcher.subscribe(subs)
+	waitUntil(t, "the webhook subscriptions", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return connected == 2
+	})
+
+	id := reporter.report("Fire", "smoke", "Main St")
+	waitFor(t, "the report", func(p *peer) bool { _, ok := p.event(id); return ok }, b, c)
+	b.confirm(id)
+	waitFor(t, "the confirmation", func(p *peer) bool { e, _ := p.event(id); return e.ConfirmedBy == 1 }, c)
+	c.confirm(id)
+
+	// deliveries run concurrently and may arrive in any order
+	want := []string{changeConfirmed, changeConfirmed, changeCreated, changeNews}
+	waitUntil(t, "the webhook calls", func() bool { return len(receiver.kinds()) == len(want) })
+	h.wait()
+	got := receiver.kinds()
+	slices.Sort(got)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("received %q, want %q", got, want)
+	}
+}
+
+// waitUntil polls cond until it holds or the test times out.
+func waitUntil(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}